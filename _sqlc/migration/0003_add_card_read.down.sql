@@ -0,0 +1,2 @@
+-- Drop card_read table
+DROP TABLE card_read;
@@ -0,0 +1,10 @@
+-- Create card_read table for de-duplicating retried card reads from devices
+CREATE TABLE card_read (
+    `id` INT AUTO_INCREMENT PRIMARY KEY,
+    `device_id` VARCHAR(255) NOT NULL,
+    `idempotency_key` VARCHAR(255) NOT NULL,
+    `response` TEXT NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (`device_id`, `idempotency_key`),
+    INDEX idx_created_at (`created_at`)
+);
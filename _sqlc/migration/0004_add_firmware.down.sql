@@ -0,0 +1,3 @@
+-- Drop device and firmware tables
+DROP TABLE device;
+DROP TABLE firmware;
@@ -0,0 +1,23 @@
+-- Create firmware table for hosting firmware images of devices
+CREATE TABLE firmware (
+    `id` INT AUTO_INCREMENT PRIMARY KEY,
+    `client_type` VARCHAR(64) NOT NULL,
+    `version` VARCHAR(64) NOT NULL,
+    `sha256` CHAR(64) NOT NULL,
+    `size` INT NOT NULL,
+    `data` LONGBLOB NOT NULL,
+    `is_desired` BOOLEAN NOT NULL DEFAULT false,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (`client_type`, `version`)
+);
+
+-- Create device table for tracking firmware rollout per device
+CREATE TABLE device (
+    `device_id` VARCHAR(255) PRIMARY KEY,
+    `client_type` VARCHAR(64) NOT NULL,
+    `firmware_version` VARCHAR(64) NOT NULL,
+    `offered_version` VARCHAR(64) NULL,
+    `downloaded_version` VARCHAR(64) NULL,
+    `downloaded_at` TIMESTAMP NULL,
+    `last_boot_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+ALTER TABLE card DROP COLUMN `read_at`;
@@ -0,0 +1,2 @@
+-- Add read_at to card table for the card location map
+ALTER TABLE card ADD COLUMN `read_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
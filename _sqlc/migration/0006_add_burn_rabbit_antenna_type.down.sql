@@ -0,0 +1,6 @@
+DELETE FROM card WHERE serial IN (
+    SELECT serial FROM antenna WHERE antenna_type_id IN (SELECT id FROM antenna_type WHERE name IN ('burn', 'rabbit'))
+);
+UPDATE antenna SET antenna_type_id = (SELECT id FROM antenna_type WHERE name = 'unknown')
+WHERE antenna_type_id IN (SELECT id FROM antenna_type WHERE name IN ('burn', 'rabbit'));
+DELETE FROM antenna_type WHERE name IN ('burn', 'rabbit');
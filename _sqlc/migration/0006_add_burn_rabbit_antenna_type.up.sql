@@ -0,0 +1,2 @@
+-- Add burn and rabbit antenna types
+INSERT INTO antenna_type (`name`) VALUES ('burn'), ('rabbit');
@@ -0,0 +1,3 @@
+DROP TABLE hand_equity;
+DROP TABLE game_board;
+ALTER TABLE card DROP COLUMN `board_number`;
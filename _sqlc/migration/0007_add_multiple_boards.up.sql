@@ -0,0 +1,22 @@
+-- Add board_number to card table for run-it-twice and double-board games
+ALTER TABLE card ADD COLUMN `board_number` INT NOT NULL DEFAULT 1;
+
+-- Create game_board table for boards after the first one in a game
+-- shared_cards is the number of cards shared with the first board (e.g. 3 if run it twice after the flop)
+CREATE TABLE game_board (
+    `game_id` VARCHAR(36) NOT NULL,
+    `board_number` INT NOT NULL,
+    `shared_cards` INT NOT NULL DEFAULT 0,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`game_id`, `board_number`),
+    CONSTRAINT `fk_game_board_game` FOREIGN KEY (`game_id`) REFERENCES game (`id`)
+);
+
+-- Create hand_equity table for equity of a hand per board
+CREATE TABLE hand_equity (
+    `hand_id` INT NOT NULL,
+    `board_number` INT NOT NULL,
+    `equity` FLOAT NOT NULL,
+    PRIMARY KEY (`hand_id`, `board_number`),
+    CONSTRAINT `fk_hand_equity_hand` FOREIGN KEY (`hand_id`) REFERENCES hand (`id`) ON DELETE CASCADE
+);
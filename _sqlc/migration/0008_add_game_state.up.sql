@@ -0,0 +1,2 @@
+-- Add state to game table for the game state machine
+ALTER TABLE game ADD COLUMN `state` VARCHAR(16) NOT NULL DEFAULT 'waiting';
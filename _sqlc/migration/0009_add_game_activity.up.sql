@@ -0,0 +1,18 @@
+-- Create game_activity table for the last card read of each antenna type in a game, used by the game timeout
+CREATE TABLE game_activity (
+    `game_id` VARCHAR(36) NOT NULL,
+    `antenna_type_name` VARCHAR(10) NOT NULL,
+    `last_read_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`game_id`, `antenna_type_name`),
+    CONSTRAINT `fk_game_activity_game` FOREIGN KEY (`game_id`) REFERENCES game (`id`) ON DELETE CASCADE
+);
+
+-- Restore activities of active games from read times of cards
+INSERT INTO game_activity (`game_id`, `antenna_type_name`, `last_read_at`)
+SELECT card.game_id, antenna_type.name, MAX(card.read_at)
+FROM card
+JOIN antenna ON card.serial = antenna.serial
+JOIN antenna_type ON antenna.antenna_type_id = antenna_type.id
+JOIN game ON card.game_id = game.id
+WHERE game.status = 'active'
+GROUP BY card.game_id, antenna_type.name;
@@ -0,0 +1,3 @@
+ALTER TABLE game DROP COLUMN `paused_at`;
+ALTER TABLE game DROP COLUMN `label`;
+ALTER TABLE game DROP COLUMN `button_position`;
@@ -0,0 +1,5 @@
+-- Add dealer controls to game table
+-- button_position is the seat of the dealer button, paused_at is set while auto-clearing of the game is paused
+ALTER TABLE game ADD COLUMN `button_position` INT NULL;
+ALTER TABLE game ADD COLUMN `label` VARCHAR(255) NULL;
+ALTER TABLE game ADD COLUMN `paused_at` TIMESTAMP NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE antenna DROP INDEX `uq_antenna_seat_number`;
+ALTER TABLE antenna DROP COLUMN `seat_number`;
@@ -0,0 +1,3 @@
+-- Add seat_number to antenna table, a player antenna is a seat of the table
+ALTER TABLE antenna ADD COLUMN `seat_number` INT NULL;
+ALTER TABLE antenna ADD CONSTRAINT `uq_antenna_seat_number` UNIQUE (`seat_number`);
@@ -0,0 +1,5 @@
+ALTER TABLE player DROP INDEX `uq_player_membership_uid`;
+ALTER TABLE player DROP COLUMN `membership_uid`;
+ALTER TABLE player DROP COLUMN `country`;
+ALTER TABLE player DROP COLUMN `avatar_url`;
+ALTER TABLE player DROP COLUMN `display_name`;
@@ -0,0 +1,7 @@
+-- Add profile to player table, a player is a person independent of antennas
+-- membership_uid is the UID of the personal RFID card to check in at a seat
+ALTER TABLE player ADD COLUMN `display_name` VARCHAR(255) NULL;
+ALTER TABLE player ADD COLUMN `avatar_url` VARCHAR(2048) NULL;
+ALTER TABLE player ADD COLUMN `country` VARCHAR(2) NULL;
+ALTER TABLE player ADD COLUMN `membership_uid` VARCHAR(255) NULL;
+ALTER TABLE player ADD CONSTRAINT `uq_player_membership_uid` UNIQUE (`membership_uid`);
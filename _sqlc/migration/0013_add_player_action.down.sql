@@ -0,0 +1,4 @@
+ALTER TABLE game DROP COLUMN `pot`;
+ALTER TABLE hand_history DROP COLUMN `contributed`;
+DROP TABLE player_action;
+ALTER TABLE antenna DROP COLUMN `stack`;
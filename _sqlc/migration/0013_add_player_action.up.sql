@@ -0,0 +1,21 @@
+-- Add stack to antenna table, the chips of the player at the seat
+ALTER TABLE antenna ADD COLUMN `stack` BIGINT NOT NULL DEFAULT 0;
+
+-- Create player_action table for blinds, antes, and bets recorded by the dealer
+-- amount is the chips put into the pot by the action
+CREATE TABLE player_action (
+    `id` INT AUTO_INCREMENT PRIMARY KEY,
+    `game_id` VARCHAR(36) NOT NULL,
+    `seat_number` INT NOT NULL,
+    `player_id` INT NULL,
+    `street` VARCHAR(16) NOT NULL,
+    `action` VARCHAR(16) NOT NULL,
+    `amount` BIGINT NOT NULL DEFAULT 0,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_game_id (`game_id`),
+    CONSTRAINT `fk_player_action_game` FOREIGN KEY (`game_id`) REFERENCES game (`id`) ON DELETE CASCADE
+);
+
+-- Add chips to history
+ALTER TABLE hand_history ADD COLUMN `contributed` BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE game ADD COLUMN `pot` BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE hand_history DROP COLUMN `won`;
@@ -0,0 +1,2 @@
+-- Add won to hand_history table, the chips awarded to the player from pots
+ALTER TABLE hand_history ADD COLUMN `won` BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+DROP TABLE allin_equity;
@@ -0,0 +1,17 @@
+-- Create allin_equity table, the equity of a player at the moment of going all-in and the result
+-- pot and won are set when the game finishes
+CREATE TABLE allin_equity (
+    `id` INT AUTO_INCREMENT PRIMARY KEY,
+    `game_id` VARCHAR(36) NOT NULL,
+    `player_id` INT NOT NULL,
+    `seat_number` INT NOT NULL,
+    `street` VARCHAR(16) NOT NULL,
+    `equity` DOUBLE NULL,
+    `pot` BIGINT NOT NULL DEFAULT 0,
+    `won` BIGINT NOT NULL DEFAULT 0,
+    `settled` BOOLEAN NOT NULL DEFAULT FALSE,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_player_id_created_at (`player_id`, `created_at`),
+    CONSTRAINT `fk_allin_equity_game` FOREIGN KEY (`game_id`) REFERENCES game (`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_allin_equity_player` FOREIGN KEY (`player_id`) REFERENCES player (`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE hand_history DROP INDEX idx_created_at;
+ALTER TABLE hand_history DROP COLUMN `hand_category`;
+ALTER TABLE hand_history DROP COLUMN `showdown_won`;
+ALTER TABLE hand_history DROP COLUMN `showdown`;
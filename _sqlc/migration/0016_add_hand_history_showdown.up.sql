@@ -0,0 +1,6 @@
+-- Add showdown results to hand_history table
+-- hand_category is the best made hand on the first board at showdown
+ALTER TABLE hand_history ADD COLUMN `showdown` BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE hand_history ADD COLUMN `showdown_won` BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE hand_history ADD COLUMN `hand_category` VARCHAR(32) NULL;
+ALTER TABLE hand_history ADD INDEX idx_created_at (`created_at`);
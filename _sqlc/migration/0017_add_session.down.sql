@@ -0,0 +1,3 @@
+ALTER TABLE game DROP FOREIGN KEY `fk_game_session`;
+ALTER TABLE game DROP COLUMN `session_id`;
+DROP TABLE session;
@@ -0,0 +1,14 @@
+-- Create session table, a session (e.g. an event or a stream) groups games for reporting
+-- tables is names of tables in the session as a JSON array
+CREATE TABLE session (
+    `id` INT AUTO_INCREMENT PRIMARY KEY,
+    `name` VARCHAR(255) NOT NULL,
+    `venue` VARCHAR(255) NULL,
+    `tables` JSON NULL,
+    `started_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `ended_at` TIMESTAMP NULL DEFAULT NULL
+);
+
+-- Games are attached to the session open when the game starts
+ALTER TABLE game ADD COLUMN `session_id` INT NULL;
+ALTER TABLE game ADD CONSTRAINT `fk_game_session` FOREIGN KEY (`session_id`) REFERENCES session (`id`) ON DELETE SET NULL;
@@ -0,0 +1,15 @@
+-- Create tournament table, the blind clock of a tournament is kept with the tournament
+-- levels is the blind structure as a JSON array, level is the index of the current level
+-- level_started_at is moved forward by the paused time on resume
+CREATE TABLE tournament (
+    `id` INT AUTO_INCREMENT PRIMARY KEY,
+    `name` VARCHAR(255) NOT NULL,
+    `levels` JSON NOT NULL,
+    `starting_stack` BIGINT NOT NULL DEFAULT 0,
+    `entries` INT NOT NULL DEFAULT 0,
+    `level` INT NOT NULL DEFAULT 0,
+    `level_started_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `paused_at` TIMESTAMP NULL DEFAULT NULL,
+    `started_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `ended_at` TIMESTAMP NULL DEFAULT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE antenna ADD COLUMN `stack` BIGINT NOT NULL DEFAULT 0;
+UPDATE antenna JOIN player ON player.id = antenna.player_id SET antenna.stack = player.stack;
+ALTER TABLE player DROP COLUMN `stack`;
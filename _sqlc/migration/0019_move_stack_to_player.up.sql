@@ -0,0 +1,4 @@
+-- Move stack from antenna to player, the chips belong to the player and not to the seat
+ALTER TABLE player ADD COLUMN `stack` BIGINT NOT NULL DEFAULT 0;
+UPDATE player JOIN antenna ON antenna.player_id = player.id SET player.stack = antenna.stack;
+ALTER TABLE antenna DROP COLUMN `stack`;
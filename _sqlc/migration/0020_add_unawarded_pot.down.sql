@@ -0,0 +1 @@
+DROP TABLE unawarded_pot;
@@ -0,0 +1,13 @@
+-- Create unawarded_pot table for pots that can't be awarded when the game finishes (e.g. the board is not complete)
+-- player_ids is players eligible to win the pot as a JSON array, the dealer awards the pot to some of them by hand
+-- game_id has no foreign key, the game may be deleted by DELETE /admin/game (the timeout finishes the game and keeps it)
+CREATE TABLE unawarded_pot (
+    `id` INT AUTO_INCREMENT PRIMARY KEY,
+    `game_id` VARCHAR(36) NOT NULL,
+    `amount` BIGINT NOT NULL,
+    `player_ids` JSON NOT NULL,
+    `reason` VARCHAR(32) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `awarded_at` TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_awarded_at (`awarded_at`)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE allin_equity DROP INDEX idx_game_id;
+DELETE FROM allin_equity WHERE game_id NOT IN (SELECT id FROM game);
+ALTER TABLE allin_equity ADD CONSTRAINT `fk_allin_equity_game` FOREIGN KEY (`game_id`) REFERENCES game (`id`) ON DELETE CASCADE;
+ALTER TABLE allin_equity DROP INDEX idx_session_id;
+ALTER TABLE allin_equity DROP COLUMN `session_id`;
+ALTER TABLE hand_history DROP INDEX idx_session_id;
+ALTER TABLE hand_history DROP COLUMN `session_id`;
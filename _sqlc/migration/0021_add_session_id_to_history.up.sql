@@ -0,0 +1,12 @@
+-- Copy session_id of the game to archived hands and all-ins, statistics of a session outlive the game
+ALTER TABLE hand_history ADD COLUMN `session_id` INT NULL;
+ALTER TABLE hand_history ADD INDEX idx_session_id (`session_id`);
+UPDATE hand_history JOIN game ON game.id = hand_history.game_id SET hand_history.session_id = game.session_id;
+
+ALTER TABLE allin_equity ADD COLUMN `session_id` INT NULL;
+ALTER TABLE allin_equity ADD INDEX idx_session_id (`session_id`);
+UPDATE allin_equity JOIN game ON game.id = allin_equity.game_id SET allin_equity.session_id = game.session_id;
+
+-- all-ins of a deleted game are kept as hand_history
+ALTER TABLE allin_equity DROP FOREIGN KEY `fk_allin_equity_game`;
+ALTER TABLE allin_equity ADD INDEX idx_game_id (`game_id`);
@@ -0,0 +1,34 @@
+-- name: AddAllInEquity :exec
+INSERT INTO allin_equity (game_id, player_id, seat_number, street, equity)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: SetAllInEquityIfUnset :exec
+UPDATE allin_equity SET equity = ?
+WHERE game_id = ? AND player_id = ? AND equity IS NULL AND settled = FALSE;
+
+-- name: SettleAllInEquity :exec
+UPDATE allin_equity
+    LEFT JOIN game ON game.id = allin_equity.game_id
+SET allin_equity.pot = ?, allin_equity.won = ?, allin_equity.settled = TRUE, allin_equity.session_id = game.session_id
+WHERE allin_equity.game_id = ? AND allin_equity.player_id = ?;
+
+-- name: GetAllInStats :many
+SELECT
+    allin_equity.player_id,
+    player.name,
+    COUNT(*) AS allin_count,
+    CAST(SUM(allin_equity.equity) AS DOUBLE) AS equity_sum,
+    CAST(SUM(allin_equity.equity * allin_equity.pot) AS DOUBLE) AS expected,
+    CAST(SUM(allin_equity.won) AS SIGNED) AS actual
+FROM allin_equity
+         JOIN player ON player.id = allin_equity.player_id
+WHERE allin_equity.settled = TRUE
+  AND allin_equity.equity IS NOT NULL
+  AND allin_equity.created_at >= ?
+  AND allin_equity.created_at < ?
+  AND (sqlc.narg('session_id') IS NULL OR allin_equity.session_id = sqlc.narg('session_id'))
+GROUP BY allin_equity.player_id, player.name
+ORDER BY allin_equity.player_id;
+
+-- name: DeleteUnsettledAllInEquity :exec
+DELETE FROM allin_equity WHERE game_id = ? AND seat_number = ? AND settled = FALSE;
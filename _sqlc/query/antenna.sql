@@ -1,16 +1,16 @@
 -- name: GetAntenna :many
-SELECT antenna.id, serial, antenna_type_id, player_id, antenna_type.name AS antenna_type_name
+SELECT antenna.id, serial, antenna_type_id, player_id, seat_number, antenna_type.name AS antenna_type_name
 FROM antenna
 JOIN antenna_type ON antenna_type.id = antenna.antenna_type_id;
 
 -- name: GetAntennaById :one
-SELECT antenna.id, serial, antenna_type_id, player_id, antenna_type.name AS antenna_type_name
+SELECT antenna.id, serial, antenna_type_id, player_id, seat_number, antenna_type.name AS antenna_type_name
 FROM antenna
 JOIN antenna_type ON antenna_type.id = antenna.antenna_type_id
 WHERE antenna.id = ?;
 
 -- name: GetAntennaBySerial :one
-SELECT antenna.id, serial, antenna_type_id, player_id, antenna_type.name AS antenna_type_name
+SELECT antenna.id, serial, antenna_type_id, player_id, seat_number, antenna_type.name AS antenna_type_name
 FROM antenna
 JOIN antenna_type ON antenna_type.id = antenna.antenna_type_id
 WHERE serial = ?;
@@ -40,9 +40,36 @@ DELETE FROM antenna WHERE id = ?;
 -- name: ResetAntenna :exec
 DELETE FROM antenna;
 
--- name: GetBoardAntennaByDeviceIDPrefix :one
-SELECT antenna.id, serial, antenna_type_id, player_id, antenna_type.name AS antenna_type_name
+-- name: GetBoardAntennaByDeviceID :one
+SELECT antenna.id, serial, antenna_type_id, player_id, seat_number, antenna_type.name AS antenna_type_name
 FROM antenna
 JOIN antenna_type ON antenna_type.id = antenna.antenna_type_id
-WHERE antenna_type.name = 'board' AND serial LIKE CONCAT(?, '%')
-LIMIT 1;
\ No newline at end of file
+WHERE antenna_type.name = 'board' AND SUBSTRING_INDEX(serial, '-', 1) = sqlc.arg('device_id')
+ORDER BY antenna.id
+LIMIT 1;
+
+-- name: SetSeatNumberToAntennaByID :exec
+UPDATE antenna SET seat_number = ? WHERE id = ?;
+
+-- name: SetSeatNumberToAntennaBySerial :exec
+UPDATE antenna SET seat_number = ? WHERE serial = ?;
+
+-- name: UnsetPlayerIDToAntennaByID :exec
+UPDATE antenna SET player_id = NULL WHERE id = ?;
+
+-- name: UnsetPlayerIDToAntennaByPlayerID :exec
+UPDATE antenna SET player_id = NULL WHERE player_id = ?;
+
+-- name: GetSeats :many
+SELECT antenna.id, antenna.serial, antenna.seat_number, COALESCE(player.stack, 0) AS stack, antenna.player_id, player.name AS player_name
+FROM antenna
+LEFT JOIN player ON player.id = antenna.player_id
+WHERE antenna.seat_number IS NOT NULL
+ORDER BY antenna.seat_number;
+
+-- name: GetSeatBySeatNumber :one
+SELECT antenna.id, antenna.serial, antenna.seat_number, COALESCE(player.stack, 0) AS stack, antenna.player_id
+FROM antenna
+LEFT JOIN player ON player.id = antenna.player_id
+WHERE antenna.seat_number = ?
+LIMIT 1;
@@ -1,11 +1,23 @@
 -- name: GetBoard :many
-SELECT id, card_suit, card_rank, serial, is_board FROM card
+SELECT id, card_suit, card_rank, serial, is_board, board_number FROM card
 WHERE is_board = true;
 
 -- name: AddCardToBoard :exec
-INSERT INTO card (card_suit, card_rank, serial, game_id, is_board)
-VALUES (?, ?, ?, ?, true);
+INSERT INTO card (card_suit, card_rank, serial, game_id, is_board, board_number)
+VALUES (?, ?, ?, ?, true, ?);
 
 -- name: ResetBoard :exec
 DELETE FROM card
-WHERE is_board = true;
\ No newline at end of file
+WHERE is_board = true;
+
+-- name: AddGameBoard :exec
+INSERT IGNORE INTO game_board (game_id, board_number, shared_cards)
+VALUES (?, ?, ?);
+
+-- name: GetGameBoards :many
+SELECT game_id, board_number, shared_cards, created_at FROM game_board
+WHERE game_id = ?
+ORDER BY board_number;
+
+-- name: DeleteGameBoardByGameID :exec
+DELETE FROM game_board WHERE game_id = ?;
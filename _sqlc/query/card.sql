@@ -30,4 +30,57 @@ SELECT DISTINCT antenna_type.name AS antenna_type_name
 FROM card
 JOIN antenna ON card.serial = antenna.serial
 JOIN antenna_type ON antenna.antenna_type_id = antenna_type.id
-WHERE card.game_id = (SELECT id FROM game WHERE status = 'active' ORDER BY started_at DESC LIMIT 1);
\ No newline at end of file
+WHERE card.game_id = (SELECT id FROM game WHERE status = 'active' ORDER BY started_at DESC LIMIT 1);
+
+-- name: GetCardLocationByRankSuit :one
+SELECT
+    card.id,
+    card.serial,
+    card.hand_id,
+    card.is_board,
+    antenna_type.name AS antenna_type_name,
+    hand.is_muck
+FROM card
+JOIN antenna ON card.serial = antenna.serial
+JOIN antenna_type ON antenna.antenna_type_id = antenna_type.id
+LEFT JOIN hand ON card.hand_id = hand.id
+WHERE card.card_rank = ? AND card.card_suit = ?;
+
+-- name: DeleteCardByID :exec
+DELETE FROM card WHERE id = ?;
+
+-- name: UnsetCardHandByHandID :exec
+UPDATE card SET hand_id = NULL WHERE hand_id = ?;
+
+
+-- name: GetCardsWithLocation :many
+SELECT
+    card.card_suit,
+    card.card_rank,
+    card.serial,
+    card.read_at,
+    antenna_type.name AS antenna_type_name,
+    hand.is_muck,
+    player.id AS player_id,
+    player.name AS player_name
+FROM card
+JOIN antenna ON card.serial = antenna.serial
+JOIN antenna_type ON antenna.antenna_type_id = antenna_type.id
+LEFT JOIN hand ON card.hand_id = hand.id
+LEFT JOIN player ON antenna.player_id = player.id;
+
+
+-- name: DeleteCardBySerial :exec
+DELETE FROM card WHERE serial = ?;
+
+-- name: GetCardsByAntennaTypeName :many
+SELECT card.id, card.card_suit, card.card_rank, card.serial
+FROM card
+JOIN antenna ON card.serial = antenna.serial
+JOIN antenna_type ON antenna.antenna_type_id = antenna_type.id
+WHERE antenna_type.name = ?
+ORDER BY card.id;
+
+
+-- name: CountCardsByGameID :one
+SELECT COUNT(*) FROM card WHERE game_id = ?;
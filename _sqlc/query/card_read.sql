@@ -0,0 +1,12 @@
+-- name: GetCardReadByIdempotencyKey :one
+SELECT id, device_id, idempotency_key, response, created_at
+FROM card_read
+WHERE device_id = ? AND idempotency_key = ?
+LIMIT 1;
+
+-- name: AddCardRead :exec
+INSERT INTO card_read (device_id, idempotency_key, response)
+VALUES (?, ?, ?);
+
+-- name: DeleteCardReadCreatedBefore :exec
+DELETE FROM card_read WHERE created_at < ?;
@@ -0,0 +1,15 @@
+-- name: UpsertDevice :exec
+INSERT INTO device (device_id, client_type, firmware_version, last_boot_at)
+VALUES (?, ?, ?, NOW())
+ON DUPLICATE KEY UPDATE client_type = VALUES(client_type), firmware_version = VALUES(firmware_version), last_boot_at = NOW();
+
+-- name: SetDeviceOfferedVersion :exec
+UPDATE device SET offered_version = ? WHERE device_id = ?;
+
+-- name: SetDeviceDownloadedVersion :exec
+UPDATE device SET downloaded_version = ?, downloaded_at = NOW() WHERE device_id = ?;
+
+-- name: GetDevices :many
+SELECT device_id, client_type, firmware_version, offered_version, downloaded_version, downloaded_at, last_boot_at
+FROM device
+ORDER BY device_id;
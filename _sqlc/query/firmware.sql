@@ -0,0 +1,31 @@
+-- name: AddFirmware :execresult
+INSERT INTO firmware (client_type, version, sha256, size, data)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetFirmwareList :many
+SELECT id, client_type, version, sha256, size, is_desired, created_at
+FROM firmware
+ORDER BY created_at DESC;
+
+-- name: GetFirmwareByID :one
+SELECT id, client_type, version, sha256, size, is_desired, created_at
+FROM firmware
+WHERE id = ? LIMIT 1;
+
+-- name: GetFirmwareDataByID :one
+SELECT data FROM firmware WHERE id = ? LIMIT 1;
+
+-- name: GetDesiredFirmwareByClientType :one
+SELECT id, client_type, version, sha256, size, is_desired, created_at
+FROM firmware
+WHERE client_type = ? AND is_desired = true
+LIMIT 1;
+
+-- name: UnsetDesiredFirmwareByClientType :exec
+UPDATE firmware SET is_desired = false WHERE client_type = ?;
+
+-- name: SetDesiredFirmware :exec
+UPDATE firmware SET is_desired = true WHERE id = ?;
+
+-- name: DeleteFirmwareByID :exec
+DELETE FROM firmware WHERE id = ?;
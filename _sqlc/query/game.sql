@@ -1,18 +1,37 @@
 -- name: CreateGame :exec
-INSERT INTO game (id, status)
-VALUES (?, 'active');
+INSERT INTO game (id, status, session_id)
+VALUES (?, 'active', (SELECT id FROM session WHERE ended_at IS NULL ORDER BY started_at DESC LIMIT 1));
 
 -- name: GetCurrentGame :one
-SELECT id, started_at, ended_at, status FROM game WHERE status = 'active' ORDER BY started_at DESC LIMIT 1;
+SELECT id, started_at, ended_at, status, state, button_position, label, paused_at, pot, session_id FROM game WHERE status = 'active' ORDER BY started_at DESC LIMIT 1;
 
 -- name: GetGameByID :one
-SELECT id, started_at, ended_at, status FROM game WHERE id = ? LIMIT 1;
+SELECT id, started_at, ended_at, status, state, button_position, label, paused_at, pot, session_id FROM game WHERE id = ? LIMIT 1;
 
 -- name: FinishGame :exec
-UPDATE game SET ended_at = NOW(), status = 'finished' WHERE id = ?;
+UPDATE game
+SET ended_at = NOW(), status = 'finished', state = 'finished',
+    pot = (SELECT COALESCE(SUM(amount), 0) FROM player_action WHERE player_action.game_id = game.id)
+WHERE id = ?;
 
 -- name: DeleteAllGames :exec
 DELETE FROM game;
 
 -- name: DeleteGameByID :exec
 DELETE FROM game WHERE id = ?;
+
+-- name: UpdateGameState :exec
+UPDATE game SET state = ? WHERE id = ?;
+
+-- name: StartGame :exec
+INSERT INTO game (id, status, button_position, label, session_id)
+VALUES (?, 'active', ?, ?, (SELECT id FROM session WHERE ended_at IS NULL ORDER BY started_at DESC LIMIT 1));
+
+-- name: PauseGame :exec
+UPDATE game SET paused_at = NOW() WHERE id = ? AND paused_at IS NULL;
+
+-- name: ResumeGame :exec
+UPDATE game SET paused_at = NULL WHERE id = ?;
+
+-- name: GetLastButtonPosition :one
+SELECT button_position FROM game WHERE button_position IS NOT NULL ORDER BY started_at DESC LIMIT 1;
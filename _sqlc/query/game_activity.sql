@@ -0,0 +1,16 @@
+-- name: UpsertGameActivity :exec
+INSERT INTO game_activity (game_id, antenna_type_name, last_read_at)
+VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE last_read_at = VALUES(last_read_at);
+
+-- name: GetGameActivitiesByGameID :many
+SELECT game_id, antenna_type_name, last_read_at FROM game_activity WHERE game_id = ? ORDER BY antenna_type_name;
+
+-- name: DeleteGameActivityByGameID :exec
+DELETE FROM game_activity WHERE game_id = ?;
+
+-- name: DelayGameActivityByPause :exec
+UPDATE game_activity
+JOIN game ON game_activity.game_id = game.id
+SET game_activity.last_read_at = game_activity.last_read_at + INTERVAL TIMESTAMPDIFF(SECOND, game.paused_at, NOW()) SECOND
+WHERE game.id = ? AND game.paused_at IS NOT NULL;
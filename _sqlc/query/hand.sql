@@ -43,6 +43,9 @@ UPDATE hand SET equity = 0;
 -- name: MuckHand :exec
 UPDATE hand SET is_muck = true WHERE id = ?;
 
+-- name: UnmuckHandByGameIDAndPlayerID :exec
+UPDATE hand SET is_muck = false WHERE game_id = ? AND player_id = ?;
+
 -- name: DeleteHandByAntennaID :exec
 DELETE FROM hand WHERE player_id = (SELECT player_id FROM antenna WHERE antenna.id = ?);
 
@@ -50,4 +53,24 @@ DELETE FROM hand WHERE player_id = (SELECT player_id FROM antenna WHERE antenna.
 DELETE FROM hand;
 
 -- name: DeleteHandByGameID :exec
-DELETE FROM hand WHERE game_id = ?;
\ No newline at end of file
+DELETE FROM hand WHERE game_id = ?;
+
+-- name: DeleteHandByID :exec
+DELETE FROM hand WHERE id = ?;
+
+
+-- name: UpsertHandEquity :exec
+INSERT INTO hand_equity (hand_id, board_number, equity)
+VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE equity = VALUES(equity);
+
+-- name: GetHandEquities :many
+SELECT hand_id, board_number, equity FROM hand_equity
+ORDER BY hand_id, board_number;
+
+-- name: DeleteHandEquityAll :exec
+DELETE FROM hand_equity;
+
+
+-- name: CountHandsByGameID :one
+SELECT COUNT(*) FROM hand WHERE game_id = ?;
@@ -1,17 +1,80 @@
 -- name: CopyHandsToHistory :exec
-INSERT INTO hand_history (game_id, player_id, equity, is_muck)
-SELECT hand.game_id, hand.player_id, hand.equity, hand.is_muck
+INSERT INTO hand_history (game_id, player_id, equity, is_muck, contributed, session_id)
+SELECT hand.game_id, hand.player_id, hand.equity, hand.is_muck,
+       COALESCE((SELECT SUM(amount) FROM player_action
+                 WHERE player_action.game_id = hand.game_id AND player_action.player_id = hand.player_id), 0),
+       game.session_id
 FROM hand
+         JOIN game ON game.id = hand.game_id
 WHERE hand.game_id = ?;
 
 -- name: GetHandHistoryByGameID :many
-SELECT id, game_id, player_id, equity, is_muck, created_at
+SELECT id, game_id, player_id, equity, is_muck, created_at, contributed, won, showdown, showdown_won, hand_category, session_id
 FROM hand_history
 WHERE game_id = ?
 ORDER BY created_at DESC;
 
 -- name: GetHandHistoryByPlayerID :many
-SELECT id, game_id, player_id, equity, is_muck, created_at
+SELECT hand_history.id, hand_history.game_id, hand_history.player_id, hand_history.equity, hand_history.is_muck, hand_history.created_at,
+       hand_history.contributed, hand_history.won, hand_history.showdown, hand_history.showdown_won, hand_history.hand_category,
+       hand_history.session_id
 FROM hand_history
-WHERE player_id = ?
-ORDER BY created_at DESC;
+WHERE hand_history.player_id = ?
+  AND hand_history.created_at >= ?
+  AND hand_history.created_at < ?
+  AND (sqlc.narg('session_id') IS NULL OR hand_history.session_id = sqlc.narg('session_id'))
+ORDER BY hand_history.created_at DESC;
+
+-- name: AddWonToHandHistory :exec
+UPDATE hand_history SET won = won + ? WHERE game_id = ? AND player_id = ?;
+
+-- name: SetShowdownToHandHistory :exec
+UPDATE hand_history SET showdown = TRUE, showdown_won = ?, hand_category = ?
+WHERE game_id = ? AND player_id = ?;
+
+-- name: GetPlayerStats :many
+SELECT
+    hand_history.player_id,
+    player.name,
+    COUNT(*) AS hands_dealt,
+    CAST(SUM(hand_history.is_muck) AS SIGNED) AS hands_mucked,
+    CAST(SUM(hand_history.showdown) AS SIGNED) AS showdowns,
+    CAST(SUM(hand_history.showdown_won) AS SIGNED) AS showdowns_won,
+    CAST(AVG(CASE WHEN hand_history.showdown THEN
+        (SELECT allin_equity.equity FROM allin_equity
+         WHERE allin_equity.game_id = hand_history.game_id AND allin_equity.player_id = hand_history.player_id
+         ORDER BY allin_equity.id LIMIT 1) END) AS DOUBLE) AS showdown_equity,
+    CAST(MAX(hand_history.won) AS SIGNED) AS biggest_pot,
+    CAST(SUM(hand_history.won) AS SIGNED) AS won,
+    CAST(SUM(hand_history.contributed) AS SIGNED) AS contributed
+FROM hand_history
+         JOIN player ON player.id = hand_history.player_id
+WHERE hand_history.created_at >= ?
+  AND hand_history.created_at < ?
+  AND (sqlc.narg('session_id') IS NULL OR hand_history.session_id = sqlc.narg('session_id'))
+  AND (sqlc.narg('player_id') IS NULL OR hand_history.player_id = sqlc.narg('player_id'))
+GROUP BY hand_history.player_id, player.name
+ORDER BY hand_history.player_id;
+
+-- name: GetHandCategoryCounts :many
+SELECT hand_history.player_id, hand_history.hand_category, COUNT(*) AS count
+FROM hand_history
+WHERE hand_history.showdown = TRUE
+  AND hand_history.hand_category IS NOT NULL
+  AND hand_history.created_at >= ?
+  AND hand_history.created_at < ?
+  AND (sqlc.narg('session_id') IS NULL OR hand_history.session_id = sqlc.narg('session_id'))
+  AND (sqlc.narg('player_id') IS NULL OR hand_history.player_id = sqlc.narg('player_id'))
+GROUP BY hand_history.player_id, hand_history.hand_category;
+
+-- name: GetBiggestPotsByPlayerID :many
+SELECT hand_history.game_id, hand_history.won, hand_history.hand_category, hand_history.created_at, COALESCE(game.pot, 0) AS pot
+FROM hand_history
+         LEFT JOIN game ON game.id = hand_history.game_id
+WHERE hand_history.player_id = ?
+  AND hand_history.won > 0
+  AND hand_history.created_at >= ?
+  AND hand_history.created_at < ?
+  AND (sqlc.narg('session_id') IS NULL OR hand_history.session_id = sqlc.narg('session_id'))
+ORDER BY hand_history.won DESC
+LIMIT ?;
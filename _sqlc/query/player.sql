@@ -1,9 +1,9 @@
 -- name: GetPlayer :one
-SELECT id, name FROM player
+SELECT id, name, display_name, avatar_url, country, membership_uid FROM player
 WHERE id = ? LIMIT 1;
 
 -- name: GetPlayersWithDevice :many
-SELECT player.id, player.name, antenna.serial
+SELECT player.id, player.name, antenna.serial, antenna.seat_number
 FROM player
 JOIN antenna ON player.id = antenna.player_id;
 
@@ -14,7 +14,7 @@ JOIN antenna ON player.id = antenna.player_id
 WHERE player.id = ? LIMIT 1;
 
 -- name: GetPlayerBySerial :one
-SELECT player.id, player.name
+SELECT player.id, player.name, player.display_name, player.avatar_url, player.country, player.membership_uid
 FROM player
 JOIN antenna ON player.id = antenna.player_id
 WHERE antenna.serial = ? LIMIT 1;
@@ -24,6 +24,7 @@ SELECT
     player.id,
     player.name,
     hand.id AS hand_id,
+    hand.game_id,
     hand.equity,
     hand.is_muck,
     card_a.card_suit AS card_a_suit,
@@ -56,4 +57,33 @@ DELETE FROM hand
 WHERE player_id = ?;
 
 DELETE FROM player
-WHERE id = ?;
\ No newline at end of file
+WHERE id = ?;
+
+-- name: AddPlayerProfile :execresult
+INSERT INTO player (name, display_name, avatar_url, country, membership_uid)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: UpdatePlayerProfile :exec
+UPDATE player
+SET name = ?, display_name = ?, avatar_url = ?, country = ?, membership_uid = ?
+WHERE id = ?;
+
+-- name: GetPlayerByMembershipUid :one
+SELECT id, name, display_name, avatar_url, country, membership_uid FROM player
+WHERE membership_uid = ? LIMIT 1;
+
+-- name: GetPlayerProfiles :many
+SELECT player.id, player.name, player.display_name, player.avatar_url, player.country, player.membership_uid, antenna.serial, antenna.seat_number
+FROM player
+LEFT JOIN antenna ON player.id = antenna.player_id
+ORDER BY player.id;
+
+-- name: SetStackToPlayer :exec
+UPDATE player SET stack = ? WHERE id = ?;
+
+-- name: AddStackToPlayer :exec
+UPDATE player SET stack = stack + ? WHERE id = ?;
+
+-- name: SetStackToSeatedPlayers :exec
+UPDATE player SET stack = ?
+WHERE id IN (SELECT player_id FROM antenna WHERE seat_number IS NOT NULL AND player_id IS NOT NULL);
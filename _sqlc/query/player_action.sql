@@ -0,0 +1,19 @@
+-- name: AddPlayerAction :exec
+INSERT INTO player_action (game_id, seat_number, player_id, street, action, amount)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetPlayerActionsByGameID :many
+SELECT id, game_id, seat_number, player_id, street, action, amount, created_at
+FROM player_action
+WHERE game_id = ?
+ORDER BY id;
+
+-- name: GetLastPlayerActionByGameID :one
+SELECT id, game_id, seat_number, player_id, street, action, amount, created_at
+FROM player_action
+WHERE game_id = ?
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: DeletePlayerActionByID :exec
+DELETE FROM player_action WHERE id = ?;
@@ -0,0 +1,23 @@
+-- name: CreateSession :execresult
+INSERT INTO session (name, venue, tables)
+VALUES (?, ?, ?);
+
+-- name: GetSession :one
+SELECT id, name, venue, tables, started_at, ended_at FROM session WHERE id = ? LIMIT 1;
+
+-- name: GetOpenSession :one
+SELECT id, name, venue, tables, started_at, ended_at FROM session WHERE ended_at IS NULL ORDER BY started_at DESC LIMIT 1;
+
+-- name: GetSessions :many
+SELECT session.id, session.name, session.venue, session.tables, session.started_at, session.ended_at,
+       COUNT(game.id) AS game_count
+FROM session
+         LEFT JOIN game ON game.session_id = session.id
+GROUP BY session.id
+ORDER BY session.started_at DESC;
+
+-- name: CloseSession :exec
+UPDATE session SET ended_at = NOW() WHERE id = ? AND ended_at IS NULL;
+
+-- name: GetGameIDsBySessionID :many
+SELECT id FROM game WHERE session_id = ?;
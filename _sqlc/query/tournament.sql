@@ -0,0 +1,28 @@
+-- name: CreateTournament :execresult
+INSERT INTO tournament (name, levels, starting_stack, entries)
+VALUES (?, ?, ?, ?);
+
+-- name: GetTournament :one
+SELECT id, name, levels, starting_stack, entries, level, level_started_at, paused_at, started_at, ended_at FROM tournament WHERE id = ? LIMIT 1;
+
+-- name: GetRunningTournament :one
+SELECT id, name, levels, starting_stack, entries, level, level_started_at, paused_at, started_at, ended_at FROM tournament WHERE ended_at IS NULL ORDER BY id DESC LIMIT 1;
+
+-- name: PauseTournament :exec
+UPDATE tournament SET paused_at = NOW() WHERE id = ? AND paused_at IS NULL;
+
+-- name: ResumeTournament :exec
+UPDATE tournament
+SET level_started_at = level_started_at + INTERVAL TIMESTAMPDIFF(SECOND, paused_at, NOW()) SECOND,
+    paused_at = NULL
+WHERE id = ? AND paused_at IS NOT NULL;
+
+-- name: SetTournamentLevel :exec
+UPDATE tournament
+SET level = ?,
+    level_started_at = NOW(),
+    paused_at = IF(paused_at IS NULL, NULL, NOW())
+WHERE id = ?;
+
+-- name: FinishTournament :exec
+UPDATE tournament SET ended_at = NOW(), paused_at = NULL WHERE id = ? AND ended_at IS NULL;
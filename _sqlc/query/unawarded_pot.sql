@@ -0,0 +1,15 @@
+-- name: AddUnawardedPot :exec
+INSERT INTO unawarded_pot (game_id, amount, player_ids, reason)
+VALUES (?, ?, ?, ?);
+
+-- name: GetUnawardedPots :many
+SELECT id, game_id, amount, player_ids, reason, created_at, awarded_at FROM unawarded_pot
+WHERE awarded_at IS NULL
+ORDER BY id;
+
+-- name: GetUnawardedPotByID :one
+SELECT id, game_id, amount, player_ids, reason, created_at, awarded_at FROM unawarded_pot
+WHERE id = ? LIMIT 1;
+
+-- name: SetUnawardedPotAwarded :exec
+UPDATE unawarded_pot SET awarded_at = NOW() WHERE id = ?;
@@ -8,6 +8,76 @@ type Config struct {
 	// GameTimeoutSeconds is the number of seconds to wait for card reads before automatically ending the game
 	// If set to 0, timeout is disabled. Default: 10
 	GameTimeoutSeconds int `env:"RFID_POKER_CLIENT_TIMEOUT_SECONDS" default:"10"`
+	// GameTimeoutSecondsByAntennaType overrides GameTimeoutSeconds for an antenna type (key: player, board, muck, burn, rabbit)
+	// If set to 0, reads of the antenna type do not extend the game
+	GameTimeoutSecondsByAntennaType map[string]int `yaml:"game_timeout_seconds_by_antenna_type"`
+
+	// EndGameOnBoardCleared ends the game when all cards are removed from the board antenna, instead of GameTimeoutSeconds
+	// Readers must send "removed" events to use this. Same as GameEndPolicy "board_removed".
+	EndGameOnBoardCleared bool `env:"RFID_POKER_END_GAME_ON_BOARD_CLEARED" default:"false"`
+
+	// GameEndPolicy is when the game ends
+	// "timeout" (all antennas idle for GameTimeoutSeconds), "one_player_left" (all but one player mucked),
+	// "river_complete" (GameEndDelaySeconds after the river), "board_removed" (board cards removed), or "manual" (DELETE /admin/game only). Default: timeout
+	GameEndPolicy string `env:"RFID_POKER_GAME_END_POLICY" default:"timeout"`
+	// GameEndDelaySeconds is the delay to end the game after the river for "river_complete". Default: 10
+	GameEndDelaySeconds int `env:"RFID_POKER_GAME_END_DELAY_SECONDS" default:"10"`
+
+	// TableName is the name of the table served by the server, e.g. "Table 1". It is sent in /ws and used by /events?table=
+	TableName string `env:"RFID_POKER_TABLE_NAME"`
+
+	// TableSeats is the number of seats of the table, seat numbers are from 1 to TableSeats. Default: 10
+	// SeatNumberBySerial sets the seat number of player antennas (key: <device_id>-<pair_id>)
+	TableSeats         int            `env:"RFID_POKER_TABLE_SEATS" default:"10"`
+	SeatNumberBySerial map[string]int `yaml:"seat_number_by_serial"`
+
+	// ReadDebounceMillis drops repeated reads of the same card on the same antenna within this window
+	// If set to 0, debounce is disabled. Default: 1000
+	ReadDebounceMillis int `env:"RFID_POKER_READ_DEBOUNCE_MILLIS" default:"1000"`
+	// ReadConfirmCount is the number of consecutive reads required before a card is processed. Default: 1
+	// ReadConfirmCountBySerial overrides it for noisy antennas (key: <device_id>-<pair_id>)
+	ReadConfirmCount         int            `env:"RFID_POKER_READ_CONFIRM_COUNT" default:"1"`
+	ReadConfirmCountBySerial map[string]int `yaml:"read_confirm_count_by_serial"`
+	// ReadConfirmWindowMillis is the maximum gap between reads to be treated as consecutive. Default: 2000
+	ReadConfirmWindowMillis int `env:"RFID_POKER_READ_CONFIRM_WINDOW_MILLIS" default:"2000"`
+
+	// DuplicateCardPolicy is how to handle a card read at a second location in one game
+	// "reject" (keep the first location), "move" (move the card to the new location), or "flag" (keep the first location, alert only). Default: reject
+	DuplicateCardPolicy string `env:"RFID_POKER_DUPLICATE_CARD_POLICY" default:"reject"`
+
+	// LeaderboardMetric is the default metric to rank players on the leaderboard
+	// "net", "won", "hands_dealt", "showdowns", "showdowns_won", "showdown_win_rate", "biggest_pot", or "showdown_equity". Default: net
+	LeaderboardMetric string `env:"RFID_POKER_LEADERBOARD_METRIC" default:"net"`
+	// LeaderboardMinHands excludes players with fewer hands from the leaderboard. Default: 0
+	LeaderboardMinHands int `env:"RFID_POKER_LEADERBOARD_MIN_HANDS" default:"0"`
+
+	// BroadcastDelaySeconds delays the public feed (/ws) so players at the table can't see hole cards live. Default: 0
+	// It is changed by POST /admin/broadcast without restarting
+	BroadcastDelaySeconds int `env:"RFID_POKER_BROADCAST_DELAY_SECONDS" default:"0"`
+	// ProducerToken authenticates the producer feed (/producer/ws) without the delay. The producer feed is disabled if empty
+	ProducerToken string `env:"RFID_POKER_PRODUCER_TOKEN"`
+
+	// WebSocketQueueSize is the number of messages queued for a WebSocket client. Default: 16
+	// WebSocketSlowClientPolicy is what to do when the queue is full, "drop" (drop the oldest message) or "close" (close the connection). Default: drop
+	WebSocketQueueSize        int    `env:"RFID_POKER_WEBSOCKET_QUEUE_SIZE" default:"16"`
+	WebSocketSlowClientPolicy string `env:"RFID_POKER_WEBSOCKET_SLOW_CLIENT_POLICY" default:"drop"`
+	// WebSocketPingSeconds is the interval to ping WebSocket clients, a client not answering is closed. If set to 0, ping is disabled. Default: 30
+	WebSocketPingSeconds int `env:"RFID_POKER_WEBSOCKET_PING_SECONDS" default:"30"`
+
+	// FirmwareMaxBytes is the maximum size of a firmware image uploaded by POST /admin/firmware. Default: 16777216 (16 MiB)
+	FirmwareMaxBytes int64 `env:"RFID_POKER_FIRMWARE_MAX_BYTES" default:"16777216"`
+
+	// TournamentLevels is the default blind structure of tournaments started by POST /admin/tournament
+	TournamentLevels []BlindLevel `yaml:"tournament_levels"`
+
+	// MQTTMode enables receiving card reads and boot messages over MQTT
+	// "" (disabled), "external" (connect to MQTTBrokerURL), or "embedded" (run a broker listening on MQTTListenAddr)
+	MQTTMode        string `env:"RFID_POKER_MQTT_MODE"`
+	MQTTBrokerURL   string `env:"RFID_POKER_MQTT_BROKER_URL"` // e.g. tcp://localhost:1883
+	MQTTUsername    string `env:"RFID_POKER_MQTT_USERNAME"`
+	MQTTPassword    string `env:"RFID_POKER_MQTT_PASSWORD"`
+	MQTTListenAddr  string `env:"RFID_POKER_MQTT_LISTEN_ADDR" default:"127.0.0.1:1883"` // MQTTUsername is required to listen on other addresses
+	MQTTTopicPrefix string `env:"RFID_POKER_MQTT_TOPIC_PREFIX" default:"rfid-poker"`
 
 	MySQLUser     string `required:"true" env:"RFID_POKER_MYSQL_USER"`
 	MySQLPass     string `required:"true" env:"RFID_POKER_MYSQL_PASS"`
@@ -15,3 +85,11 @@ type Config struct {
 	MySQLPort     string `required:"true" env:"RFID_POKER_MYSQL_PORT"`
 	MySQLDatabase string `required:"true" env:"RFID_POKER_MYSQL_DATABASE"`
 }
+
+// BlindLevel is a level of the blind structure of tournaments, it is converted to store.BlindLevel by the server
+type BlindLevel struct {
+	SmallBlind int64 `yaml:"small_blind"`
+	BigBlind   int64 `yaml:"big_blind"`
+	Ante       int64 `yaml:"ante"`
+	Minutes    int   `yaml:"minutes"`
+}
@@ -35,7 +35,7 @@ func (q *Queries) DeleteAntennaByID(ctx context.Context, id int32) error {
 }
 
 const getAntenna = `-- name: GetAntenna :many
-SELECT antenna.id, serial, antenna_type_id, player_id, antenna_type.name AS antenna_type_name
+SELECT antenna.id, serial, antenna_type_id, player_id, seat_number, antenna_type.name AS antenna_type_name
 FROM antenna
 JOIN antenna_type ON antenna_type.id = antenna.antenna_type_id
 `
@@ -45,6 +45,7 @@ type GetAntennaRow struct {
 	Serial          string
 	AntennaTypeID   int32
 	PlayerID        sql.NullInt32
+	SeatNumber      sql.NullInt32
 	AntennaTypeName string
 }
 
@@ -62,6 +63,7 @@ func (q *Queries) GetAntenna(ctx context.Context) ([]GetAntennaRow, error) {
 			&i.Serial,
 			&i.AntennaTypeID,
 			&i.PlayerID,
+			&i.SeatNumber,
 			&i.AntennaTypeName,
 		); err != nil {
 			return nil, err
@@ -78,7 +80,7 @@ func (q *Queries) GetAntenna(ctx context.Context) ([]GetAntennaRow, error) {
 }
 
 const getAntennaById = `-- name: GetAntennaById :one
-SELECT antenna.id, serial, antenna_type_id, player_id, antenna_type.name AS antenna_type_name
+SELECT antenna.id, serial, antenna_type_id, player_id, seat_number, antenna_type.name AS antenna_type_name
 FROM antenna
 JOIN antenna_type ON antenna_type.id = antenna.antenna_type_id
 WHERE antenna.id = ?
@@ -89,6 +91,7 @@ type GetAntennaByIdRow struct {
 	Serial          string
 	AntennaTypeID   int32
 	PlayerID        sql.NullInt32
+	SeatNumber      sql.NullInt32
 	AntennaTypeName string
 }
 
@@ -100,13 +103,14 @@ func (q *Queries) GetAntennaById(ctx context.Context, id int32) (GetAntennaByIdR
 		&i.Serial,
 		&i.AntennaTypeID,
 		&i.PlayerID,
+		&i.SeatNumber,
 		&i.AntennaTypeName,
 	)
 	return i, err
 }
 
 const getAntennaBySerial = `-- name: GetAntennaBySerial :one
-SELECT antenna.id, serial, antenna_type_id, player_id, antenna_type.name AS antenna_type_name
+SELECT antenna.id, serial, antenna_type_id, player_id, seat_number, antenna_type.name AS antenna_type_name
 FROM antenna
 JOIN antenna_type ON antenna_type.id = antenna.antenna_type_id
 WHERE serial = ?
@@ -117,6 +121,7 @@ type GetAntennaBySerialRow struct {
 	Serial          string
 	AntennaTypeID   int32
 	PlayerID        sql.NullInt32
+	SeatNumber      sql.NullInt32
 	AntennaTypeName string
 }
 
@@ -128,6 +133,7 @@ func (q *Queries) GetAntennaBySerial(ctx context.Context, serial string) (GetAnt
 		&i.Serial,
 		&i.AntennaTypeID,
 		&i.PlayerID,
+		&i.SeatNumber,
 		&i.AntennaTypeName,
 	)
 	return i, err
@@ -155,35 +161,114 @@ func (q *Queries) GetAntennaTypeIdIsUnknown(ctx context.Context) (int32, error)
 	return id, err
 }
 
-const getBoardAntennaByDeviceIDPrefix = `-- name: GetBoardAntennaByDeviceIDPrefix :one
-SELECT antenna.id, serial, antenna_type_id, player_id, antenna_type.name AS antenna_type_name
+const getBoardAntennaByDeviceID = `-- name: GetBoardAntennaByDeviceID :one
+SELECT antenna.id, serial, antenna_type_id, player_id, seat_number, antenna_type.name AS antenna_type_name
 FROM antenna
 JOIN antenna_type ON antenna_type.id = antenna.antenna_type_id
-WHERE antenna_type.name = 'board' AND serial LIKE CONCAT(?, '%')
+WHERE antenna_type.name = 'board' AND SUBSTRING_INDEX(serial, '-', 1) = ?
+ORDER BY antenna.id
 LIMIT 1
 `
 
-type GetBoardAntennaByDeviceIDPrefixRow struct {
+type GetBoardAntennaByDeviceIDRow struct {
 	ID              int32
 	Serial          string
 	AntennaTypeID   int32
 	PlayerID        sql.NullInt32
+	SeatNumber      sql.NullInt32
 	AntennaTypeName string
 }
 
-func (q *Queries) GetBoardAntennaByDeviceIDPrefix(ctx context.Context, concat interface{}) (GetBoardAntennaByDeviceIDPrefixRow, error) {
-	row := q.db.QueryRowContext(ctx, getBoardAntennaByDeviceIDPrefix, concat)
-	var i GetBoardAntennaByDeviceIDPrefixRow
+func (q *Queries) GetBoardAntennaByDeviceID(ctx context.Context, deviceID string) (GetBoardAntennaByDeviceIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getBoardAntennaByDeviceID, deviceID)
+	var i GetBoardAntennaByDeviceIDRow
 	err := row.Scan(
 		&i.ID,
 		&i.Serial,
 		&i.AntennaTypeID,
 		&i.PlayerID,
+		&i.SeatNumber,
 		&i.AntennaTypeName,
 	)
 	return i, err
 }
 
+const getSeatBySeatNumber = `-- name: GetSeatBySeatNumber :one
+SELECT antenna.id, antenna.serial, antenna.seat_number, COALESCE(player.stack, 0) AS stack, antenna.player_id
+FROM antenna
+LEFT JOIN player ON player.id = antenna.player_id
+WHERE antenna.seat_number = ?
+LIMIT 1
+`
+
+type GetSeatBySeatNumberRow struct {
+	ID         int32
+	Serial     string
+	SeatNumber sql.NullInt32
+	Stack      int64
+	PlayerID   sql.NullInt32
+}
+
+func (q *Queries) GetSeatBySeatNumber(ctx context.Context, seatNumber sql.NullInt32) (GetSeatBySeatNumberRow, error) {
+	row := q.db.QueryRowContext(ctx, getSeatBySeatNumber, seatNumber)
+	var i GetSeatBySeatNumberRow
+	err := row.Scan(
+		&i.ID,
+		&i.Serial,
+		&i.SeatNumber,
+		&i.Stack,
+		&i.PlayerID,
+	)
+	return i, err
+}
+
+const getSeats = `-- name: GetSeats :many
+SELECT antenna.id, antenna.serial, antenna.seat_number, COALESCE(player.stack, 0) AS stack, antenna.player_id, player.name AS player_name
+FROM antenna
+LEFT JOIN player ON player.id = antenna.player_id
+WHERE antenna.seat_number IS NOT NULL
+ORDER BY antenna.seat_number
+`
+
+type GetSeatsRow struct {
+	ID         int32
+	Serial     string
+	SeatNumber sql.NullInt32
+	Stack      int64
+	PlayerID   sql.NullInt32
+	PlayerName sql.NullString
+}
+
+func (q *Queries) GetSeats(ctx context.Context) ([]GetSeatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSeats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSeatsRow
+	for rows.Next() {
+		var i GetSeatsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Serial,
+			&i.SeatNumber,
+			&i.Stack,
+			&i.PlayerID,
+			&i.PlayerName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const resetAntenna = `-- name: ResetAntenna :exec
 DELETE FROM antenna
 `
@@ -222,3 +307,49 @@ func (q *Queries) SetPlayerIDToAntennaBySerial(ctx context.Context, arg SetPlaye
 	_, err := q.db.ExecContext(ctx, setPlayerIDToAntennaBySerial, arg.PlayerID, arg.Serial)
 	return err
 }
+
+const setSeatNumberToAntennaByID = `-- name: SetSeatNumberToAntennaByID :exec
+UPDATE antenna SET seat_number = ? WHERE id = ?
+`
+
+type SetSeatNumberToAntennaByIDParams struct {
+	SeatNumber sql.NullInt32
+	ID         int32
+}
+
+func (q *Queries) SetSeatNumberToAntennaByID(ctx context.Context, arg SetSeatNumberToAntennaByIDParams) error {
+	_, err := q.db.ExecContext(ctx, setSeatNumberToAntennaByID, arg.SeatNumber, arg.ID)
+	return err
+}
+
+const setSeatNumberToAntennaBySerial = `-- name: SetSeatNumberToAntennaBySerial :exec
+UPDATE antenna SET seat_number = ? WHERE serial = ?
+`
+
+type SetSeatNumberToAntennaBySerialParams struct {
+	SeatNumber sql.NullInt32
+	Serial     string
+}
+
+func (q *Queries) SetSeatNumberToAntennaBySerial(ctx context.Context, arg SetSeatNumberToAntennaBySerialParams) error {
+	_, err := q.db.ExecContext(ctx, setSeatNumberToAntennaBySerial, arg.SeatNumber, arg.Serial)
+	return err
+}
+
+const unsetPlayerIDToAntennaByID = `-- name: UnsetPlayerIDToAntennaByID :exec
+UPDATE antenna SET player_id = NULL WHERE id = ?
+`
+
+func (q *Queries) UnsetPlayerIDToAntennaByID(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, unsetPlayerIDToAntennaByID, id)
+	return err
+}
+
+const unsetPlayerIDToAntennaByPlayerID = `-- name: UnsetPlayerIDToAntennaByPlayerID :exec
+UPDATE antenna SET player_id = NULL WHERE player_id = ?
+`
+
+func (q *Queries) UnsetPlayerIDToAntennaByPlayerID(ctx context.Context, playerID sql.NullInt32) error {
+	_, err := q.db.ExecContext(ctx, unsetPlayerIDToAntennaByPlayerID, playerID)
+	return err
+}
@@ -10,15 +10,16 @@ import (
 )
 
 const addCardToBoard = `-- name: AddCardToBoard :exec
-INSERT INTO card (card_suit, card_rank, serial, game_id, is_board)
-VALUES (?, ?, ?, ?, true)
+INSERT INTO card (card_suit, card_rank, serial, game_id, is_board, board_number)
+VALUES (?, ?, ?, ?, true, ?)
 `
 
 type AddCardToBoardParams struct {
-	CardSuit string
-	CardRank string
-	Serial   string
-	GameID   string
+	CardSuit    string
+	CardRank    string
+	Serial      string
+	GameID      string
+	BoardNumber int32
 }
 
 func (q *Queries) AddCardToBoard(ctx context.Context, arg AddCardToBoardParams) error {
@@ -27,21 +28,48 @@ func (q *Queries) AddCardToBoard(ctx context.Context, arg AddCardToBoardParams)
 		arg.CardRank,
 		arg.Serial,
 		arg.GameID,
+		arg.BoardNumber,
 	)
 	return err
 }
 
+const addGameBoard = `-- name: AddGameBoard :exec
+INSERT IGNORE INTO game_board (game_id, board_number, shared_cards)
+VALUES (?, ?, ?)
+`
+
+type AddGameBoardParams struct {
+	GameID      string
+	BoardNumber int32
+	SharedCards int32
+}
+
+func (q *Queries) AddGameBoard(ctx context.Context, arg AddGameBoardParams) error {
+	_, err := q.db.ExecContext(ctx, addGameBoard, arg.GameID, arg.BoardNumber, arg.SharedCards)
+	return err
+}
+
+const deleteGameBoardByGameID = `-- name: DeleteGameBoardByGameID :exec
+DELETE FROM game_board WHERE game_id = ?
+`
+
+func (q *Queries) DeleteGameBoardByGameID(ctx context.Context, gameID string) error {
+	_, err := q.db.ExecContext(ctx, deleteGameBoardByGameID, gameID)
+	return err
+}
+
 const getBoard = `-- name: GetBoard :many
-SELECT id, card_suit, card_rank, serial, is_board FROM card
+SELECT id, card_suit, card_rank, serial, is_board, board_number FROM card
 WHERE is_board = true
 `
 
 type GetBoardRow struct {
-	ID       int32
-	CardSuit string
-	CardRank string
-	Serial   string
-	IsBoard  bool
+	ID          int32
+	CardSuit    string
+	CardRank    string
+	Serial      string
+	IsBoard     bool
+	BoardNumber int32
 }
 
 func (q *Queries) GetBoard(ctx context.Context) ([]GetBoardRow, error) {
@@ -59,6 +87,41 @@ func (q *Queries) GetBoard(ctx context.Context) ([]GetBoardRow, error) {
 			&i.CardRank,
 			&i.Serial,
 			&i.IsBoard,
+			&i.BoardNumber,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGameBoards = `-- name: GetGameBoards :many
+SELECT game_id, board_number, shared_cards, created_at FROM game_board
+WHERE game_id = ?
+ORDER BY board_number
+`
+
+func (q *Queries) GetGameBoards(ctx context.Context, gameID string) ([]GameBoard, error) {
+	rows, err := q.db.QueryContext(ctx, getGameBoards, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GameBoard
+	for rows.Next() {
+		var i GameBoard
+		if err := rows.Scan(
+			&i.GameID,
+			&i.BoardNumber,
+			&i.SharedCards,
+			&i.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
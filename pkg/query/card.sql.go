@@ -8,6 +8,7 @@ package query
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const addCard = `-- name: AddCard :execresult
@@ -32,6 +33,17 @@ func (q *Queries) AddCard(ctx context.Context, arg AddCardParams) (sql.Result, e
 	)
 }
 
+const countCardsByGameID = `-- name: CountCardsByGameID :one
+SELECT COUNT(*) FROM card WHERE game_id = ?
+`
+
+func (q *Queries) CountCardsByGameID(ctx context.Context, gameID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countCardsByGameID, gameID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deleteBoardCards = `-- name: DeleteBoardCards :exec
 DELETE FROM card WHERE is_board = true
 `
@@ -68,6 +80,24 @@ func (q *Queries) DeleteCardByGameID(ctx context.Context, gameID string) error {
 	return err
 }
 
+const deleteCardByID = `-- name: DeleteCardByID :exec
+DELETE FROM card WHERE id = ?
+`
+
+func (q *Queries) DeleteCardByID(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteCardByID, id)
+	return err
+}
+
+const deleteCardBySerial = `-- name: DeleteCardBySerial :exec
+DELETE FROM card WHERE serial = ?
+`
+
+func (q *Queries) DeleteCardBySerial(ctx context.Context, serial string) error {
+	_, err := q.db.ExecContext(ctx, deleteCardBySerial, serial)
+	return err
+}
+
 const getAntennaTypesWithCardsInCurrentGame = `-- name: GetAntennaTypesWithCardsInCurrentGame :many
 SELECT DISTINCT antenna_type.name AS antenna_type_name
 FROM card
@@ -195,6 +225,153 @@ func (q *Queries) GetCardBySerial(ctx context.Context, serial string) ([]GetCard
 	return items, nil
 }
 
+const getCardLocationByRankSuit = `-- name: GetCardLocationByRankSuit :one
+SELECT
+    card.id,
+    card.serial,
+    card.hand_id,
+    card.is_board,
+    antenna_type.name AS antenna_type_name,
+    hand.is_muck
+FROM card
+JOIN antenna ON card.serial = antenna.serial
+JOIN antenna_type ON antenna.antenna_type_id = antenna_type.id
+LEFT JOIN hand ON card.hand_id = hand.id
+WHERE card.card_rank = ? AND card.card_suit = ?
+`
+
+type GetCardLocationByRankSuitParams struct {
+	CardRank string
+	CardSuit string
+}
+
+type GetCardLocationByRankSuitRow struct {
+	ID              int32
+	Serial          string
+	HandID          sql.NullInt32
+	IsBoard         bool
+	AntennaTypeName string
+	IsMuck          sql.NullBool
+}
+
+func (q *Queries) GetCardLocationByRankSuit(ctx context.Context, arg GetCardLocationByRankSuitParams) (GetCardLocationByRankSuitRow, error) {
+	row := q.db.QueryRowContext(ctx, getCardLocationByRankSuit, arg.CardRank, arg.CardSuit)
+	var i GetCardLocationByRankSuitRow
+	err := row.Scan(
+		&i.ID,
+		&i.Serial,
+		&i.HandID,
+		&i.IsBoard,
+		&i.AntennaTypeName,
+		&i.IsMuck,
+	)
+	return i, err
+}
+
+const getCardsByAntennaTypeName = `-- name: GetCardsByAntennaTypeName :many
+SELECT card.id, card.card_suit, card.card_rank, card.serial
+FROM card
+JOIN antenna ON card.serial = antenna.serial
+JOIN antenna_type ON antenna.antenna_type_id = antenna_type.id
+WHERE antenna_type.name = ?
+ORDER BY card.id
+`
+
+type GetCardsByAntennaTypeNameRow struct {
+	ID       int32
+	CardSuit string
+	CardRank string
+	Serial   string
+}
+
+func (q *Queries) GetCardsByAntennaTypeName(ctx context.Context, name string) ([]GetCardsByAntennaTypeNameRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCardsByAntennaTypeName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCardsByAntennaTypeNameRow
+	for rows.Next() {
+		var i GetCardsByAntennaTypeNameRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CardSuit,
+			&i.CardRank,
+			&i.Serial,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCardsWithLocation = `-- name: GetCardsWithLocation :many
+SELECT
+    card.card_suit,
+    card.card_rank,
+    card.serial,
+    card.read_at,
+    antenna_type.name AS antenna_type_name,
+    hand.is_muck,
+    player.id AS player_id,
+    player.name AS player_name
+FROM card
+JOIN antenna ON card.serial = antenna.serial
+JOIN antenna_type ON antenna.antenna_type_id = antenna_type.id
+LEFT JOIN hand ON card.hand_id = hand.id
+LEFT JOIN player ON antenna.player_id = player.id
+`
+
+type GetCardsWithLocationRow struct {
+	CardSuit        string
+	CardRank        string
+	Serial          string
+	ReadAt          time.Time
+	AntennaTypeName string
+	IsMuck          sql.NullBool
+	PlayerID        sql.NullInt32
+	PlayerName      sql.NullString
+}
+
+func (q *Queries) GetCardsWithLocation(ctx context.Context) ([]GetCardsWithLocationRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCardsWithLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCardsWithLocationRow
+	for rows.Next() {
+		var i GetCardsWithLocationRow
+		if err := rows.Scan(
+			&i.CardSuit,
+			&i.CardRank,
+			&i.Serial,
+			&i.ReadAt,
+			&i.AntennaTypeName,
+			&i.IsMuck,
+			&i.PlayerID,
+			&i.PlayerName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const setCardHandByCardID = `-- name: SetCardHandByCardID :execresult
 UPDATE card SET hand_id = ?
 WHERE id = ?
@@ -208,3 +385,12 @@ type SetCardHandByCardIDParams struct {
 func (q *Queries) SetCardHandByCardID(ctx context.Context, arg SetCardHandByCardIDParams) (sql.Result, error) {
 	return q.db.ExecContext(ctx, setCardHandByCardID, arg.HandID, arg.ID)
 }
+
+const unsetCardHandByHandID = `-- name: UnsetCardHandByHandID :exec
+UPDATE card SET hand_id = NULL WHERE hand_id = ?
+`
+
+func (q *Queries) UnsetCardHandByHandID(ctx context.Context, handID sql.NullInt32) error {
+	_, err := q.db.ExecContext(ctx, unsetCardHandByHandID, handID)
+	return err
+}
@@ -7,11 +7,12 @@ package query
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createGame = `-- name: CreateGame :exec
-INSERT INTO game (id, status)
-VALUES (?, 'active')
+INSERT INTO game (id, status, session_id)
+VALUES (?, 'active', (SELECT id FROM session WHERE ended_at IS NULL ORDER BY started_at DESC LIMIT 1))
 `
 
 func (q *Queries) CreateGame(ctx context.Context, id string) error {
@@ -38,7 +39,10 @@ func (q *Queries) DeleteGameByID(ctx context.Context, id string) error {
 }
 
 const finishGame = `-- name: FinishGame :exec
-UPDATE game SET ended_at = NOW(), status = 'finished' WHERE id = ?
+UPDATE game
+SET ended_at = NOW(), status = 'finished', state = 'finished',
+    pot = (SELECT COALESCE(SUM(amount), 0) FROM player_action WHERE player_action.game_id = game.id)
+WHERE id = ?
 `
 
 func (q *Queries) FinishGame(ctx context.Context, id string) error {
@@ -47,7 +51,7 @@ func (q *Queries) FinishGame(ctx context.Context, id string) error {
 }
 
 const getCurrentGame = `-- name: GetCurrentGame :one
-SELECT id, started_at, ended_at, status FROM game WHERE status = 'active' ORDER BY started_at DESC LIMIT 1
+SELECT id, started_at, ended_at, status, state, button_position, label, paused_at, pot, session_id FROM game WHERE status = 'active' ORDER BY started_at DESC LIMIT 1
 `
 
 func (q *Queries) GetCurrentGame(ctx context.Context) (Game, error) {
@@ -58,12 +62,18 @@ func (q *Queries) GetCurrentGame(ctx context.Context) (Game, error) {
 		&i.StartedAt,
 		&i.EndedAt,
 		&i.Status,
+		&i.State,
+		&i.ButtonPosition,
+		&i.Label,
+		&i.PausedAt,
+		&i.Pot,
+		&i.SessionID,
 	)
 	return i, err
 }
 
 const getGameByID = `-- name: GetGameByID :one
-SELECT id, started_at, ended_at, status FROM game WHERE id = ? LIMIT 1
+SELECT id, started_at, ended_at, status, state, button_position, label, paused_at, pot, session_id FROM game WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetGameByID(ctx context.Context, id string) (Game, error) {
@@ -74,6 +84,71 @@ func (q *Queries) GetGameByID(ctx context.Context, id string) (Game, error) {
 		&i.StartedAt,
 		&i.EndedAt,
 		&i.Status,
+		&i.State,
+		&i.ButtonPosition,
+		&i.Label,
+		&i.PausedAt,
+		&i.Pot,
+		&i.SessionID,
 	)
 	return i, err
 }
+
+const getLastButtonPosition = `-- name: GetLastButtonPosition :one
+SELECT button_position FROM game WHERE button_position IS NOT NULL ORDER BY started_at DESC LIMIT 1
+`
+
+func (q *Queries) GetLastButtonPosition(ctx context.Context) (sql.NullInt32, error) {
+	row := q.db.QueryRowContext(ctx, getLastButtonPosition)
+	var button_position sql.NullInt32
+	err := row.Scan(&button_position)
+	return button_position, err
+}
+
+const pauseGame = `-- name: PauseGame :exec
+UPDATE game SET paused_at = NOW() WHERE id = ? AND paused_at IS NULL
+`
+
+func (q *Queries) PauseGame(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, pauseGame, id)
+	return err
+}
+
+const resumeGame = `-- name: ResumeGame :exec
+UPDATE game SET paused_at = NULL WHERE id = ?
+`
+
+func (q *Queries) ResumeGame(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, resumeGame, id)
+	return err
+}
+
+const startGame = `-- name: StartGame :exec
+INSERT INTO game (id, status, button_position, label, session_id)
+VALUES (?, 'active', ?, ?, (SELECT id FROM session WHERE ended_at IS NULL ORDER BY started_at DESC LIMIT 1))
+`
+
+type StartGameParams struct {
+	ID             string
+	ButtonPosition sql.NullInt32
+	Label          sql.NullString
+}
+
+func (q *Queries) StartGame(ctx context.Context, arg StartGameParams) error {
+	_, err := q.db.ExecContext(ctx, startGame, arg.ID, arg.ButtonPosition, arg.Label)
+	return err
+}
+
+const updateGameState = `-- name: UpdateGameState :exec
+UPDATE game SET state = ? WHERE id = ?
+`
+
+type UpdateGameStateParams struct {
+	State string
+	ID    string
+}
+
+func (q *Queries) UpdateGameState(ctx context.Context, arg UpdateGameStateParams) error {
+	_, err := q.db.ExecContext(ctx, updateGameState, arg.State, arg.ID)
+	return err
+}
@@ -24,6 +24,17 @@ func (q *Queries) AddHand(ctx context.Context, arg AddHandParams) (sql.Result, e
 	return q.db.ExecContext(ctx, addHand, arg.PlayerID, arg.GameID)
 }
 
+const countHandsByGameID = `-- name: CountHandsByGameID :one
+SELECT COUNT(*) FROM hand WHERE game_id = ?
+`
+
+func (q *Queries) CountHandsByGameID(ctx context.Context, gameID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countHandsByGameID, gameID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deleteHandAll = `-- name: DeleteHandAll :exec
 DELETE FROM hand
 `
@@ -51,6 +62,24 @@ func (q *Queries) DeleteHandByGameID(ctx context.Context, gameID string) error {
 	return err
 }
 
+const deleteHandByID = `-- name: DeleteHandByID :exec
+DELETE FROM hand WHERE id = ?
+`
+
+func (q *Queries) DeleteHandByID(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteHandByID, id)
+	return err
+}
+
+const deleteHandEquityAll = `-- name: DeleteHandEquityAll :exec
+DELETE FROM hand_equity
+`
+
+func (q *Queries) DeleteHandEquityAll(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteHandEquityAll)
+	return err
+}
+
 const getHand = `-- name: GetHand :one
 SELECT id, player_id, equity FROM hand WHERE id = ? LIMIT 1
 `
@@ -90,6 +119,34 @@ func (q *Queries) GetHandBySerial(ctx context.Context, serial string) (GetHandBy
 	return i, err
 }
 
+const getHandEquities = `-- name: GetHandEquities :many
+SELECT hand_id, board_number, equity FROM hand_equity
+ORDER BY hand_id, board_number
+`
+
+func (q *Queries) GetHandEquities(ctx context.Context) ([]HandEquity, error) {
+	rows, err := q.db.QueryContext(ctx, getHandEquities)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []HandEquity
+	for rows.Next() {
+		var i HandEquity
+		if err := rows.Scan(&i.HandID, &i.BoardNumber, &i.Equity); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getHandNotMucked = `-- name: GetHandNotMucked :many
 SELECT id, player_id, equity FROM hand WHERE is_muck = false
 `
@@ -191,6 +248,20 @@ func (q *Queries) ResetEquity(ctx context.Context) error {
 	return err
 }
 
+const unmuckHandByGameIDAndPlayerID = `-- name: UnmuckHandByGameIDAndPlayerID :exec
+UPDATE hand SET is_muck = false WHERE game_id = ? AND player_id = ?
+`
+
+type UnmuckHandByGameIDAndPlayerIDParams struct {
+	GameID   string
+	PlayerID int32
+}
+
+func (q *Queries) UnmuckHandByGameIDAndPlayerID(ctx context.Context, arg UnmuckHandByGameIDAndPlayerIDParams) error {
+	_, err := q.db.ExecContext(ctx, unmuckHandByGameIDAndPlayerID, arg.GameID, arg.PlayerID)
+	return err
+}
+
 const updateEquity = `-- name: UpdateEquity :exec
 UPDATE hand SET equity = ? WHERE id = ?
 `
@@ -204,3 +275,20 @@ func (q *Queries) UpdateEquity(ctx context.Context, arg UpdateEquityParams) erro
 	_, err := q.db.ExecContext(ctx, updateEquity, arg.Equity, arg.ID)
 	return err
 }
+
+const upsertHandEquity = `-- name: UpsertHandEquity :exec
+INSERT INTO hand_equity (hand_id, board_number, equity)
+VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE equity = VALUES(equity)
+`
+
+type UpsertHandEquityParams struct {
+	HandID      int32
+	BoardNumber int32
+	Equity      float64
+}
+
+func (q *Queries) UpsertHandEquity(ctx context.Context, arg UpsertHandEquityParams) error {
+	_, err := q.db.ExecContext(ctx, upsertHandEquity, arg.HandID, arg.BoardNumber, arg.Equity)
+	return err
+}
@@ -7,12 +7,33 @@ package query
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
+const addWonToHandHistory = `-- name: AddWonToHandHistory :exec
+UPDATE hand_history SET won = won + ? WHERE game_id = ? AND player_id = ?
+`
+
+type AddWonToHandHistoryParams struct {
+	Won      int64
+	GameID   string
+	PlayerID int32
+}
+
+func (q *Queries) AddWonToHandHistory(ctx context.Context, arg AddWonToHandHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, addWonToHandHistory, arg.Won, arg.GameID, arg.PlayerID)
+	return err
+}
+
 const copyHandsToHistory = `-- name: CopyHandsToHistory :exec
-INSERT INTO hand_history (game_id, player_id, equity, is_muck)
-SELECT hand.game_id, hand.player_id, hand.equity, hand.is_muck
+INSERT INTO hand_history (game_id, player_id, equity, is_muck, contributed, session_id)
+SELECT hand.game_id, hand.player_id, hand.equity, hand.is_muck,
+       COALESCE((SELECT SUM(amount) FROM player_action
+                 WHERE player_action.game_id = hand.game_id AND player_action.player_id = hand.player_id), 0),
+       game.session_id
 FROM hand
+         JOIN game ON game.id = hand.game_id
 WHERE hand.game_id = ?
 `
 
@@ -21,8 +42,128 @@ func (q *Queries) CopyHandsToHistory(ctx context.Context, gameID string) error {
 	return err
 }
 
+const getBiggestPotsByPlayerID = `-- name: GetBiggestPotsByPlayerID :many
+SELECT hand_history.game_id, hand_history.won, hand_history.hand_category, hand_history.created_at, COALESCE(game.pot, 0) AS pot
+FROM hand_history
+         LEFT JOIN game ON game.id = hand_history.game_id
+WHERE hand_history.player_id = ?
+  AND hand_history.won > 0
+  AND hand_history.created_at >= ?
+  AND hand_history.created_at < ?
+  AND (? IS NULL OR hand_history.session_id = ?)
+ORDER BY hand_history.won DESC
+LIMIT ?
+`
+
+type GetBiggestPotsByPlayerIDParams struct {
+	PlayerID    int32
+	CreatedAt   time.Time
+	CreatedAt_2 time.Time
+	SessionID   sql.NullInt32
+	Limit       int32
+}
+
+type GetBiggestPotsByPlayerIDRow struct {
+	GameID       string
+	Won          int64
+	HandCategory sql.NullString
+	CreatedAt    time.Time
+	Pot          int64
+}
+
+func (q *Queries) GetBiggestPotsByPlayerID(ctx context.Context, arg GetBiggestPotsByPlayerIDParams) ([]GetBiggestPotsByPlayerIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBiggestPotsByPlayerID,
+		arg.PlayerID,
+		arg.CreatedAt,
+		arg.CreatedAt_2,
+		arg.SessionID,
+		arg.SessionID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBiggestPotsByPlayerIDRow
+	for rows.Next() {
+		var i GetBiggestPotsByPlayerIDRow
+		if err := rows.Scan(
+			&i.GameID,
+			&i.Won,
+			&i.HandCategory,
+			&i.CreatedAt,
+			&i.Pot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHandCategoryCounts = `-- name: GetHandCategoryCounts :many
+SELECT hand_history.player_id, hand_history.hand_category, COUNT(*) AS count
+FROM hand_history
+WHERE hand_history.showdown = TRUE
+  AND hand_history.hand_category IS NOT NULL
+  AND hand_history.created_at >= ?
+  AND hand_history.created_at < ?
+  AND (? IS NULL OR hand_history.session_id = ?)
+  AND (? IS NULL OR hand_history.player_id = ?)
+GROUP BY hand_history.player_id, hand_history.hand_category
+`
+
+type GetHandCategoryCountsParams struct {
+	CreatedAt   time.Time
+	CreatedAt_2 time.Time
+	SessionID   sql.NullInt32
+	PlayerID    sql.NullInt32
+}
+
+type GetHandCategoryCountsRow struct {
+	PlayerID     int32
+	HandCategory sql.NullString
+	Count        int64
+}
+
+func (q *Queries) GetHandCategoryCounts(ctx context.Context, arg GetHandCategoryCountsParams) ([]GetHandCategoryCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getHandCategoryCounts,
+		arg.CreatedAt,
+		arg.CreatedAt_2,
+		arg.SessionID,
+		arg.SessionID,
+		arg.PlayerID,
+		arg.PlayerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetHandCategoryCountsRow
+	for rows.Next() {
+		var i GetHandCategoryCountsRow
+		if err := rows.Scan(&i.PlayerID, &i.HandCategory, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getHandHistoryByGameID = `-- name: GetHandHistoryByGameID :many
-SELECT id, game_id, player_id, equity, is_muck, created_at
+SELECT id, game_id, player_id, equity, is_muck, created_at, contributed, won, showdown, showdown_won, hand_category, session_id
 FROM hand_history
 WHERE game_id = ?
 ORDER BY created_at DESC
@@ -44,6 +185,12 @@ func (q *Queries) GetHandHistoryByGameID(ctx context.Context, gameID string) ([]
 			&i.Equity,
 			&i.IsMuck,
 			&i.CreatedAt,
+			&i.Contributed,
+			&i.Won,
+			&i.Showdown,
+			&i.ShowdownWon,
+			&i.HandCategory,
+			&i.SessionID,
 		); err != nil {
 			return nil, err
 		}
@@ -59,14 +206,32 @@ func (q *Queries) GetHandHistoryByGameID(ctx context.Context, gameID string) ([]
 }
 
 const getHandHistoryByPlayerID = `-- name: GetHandHistoryByPlayerID :many
-SELECT id, game_id, player_id, equity, is_muck, created_at
+SELECT hand_history.id, hand_history.game_id, hand_history.player_id, hand_history.equity, hand_history.is_muck, hand_history.created_at,
+       hand_history.contributed, hand_history.won, hand_history.showdown, hand_history.showdown_won, hand_history.hand_category,
+       hand_history.session_id
 FROM hand_history
-WHERE player_id = ?
-ORDER BY created_at DESC
+WHERE hand_history.player_id = ?
+  AND hand_history.created_at >= ?
+  AND hand_history.created_at < ?
+  AND (? IS NULL OR hand_history.session_id = ?)
+ORDER BY hand_history.created_at DESC
 `
 
-func (q *Queries) GetHandHistoryByPlayerID(ctx context.Context, playerID int32) ([]HandHistory, error) {
-	rows, err := q.db.QueryContext(ctx, getHandHistoryByPlayerID, playerID)
+type GetHandHistoryByPlayerIDParams struct {
+	PlayerID    int32
+	CreatedAt   time.Time
+	CreatedAt_2 time.Time
+	SessionID   sql.NullInt32
+}
+
+func (q *Queries) GetHandHistoryByPlayerID(ctx context.Context, arg GetHandHistoryByPlayerIDParams) ([]HandHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getHandHistoryByPlayerID,
+		arg.PlayerID,
+		arg.CreatedAt,
+		arg.CreatedAt_2,
+		arg.SessionID,
+		arg.SessionID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -81,6 +246,12 @@ func (q *Queries) GetHandHistoryByPlayerID(ctx context.Context, playerID int32)
 			&i.Equity,
 			&i.IsMuck,
 			&i.CreatedAt,
+			&i.Contributed,
+			&i.Won,
+			&i.Showdown,
+			&i.ShowdownWon,
+			&i.HandCategory,
+			&i.SessionID,
 		); err != nil {
 			return nil, err
 		}
@@ -94,3 +265,111 @@ func (q *Queries) GetHandHistoryByPlayerID(ctx context.Context, playerID int32)
 	}
 	return items, nil
 }
+
+const getPlayerStats = `-- name: GetPlayerStats :many
+SELECT
+    hand_history.player_id,
+    player.name,
+    COUNT(*) AS hands_dealt,
+    CAST(SUM(hand_history.is_muck) AS SIGNED) AS hands_mucked,
+    CAST(SUM(hand_history.showdown) AS SIGNED) AS showdowns,
+    CAST(SUM(hand_history.showdown_won) AS SIGNED) AS showdowns_won,
+    CAST(AVG(CASE WHEN hand_history.showdown THEN
+        (SELECT allin_equity.equity FROM allin_equity
+         WHERE allin_equity.game_id = hand_history.game_id AND allin_equity.player_id = hand_history.player_id
+         ORDER BY allin_equity.id LIMIT 1) END) AS DOUBLE) AS showdown_equity,
+    CAST(MAX(hand_history.won) AS SIGNED) AS biggest_pot,
+    CAST(SUM(hand_history.won) AS SIGNED) AS won,
+    CAST(SUM(hand_history.contributed) AS SIGNED) AS contributed
+FROM hand_history
+         JOIN player ON player.id = hand_history.player_id
+WHERE hand_history.created_at >= ?
+  AND hand_history.created_at < ?
+  AND (? IS NULL OR hand_history.session_id = ?)
+  AND (? IS NULL OR hand_history.player_id = ?)
+GROUP BY hand_history.player_id, player.name
+ORDER BY hand_history.player_id
+`
+
+type GetPlayerStatsParams struct {
+	CreatedAt   time.Time
+	CreatedAt_2 time.Time
+	SessionID   sql.NullInt32
+	PlayerID    sql.NullInt32
+}
+
+type GetPlayerStatsRow struct {
+	PlayerID       int32
+	Name           string
+	HandsDealt     int64
+	HandsMucked    int64
+	Showdowns      int64
+	ShowdownsWon   int64
+	ShowdownEquity sql.NullFloat64
+	BiggestPot     int64
+	Won            int64
+	Contributed    int64
+}
+
+func (q *Queries) GetPlayerStats(ctx context.Context, arg GetPlayerStatsParams) ([]GetPlayerStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPlayerStats,
+		arg.CreatedAt,
+		arg.CreatedAt_2,
+		arg.SessionID,
+		arg.SessionID,
+		arg.PlayerID,
+		arg.PlayerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPlayerStatsRow
+	for rows.Next() {
+		var i GetPlayerStatsRow
+		if err := rows.Scan(
+			&i.PlayerID,
+			&i.Name,
+			&i.HandsDealt,
+			&i.HandsMucked,
+			&i.Showdowns,
+			&i.ShowdownsWon,
+			&i.ShowdownEquity,
+			&i.BiggestPot,
+			&i.Won,
+			&i.Contributed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setShowdownToHandHistory = `-- name: SetShowdownToHandHistory :exec
+UPDATE hand_history SET showdown = TRUE, showdown_won = ?, hand_category = ?
+WHERE game_id = ? AND player_id = ?
+`
+
+type SetShowdownToHandHistoryParams struct {
+	ShowdownWon  bool
+	HandCategory sql.NullString
+	GameID       string
+	PlayerID     int32
+}
+
+func (q *Queries) SetShowdownToHandHistory(ctx context.Context, arg SetShowdownToHandHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, setShowdownToHandHistory,
+		arg.ShowdownWon,
+		arg.HandCategory,
+		arg.GameID,
+		arg.PlayerID,
+	)
+	return err
+}
@@ -6,14 +6,30 @@ package query
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
+type AllinEquity struct {
+	ID         int32
+	GameID     string
+	PlayerID   int32
+	SeatNumber int32
+	Street     string
+	Equity     sql.NullFloat64
+	Pot        int64
+	Won        int64
+	Settled    bool
+	CreatedAt  time.Time
+	SessionID  sql.NullInt32
+}
+
 type Antenna struct {
 	ID            int32
 	Serial        string
 	AntennaTypeID int32
 	PlayerID      sql.NullInt32
+	SeatNumber    sql.NullInt32
 }
 
 type AntennaType struct {
@@ -22,20 +38,70 @@ type AntennaType struct {
 }
 
 type Card struct {
-	ID       int32
-	CardSuit string
-	CardRank string
-	IsBoard  bool
-	HandID   sql.NullInt32
-	Serial   string
-	GameID   string
+	ID          int32
+	CardSuit    string
+	CardRank    string
+	IsBoard     bool
+	HandID      sql.NullInt32
+	Serial      string
+	GameID      string
+	ReadAt      time.Time
+	BoardNumber int32
+}
+
+type CardRead struct {
+	ID             int32
+	DeviceID       string
+	IdempotencyKey string
+	Response       string
+	CreatedAt      time.Time
+}
+
+type Device struct {
+	DeviceID          string
+	ClientType        string
+	FirmwareVersion   string
+	OfferedVersion    sql.NullString
+	DownloadedVersion sql.NullString
+	DownloadedAt      sql.NullTime
+	LastBootAt        time.Time
+}
+
+type Firmware struct {
+	ID         int32
+	ClientType string
+	Version    string
+	Sha256     string
+	Size       int32
+	Data       []byte
+	IsDesired  bool
+	CreatedAt  time.Time
 }
 
 type Game struct {
-	ID        string
-	StartedAt time.Time
-	EndedAt   sql.NullTime
-	Status    string
+	ID             string
+	StartedAt      time.Time
+	EndedAt        sql.NullTime
+	Status         string
+	State          string
+	ButtonPosition sql.NullInt32
+	Label          sql.NullString
+	PausedAt       sql.NullTime
+	Pot            int64
+	SessionID      sql.NullInt32
+}
+
+type GameActivity struct {
+	GameID          string
+	AntennaTypeName string
+	LastReadAt      time.Time
+}
+
+type GameBoard struct {
+	GameID      string
+	BoardNumber int32
+	SharedCards int32
+	CreatedAt   time.Time
 }
 
 type Hand struct {
@@ -46,16 +112,76 @@ type Hand struct {
 	GameID   string
 }
 
+type HandEquity struct {
+	HandID      int32
+	BoardNumber int32
+	Equity      float64
+}
+
 type HandHistory struct {
-	ID        int32
-	GameID    string
-	PlayerID  int32
-	Equity    sql.NullFloat64
-	IsMuck    bool
-	CreatedAt time.Time
+	ID           int32
+	GameID       string
+	PlayerID     int32
+	Equity       sql.NullFloat64
+	IsMuck       bool
+	CreatedAt    time.Time
+	Contributed  int64
+	Won          int64
+	Showdown     bool
+	ShowdownWon  bool
+	HandCategory sql.NullString
+	SessionID    sql.NullInt32
 }
 
 type Player struct {
-	ID   int32
-	Name string
+	ID            int32
+	Name          string
+	DisplayName   sql.NullString
+	AvatarUrl     sql.NullString
+	Country       sql.NullString
+	MembershipUid sql.NullString
+	Stack         int64
+}
+
+type PlayerAction struct {
+	ID         int32
+	GameID     string
+	SeatNumber int32
+	PlayerID   sql.NullInt32
+	Street     string
+	Action     string
+	Amount     int64
+	CreatedAt  time.Time
+}
+
+type Session struct {
+	ID        int32
+	Name      string
+	Venue     sql.NullString
+	Tables    json.RawMessage
+	StartedAt time.Time
+	EndedAt   sql.NullTime
+}
+
+type Tournament struct {
+	ID             int32
+	Name           string
+	Levels         json.RawMessage
+	StartingStack  int64
+	Entries        int32
+	Level          int32
+	LevelStartedAt time.Time
+	PausedAt       sql.NullTime
+	StartedAt      time.Time
+	EndedAt        sql.NullTime
+}
+
+type UnawardedPot struct {
+	ID        int32
+	GameID    string
+	Amount    int64
+	PlayerIds json.RawMessage
+	Reason    string
+	CreatedAt time.Time
+	AwardedAt sql.NullTime
 }
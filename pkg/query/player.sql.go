@@ -10,6 +10,20 @@ import (
 	"database/sql"
 )
 
+const addStackToPlayer = `-- name: AddStackToPlayer :exec
+UPDATE player SET stack = stack + ? WHERE id = ?
+`
+
+type AddStackToPlayerParams struct {
+	Stack int64
+	ID    int32
+}
+
+func (q *Queries) AddStackToPlayer(ctx context.Context, arg AddStackToPlayerParams) error {
+	_, err := q.db.ExecContext(ctx, addStackToPlayer, arg.Stack, arg.ID)
+	return err
+}
+
 const addPlayer = `-- name: AddPlayer :execresult
 INSERT INTO player (name)
 VALUES (?)
@@ -19,6 +33,29 @@ func (q *Queries) AddPlayer(ctx context.Context, name string) (sql.Result, error
 	return q.db.ExecContext(ctx, addPlayer, name)
 }
 
+const addPlayerProfile = `-- name: AddPlayerProfile :execresult
+INSERT INTO player (name, display_name, avatar_url, country, membership_uid)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type AddPlayerProfileParams struct {
+	Name          string
+	DisplayName   sql.NullString
+	AvatarUrl     sql.NullString
+	Country       sql.NullString
+	MembershipUid sql.NullString
+}
+
+func (q *Queries) AddPlayerProfile(ctx context.Context, arg AddPlayerProfileParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, addPlayerProfile,
+		arg.Name,
+		arg.DisplayName,
+		arg.AvatarUrl,
+		arg.Country,
+		arg.MembershipUid,
+	)
+}
+
 const deletePlayerWithHandWithCards = `-- name: DeletePlayerWithHandWithCards :exec
 DELETE FROM card
 WHERE hand_id IN (SELECT id FROM hand WHERE player_id = ?)
@@ -30,19 +67,45 @@ func (q *Queries) DeletePlayerWithHandWithCards(ctx context.Context, playerID in
 }
 
 const getPlayer = `-- name: GetPlayer :one
-SELECT id, name FROM player
+SELECT id, name, display_name, avatar_url, country, membership_uid FROM player
 WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetPlayer(ctx context.Context, id int32) (Player, error) {
 	row := q.db.QueryRowContext(ctx, getPlayer, id)
 	var i Player
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DisplayName,
+		&i.AvatarUrl,
+		&i.Country,
+		&i.MembershipUid,
+	)
+	return i, err
+}
+
+const getPlayerByMembershipUid = `-- name: GetPlayerByMembershipUid :one
+SELECT id, name, display_name, avatar_url, country, membership_uid FROM player
+WHERE membership_uid = ? LIMIT 1
+`
+
+func (q *Queries) GetPlayerByMembershipUid(ctx context.Context, membershipUid sql.NullString) (Player, error) {
+	row := q.db.QueryRowContext(ctx, getPlayerByMembershipUid, membershipUid)
+	var i Player
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DisplayName,
+		&i.AvatarUrl,
+		&i.Country,
+		&i.MembershipUid,
+	)
 	return i, err
 }
 
 const getPlayerBySerial = `-- name: GetPlayerBySerial :one
-SELECT player.id, player.name
+SELECT player.id, player.name, player.display_name, player.avatar_url, player.country, player.membership_uid
 FROM player
 JOIN antenna ON player.id = antenna.player_id
 WHERE antenna.serial = ? LIMIT 1
@@ -51,10 +114,67 @@ WHERE antenna.serial = ? LIMIT 1
 func (q *Queries) GetPlayerBySerial(ctx context.Context, serial string) (Player, error) {
 	row := q.db.QueryRowContext(ctx, getPlayerBySerial, serial)
 	var i Player
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DisplayName,
+		&i.AvatarUrl,
+		&i.Country,
+		&i.MembershipUid,
+	)
 	return i, err
 }
 
+const getPlayerProfiles = `-- name: GetPlayerProfiles :many
+SELECT player.id, player.name, player.display_name, player.avatar_url, player.country, player.membership_uid, antenna.serial, antenna.seat_number
+FROM player
+LEFT JOIN antenna ON player.id = antenna.player_id
+ORDER BY player.id
+`
+
+type GetPlayerProfilesRow struct {
+	ID            int32
+	Name          string
+	DisplayName   sql.NullString
+	AvatarUrl     sql.NullString
+	Country       sql.NullString
+	MembershipUid sql.NullString
+	Serial        sql.NullString
+	SeatNumber    sql.NullInt32
+}
+
+func (q *Queries) GetPlayerProfiles(ctx context.Context) ([]GetPlayerProfilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPlayerProfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPlayerProfilesRow
+	for rows.Next() {
+		var i GetPlayerProfilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.DisplayName,
+			&i.AvatarUrl,
+			&i.Country,
+			&i.MembershipUid,
+			&i.Serial,
+			&i.SeatNumber,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPlayerWithDevice = `-- name: GetPlayerWithDevice :one
 SELECT player.id, player.name, antenna.serial
 FROM player
@@ -76,15 +196,16 @@ func (q *Queries) GetPlayerWithDevice(ctx context.Context, id int32) (GetPlayerW
 }
 
 const getPlayersWithDevice = `-- name: GetPlayersWithDevice :many
-SELECT player.id, player.name, antenna.serial
+SELECT player.id, player.name, antenna.serial, antenna.seat_number
 FROM player
 JOIN antenna ON player.id = antenna.player_id
 `
 
 type GetPlayersWithDeviceRow struct {
-	ID     int32
-	Name   string
-	Serial string
+	ID         int32
+	Name       string
+	Serial     string
+	SeatNumber sql.NullInt32
 }
 
 func (q *Queries) GetPlayersWithDevice(ctx context.Context) ([]GetPlayersWithDeviceRow, error) {
@@ -96,7 +217,12 @@ func (q *Queries) GetPlayersWithDevice(ctx context.Context) ([]GetPlayersWithDev
 	var items []GetPlayersWithDeviceRow
 	for rows.Next() {
 		var i GetPlayersWithDeviceRow
-		if err := rows.Scan(&i.ID, &i.Name, &i.Serial); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Serial,
+			&i.SeatNumber,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -115,6 +241,7 @@ SELECT
     player.id,
     player.name,
     hand.id AS hand_id,
+    hand.game_id,
     hand.equity,
     hand.is_muck,
     card_a.card_suit AS card_a_suit,
@@ -135,6 +262,7 @@ type GetPlayersWithHandRow struct {
 	ID           int32
 	Name         string
 	HandID       int32
+	GameID       string
 	Equity       sql.NullFloat64
 	IsMuck       bool
 	CardASuit    string
@@ -158,6 +286,7 @@ func (q *Queries) GetPlayersWithHand(ctx context.Context) ([]GetPlayersWithHandR
 			&i.ID,
 			&i.Name,
 			&i.HandID,
+			&i.GameID,
 			&i.Equity,
 			&i.IsMuck,
 			&i.CardASuit,
@@ -180,6 +309,30 @@ func (q *Queries) GetPlayersWithHand(ctx context.Context) ([]GetPlayersWithHandR
 	return items, nil
 }
 
+const setStackToPlayer = `-- name: SetStackToPlayer :exec
+UPDATE player SET stack = ? WHERE id = ?
+`
+
+type SetStackToPlayerParams struct {
+	Stack int64
+	ID    int32
+}
+
+func (q *Queries) SetStackToPlayer(ctx context.Context, arg SetStackToPlayerParams) error {
+	_, err := q.db.ExecContext(ctx, setStackToPlayer, arg.Stack, arg.ID)
+	return err
+}
+
+const setStackToSeatedPlayers = `-- name: SetStackToSeatedPlayers :exec
+UPDATE player SET stack = ?
+WHERE id IN (SELECT player_id FROM antenna WHERE seat_number IS NOT NULL AND player_id IS NOT NULL)
+`
+
+func (q *Queries) SetStackToSeatedPlayers(ctx context.Context, stack int64) error {
+	_, err := q.db.ExecContext(ctx, setStackToSeatedPlayers, stack)
+	return err
+}
+
 const updatePlayerName = `-- name: UpdatePlayerName :execresult
 UPDATE player
 SET name = ?
@@ -194,3 +347,30 @@ type UpdatePlayerNameParams struct {
 func (q *Queries) UpdatePlayerName(ctx context.Context, arg UpdatePlayerNameParams) (sql.Result, error) {
 	return q.db.ExecContext(ctx, updatePlayerName, arg.Name, arg.ID)
 }
+
+const updatePlayerProfile = `-- name: UpdatePlayerProfile :exec
+UPDATE player
+SET name = ?, display_name = ?, avatar_url = ?, country = ?, membership_uid = ?
+WHERE id = ?
+`
+
+type UpdatePlayerProfileParams struct {
+	Name          string
+	DisplayName   sql.NullString
+	AvatarUrl     sql.NullString
+	Country       sql.NullString
+	MembershipUid sql.NullString
+	ID            int32
+}
+
+func (q *Queries) UpdatePlayerProfile(ctx context.Context, arg UpdatePlayerProfileParams) error {
+	_, err := q.db.ExecContext(ctx, updatePlayerProfile,
+		arg.Name,
+		arg.DisplayName,
+		arg.AvatarUrl,
+		arg.Country,
+		arg.MembershipUid,
+		arg.ID,
+	)
+	return err
+}
@@ -26,95 +26,63 @@ import (
 	"github.com/whywaita/rfid-poker/pkg/store"
 )
 
-type antennaTypeTimestamp struct {
-	lastReadTime time.Time
-	hasReadCard  bool
-}
-
-var (
-	antennaTypeTimestamps = map[string]*antennaTypeTimestamp{
-		"player": {lastReadTime: time.Time{}, hasReadCard: false},
-		"board":  {lastReadTime: time.Time{}, hasReadCard: false},
-		"muck":   {lastReadTime: time.Time{}, hasReadCard: false},
-	}
-	antennaTypeTimestampsMu sync.RWMutex
-)
-
-// updateLastCardReadTime updates the timestamp of the last card read for a specific antenna type
-func updateLastCardReadTime(antennaType string) {
-	antennaTypeTimestampsMu.Lock()
-	defer antennaTypeTimestampsMu.Unlock()
-
-	if ts, ok := antennaTypeTimestamps[antennaType]; ok {
-		ts.lastReadTime = time.Now()
-		ts.hasReadCard = true
-	}
-}
-
-// getAntennaTypeTimestamps returns a copy of all antenna type timestamps
-func getAntennaTypeTimestamps() map[string]antennaTypeTimestamp {
-	antennaTypeTimestampsMu.RLock()
-	defer antennaTypeTimestampsMu.RUnlock()
-
-	result := make(map[string]antennaTypeTimestamp)
-	for k, v := range antennaTypeTimestamps {
-		result[k] = *v
-	}
-	return result
-}
-
-// resetAntennaTypeTimestamps resets all antenna type timestamps
-func resetAntennaTypeTimestamps() {
-	antennaTypeTimestampsMu.Lock()
-	defer antennaTypeTimestampsMu.Unlock()
-
-	for _, ts := range antennaTypeTimestamps {
-		ts.lastReadTime = time.Time{}
-		ts.hasReadCard = false
-	}
-}
-
-// restoreAntennaTypeTimestamps restores antenna type timestamps from the database on server startup
-func restoreAntennaTypeTimestamps(ctx context.Context, conn *sql.DB) error {
-	logger := slog.With("method", "restoreAntennaTypeTimestamps")
-
-	q := query.New(conn)
-
-	// Check if there's an active game
-	_, err := q.GetCurrentGame(ctx)
+// gameActivityInterval is the minimum interval to save the time of the last card read for an antenna type.
+// Reads in between are not saved nor notified, they extend the timeout by less than the interval.
+const gameActivityInterval = 1 * time.Second
+
+// gameActivitySaved is the time the last card read was saved (key: game ID and antenna type)
+var gameActivitySaved = struct {
+	mu      sync.Mutex
+	savedAt map[[2]string]time.Time
+}{savedAt: make(map[[2]string]time.Time)}
+
+// updateLastCardReadTime saves the time of the last card read for a specific antenna type in the current game,
+// at most once per gameActivityInterval
+func updateLastCardReadTime(ctx context.Context, t *tableTx, antennaType string) error {
+	game, err := t.q.GetCurrentGame(ctx)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			// No active game, nothing to restore
-			logger.InfoContext(ctx, "no active game found, skipping timestamp restoration")
 			return nil
 		}
 		return fmt.Errorf("q.GetCurrentGame(): %w", err)
 	}
 
-	// Get all antenna types that have cards in the current game
-	antennaTypes, err := q.GetAntennaTypesWithCardsInCurrentGame(ctx)
-	if err != nil {
-		return fmt.Errorf("q.GetAntennaTypesWithCardsInCurrentGame(): %w", err)
+	now := time.Now()
+	key := [2]string{game.ID, antennaType}
+	gameActivitySaved.mu.Lock()
+	defer gameActivitySaved.mu.Unlock()
+	if savedAt, ok := gameActivitySaved.savedAt[key]; ok && now.Sub(savedAt) < gameActivityInterval {
+		return nil
 	}
 
-	// Restore timestamps for antenna types that have cards
-	antennaTypeTimestampsMu.Lock()
-	defer antennaTypeTimestampsMu.Unlock()
-
-	now := time.Now()
-	for _, antennaTypeName := range antennaTypes {
-		if ts, ok := antennaTypeTimestamps[antennaTypeName]; ok {
-			ts.hasReadCard = true
-			ts.lastReadTime = now
-			logger.InfoContext(ctx, "restored antenna type timestamp",
-				"antenna_type", antennaTypeName,
-				"last_read_time", now)
+	if err := store.RecordGameActivity(ctx, t.q, game.ID, antennaType, now); err != nil {
+		return fmt.Errorf("store.RecordGameActivity(): %w", err)
+	}
+	for k := range gameActivitySaved.savedAt {
+		if k[0] != game.ID {
+			// finished games
+			delete(gameActivitySaved.savedAt, k)
 		}
 	}
+	gameActivitySaved.savedAt[key] = now
 
+	if isGameTimeoutEnabled(config.Conf) {
+		// the time until the game is cleared is extended
+		t.notify()
+	}
 	return nil
 }
 
+// getGameTimeout returns the timeout of the current game with the configured timeouts
+func getGameTimeout(ctx context.Context, q *query.Queries) (*store.GameTimeout, error) {
+	return store.GetGameTimeout(ctx, q, config.Conf.GameTimeoutSeconds, config.Conf.GameTimeoutSecondsByAntennaType)
+}
+
+// isGameTimeoutEnabled returns true if the game is cleared by the timeout
+func isGameTimeoutEnabled(cc config.Config) bool {
+	return cc.GameTimeoutSeconds > 0 && gameEndPolicy(cc) == GameEndPolicyTimeout
+}
+
 // startGameTimeoutChecker starts a goroutine that checks for game timeout
 func startGameTimeoutChecker(ctx context.Context, conn *sql.DB) {
 	timeoutSeconds := config.Conf.GameTimeoutSeconds
@@ -123,6 +91,22 @@ func startGameTimeoutChecker(ctx context.Context, conn *sql.DB) {
 		slog.InfoContext(ctx, "game timeout is disabled")
 		return
 	}
+	if policy := gameEndPolicy(config.Conf); policy != GameEndPolicyTimeout {
+		slog.InfoContext(ctx, "game timeout is disabled", "game_end_policy", policy)
+		return
+	}
+
+	q := query.New(conn)
+
+	// Timestamps are saved with the game, so the timer continues from before the restart
+	if timeout, err := getGameTimeout(ctx, q); err != nil {
+		slog.WarnContext(ctx, "failed to get game timeout", "error", err)
+	} else if timeout != nil && timeout.Started {
+		slog.InfoContext(ctx, "restored game timeout",
+			"game_id", timeout.GameID,
+			"expires_at", timeout.ExpiresAt,
+			"remaining", timeout.Remaining(time.Now()))
+	}
 
 	go func() {
 		ticker := time.NewTicker(5 * time.Second) // Check every 5 seconds
@@ -135,60 +119,31 @@ func startGameTimeoutChecker(ctx context.Context, conn *sql.DB) {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				timestamps := getAntennaTypeTimestamps()
-
-				// Check if player and board have both read cards
-				playerTs, hasPlayer := timestamps["player"]
-				boardTs, hasBoard := timestamps["board"]
-
-				// Game must have started: both player and board must have read cards
-				if !hasPlayer || !playerTs.hasReadCard || !hasBoard || !boardTs.hasReadCard {
-					// Game hasn't properly started yet
+				timeout, err := getGameTimeout(ctx, q)
+				if err != nil {
+					slog.WarnContext(ctx, "failed to get game timeout", "error", err)
 					continue
 				}
 
-				// Check if all antenna types that have read cards have timed out
-				var activeTypes []string      // antenna types that have read cards
-				var timedOutTypes []string    // antenna types that have timed out
-				var stillActiveTypes []string // antenna types still reading cards
-
-				for antennaType, ts := range timestamps {
-					// Skip if no card has been read yet for this antenna type
-					if !ts.hasReadCard {
-						continue
-					}
-
-					activeTypes = append(activeTypes, antennaType)
-
-					elapsed := time.Since(ts.lastReadTime)
-					if elapsed >= time.Duration(timeoutSeconds)*time.Second {
-						timedOutTypes = append(timedOutTypes, antennaType)
-					} else {
-						stillActiveTypes = append(stillActiveTypes, antennaType)
-					}
+				// Game must have started: both player and board must have read cards,
+				// and ALL antenna types that have read cards must have timed out
+				if timeout == nil || !timeout.Expired(time.Now()) {
+					continue
 				}
 
-				// Only clear game if:
-				// 1. Both player and board have read cards (checked above)
-				// 2. ALL active antenna types have timed out
-				shouldClearGame := len(activeTypes) > 0 && len(stillActiveTypes) == 0
-
-				if shouldClearGame {
-					slog.InfoContext(ctx, "game timeout detected, clearing game",
-						"timeout_seconds", timeoutSeconds,
-						"timed_out_types", timedOutTypes)
-
-					// Clear the game
-					if err := store.ClearGame(context.Background(), conn); err != nil {
-						slog.WarnContext(ctx, "failed to clear game on timeout", "error", err)
-						continue
-					}
-
-					// Reset all antenna type timestamps
-					resetAntennaTypeTimestamps()
-
-					// Notify clients
-					notifyClients()
+				slog.InfoContext(ctx, "game timeout detected, clearing game",
+					"game_id", timeout.GameID,
+					"expires_at", timeout.ExpiresAt)
+
+				// Clear the game
+				ingestMu.Lock()
+				err = runTableTx(context.Background(), conn, func(t *tableTx) error {
+					return endGame(context.Background(), t, GameEndPolicyTimeout)
+				})
+				ingestMu.Unlock()
+				if err != nil {
+					slog.WarnContext(ctx, "failed to clear game on timeout", "error", err)
+					continue
 				}
 			}
 		}
@@ -209,14 +164,22 @@ func Run(ctx context.Context) error {
 		return fmt.Errorf("initializeDatabase(): %w", err)
 	}
 
-	// Restore antenna type timestamps from database
-	if err := restoreAntennaTypeTimestamps(ctx, conn); err != nil {
-		slog.WarnContext(ctx, "failed to restore antenna type timestamps", "error", err)
-		// Continue server startup even if restoration fails
-	}
+	// Set seats from the table layout
+	applySeatLayout(ctx, conn, config.Conf)
 
 	// Start game timeout checker
 	startGameTimeoutChecker(ctx, conn)
+	startAdminCardsNotifier(ctx, conn)
+	startTournamentClock(ctx, conn)
+	startBroadcaster(ctx, conn)
+
+	mqttConn, err := startMQTT(ctx, conn, config.Conf)
+	if err != nil {
+		return fmt.Errorf("startMQTT(): %w", err)
+	}
+	if mqttConn != nil {
+		defer mqttConn.Close()
+	}
 
 	e := echo.New()
 	e.Use(middleware.Logger())
@@ -241,6 +204,12 @@ func Run(ctx context.Context) error {
 	e.POST("/card", func(c echo.Context) error {
 		return HandleCards(c, conn)
 	})
+	e.POST("/cards", func(c echo.Context) error {
+		return HandleBatchCards(c, conn)
+	})
+	e.GET("/device/firmware/:id", func(c echo.Context) error {
+		return HandleGetDeviceFirmware(c, conn)
+	})
 
 	// For admin
 	e.GET("/admin/antenna", func(c echo.Context) error {
@@ -249,9 +218,64 @@ func Run(ctx context.Context) error {
 	e.POST("/admin/antenna/:id", func(c echo.Context) error {
 		return HandlePostAdminAntenna(c, conn)
 	})
+	e.POST("/admin/antenna/:id/seat", func(c echo.Context) error {
+		return HandlePostAdminAntennaSeat(c, conn)
+	})
 	e.DELETE("/admin/antenna/:id", func(c echo.Context) error {
 		return HandleDeleteAdminAntenna(c, conn)
 	})
+	e.GET("/admin/device", func(c echo.Context) error {
+		return HandleGetAdminDevice(c, conn)
+	})
+	e.GET("/admin/firmware", func(c echo.Context) error {
+		return HandleGetAdminFirmware(c, conn)
+	})
+	e.POST("/admin/firmware", func(c echo.Context) error {
+		return HandlePostAdminFirmware(c, conn)
+	})
+	e.POST("/admin/firmware/:id/desired", func(c echo.Context) error {
+		return HandlePostAdminFirmwareDesired(c, conn)
+	})
+	e.DELETE("/admin/firmware/:id", func(c echo.Context) error {
+		return HandleDeleteAdminFirmware(c, conn)
+	})
+	e.GET("/admin/presence", func(c echo.Context) error {
+		return HandleGetAdminPresence(c, conn)
+	})
+	e.GET("/admin/reader/stats", HandleGetAdminReaderStats)
+	e.GET("/admin/broadcast", HandleGetAdminBroadcast)
+	e.GET("/admin/ws/stats", HandleGetAdminWSStats)
+	e.POST("/admin/broadcast", HandlePostAdminBroadcast, requireProducerToken)
+	e.GET("/admin/session", func(c echo.Context) error {
+		return HandleGetAdminSessions(c, conn)
+	})
+	e.POST("/admin/session", func(c echo.Context) error {
+		return HandlePostAdminSession(c, conn)
+	})
+	e.POST("/admin/session/:id/close", func(c echo.Context) error {
+		return HandlePostAdminSessionClose(c, conn)
+	})
+	e.GET("/admin/stats/allin", func(c echo.Context) error {
+		return HandleGetAdminAllInStats(c, conn)
+	})
+	e.GET("/admin/stats/player/:id", func(c echo.Context) error {
+		return HandleGetAdminPlayerStats(c, conn)
+	})
+	e.GET("/admin/stats/leaderboard", func(c echo.Context) error {
+		return HandleGetAdminLeaderboard(c, conn)
+	})
+	e.POST("/admin/antenna/:id/player", func(c echo.Context) error {
+		return HandlePostAdminAntennaPlayer(c, conn)
+	})
+	e.GET("/admin/profile", func(c echo.Context) error {
+		return HandleGetAdminProfiles(c, conn)
+	})
+	e.POST("/admin/profile", func(c echo.Context) error {
+		return HandlePostAdminProfile(c, conn)
+	})
+	e.POST("/admin/profile/:id", func(c echo.Context) error {
+		return HandlePostAdminProfileByID(c, conn)
+	})
 	e.GET("/admin/player", func(c echo.Context) error {
 		return HandleGetAdminPlayers(c, conn)
 	})
@@ -260,17 +284,84 @@ func Run(ctx context.Context) error {
 	})
 	e.GET("/admin/player/:id/hand", func(c echo.Context) error {
 		return HandleGetAdminPlayerHand(c, conn)
-	})
+	}, requireProducerTokenIfSet)
 	e.DELETE("/admin/player/:id/hand", func(c echo.Context) error {
 		return HandleDeleteAdminPlayerHand(c, conn)
 	})
 	e.DELETE("/admin/game", func(c echo.Context) error {
 		return HandleDeleteAdminGame(c, conn)
 	})
+	e.GET("/admin/game", func(c echo.Context) error {
+		return HandleGetAdminGame(c, conn)
+	})
+	e.POST("/admin/game", func(c echo.Context) error {
+		return HandlePostAdminGame(c, conn)
+	})
+	e.POST("/admin/game/pause", func(c echo.Context) error {
+		return HandlePostAdminGamePause(c, conn)
+	})
+	e.POST("/admin/game/resume", func(c echo.Context) error {
+		return HandlePostAdminGameResume(c, conn)
+	})
+	e.POST("/admin/game/finish", func(c echo.Context) error {
+		return HandlePostAdminGameFinish(c, conn)
+	})
+	e.POST("/admin/game/board", func(c echo.Context) error {
+		return HandlePostAdminGameBoard(c, conn)
+	})
+	e.GET("/admin/game/cards", func(c echo.Context) error {
+		return HandleGetAdminGameCards(c, conn)
+	}, requireProducerTokenIfSet)
+	e.GET("/admin/game/action", func(c echo.Context) error {
+		return HandleGetAdminGameAction(c, conn)
+	})
+	e.POST("/admin/game/action", func(c echo.Context) error {
+		return HandlePostAdminGameAction(c, conn)
+	})
+	e.DELETE("/admin/game/action", func(c echo.Context) error {
+		return HandleDeleteAdminGameAction(c, conn)
+	})
+	e.GET("/admin/table", func(c echo.Context) error {
+		return HandleGetAdminTable(c, conn)
+	})
+	e.POST("/admin/table/stack", func(c echo.Context) error {
+		return HandlePostAdminTableStack(c, conn)
+	})
+	e.GET("/admin/table/pots/unawarded", func(c echo.Context) error {
+		return HandleGetAdminUnawardedPots(c, conn)
+	})
+	e.POST("/admin/table/pots/unawarded/:id/award", func(c echo.Context) error {
+		return HandlePostAdminUnawardedPotAward(c, conn)
+	})
 
-	e.GET("/ws", func(c echo.Context) error {
-		return ws(c, conn)
+	e.GET("/admin/tournament", func(c echo.Context) error {
+		return HandleGetAdminTournament(c, conn)
+	})
+	e.POST("/admin/tournament", func(c echo.Context) error {
+		return HandlePostAdminTournament(c, conn)
+	})
+	e.POST("/admin/tournament/pause", func(c echo.Context) error {
+		return HandlePostAdminTournamentPause(c, conn)
 	})
+	e.POST("/admin/tournament/resume", func(c echo.Context) error {
+		return HandlePostAdminTournamentResume(c, conn)
+	})
+	e.POST("/admin/tournament/skip", func(c echo.Context) error {
+		return HandlePostAdminTournamentSkip(c, conn)
+	})
+	e.POST("/admin/tournament/level", func(c echo.Context) error {
+		return HandlePostAdminTournamentLevel(c, conn)
+	})
+	e.POST("/admin/tournament/finish", func(c echo.Context) error {
+		return HandlePostAdminTournamentFinish(c, conn)
+	})
+
+	e.GET("/ws", ws)
+	e.GET("/admin/ws", func(c echo.Context) error {
+		return adminWS(c, conn)
+	}, requireProducerTokenIfSet)
+	e.GET("/producer/ws", producerWS)
+	e.GET("/events", events)
 	go func() {
 		if err := e.Start(":8080"); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.WarnContext(ctx, "failed to start server", "error", err)
@@ -20,6 +20,7 @@ type Antenna struct {
 	DeviceID        string `json:"device_id"`
 	PairID          int    `json:"pair_id"`
 	AntennaTypeName string `json:"antenna_type_name"`
+	SeatNumber      *int32 `json:"seat_number"`
 }
 
 type GetAdminAntennaResponse struct {
@@ -48,6 +49,7 @@ func HandleGetAdminAntenna(c echo.Context, conn *sql.DB) error {
 			DeviceID:        deviceID,
 			PairID:          pairID,
 			AntennaTypeName: a.AntennaTypeName,
+			SeatNumber:      nullInt32ToPtr(a.SeatNumber),
 		})
 	}
 
@@ -85,29 +87,37 @@ func HandlePostAdminAntenna(c echo.Context, conn *sql.DB) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("antenna type name (input: %s) is unknown", req.AntennaTypeName)})
 	}
 
+	antenna, err := q.GetAntennaById(c.Request().Context(), int32(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "q.GetAntennaById", "error", err, slog.Int("id", id))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
 	storedAntennas, err := q.GetAntenna(c.Request().Context())
 	if err != nil {
 		logger.WarnContext(c.Request().Context(), "q.GetAntenna", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 	}
 
-	// board antenna and muck antenna is only one
-	if req.AntennaTypeName == store.AntennaTypeBoard.String() || req.AntennaTypeName == store.AntennaTypeMuck.String() {
-		for _, a := range storedAntennas {
-			if a.AntennaTypeName == req.AntennaTypeName {
-				logger.WarnContext(c.Request().Context(), "antenna type name is already exists", slog.String("antenna_type_name", req.AntennaTypeName))
-				return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("antenna type name %s is already exists", req.AntennaTypeName)})
-			}
+	// muck antenna is only one.
+	// board antennas can be more than one for double-board games, but one per device as all pair_ids of a board device are one board
+	for _, a := range storedAntennas {
+		if a.ID == antenna.ID || a.AntennaTypeName != req.AntennaTypeName {
+			continue
 		}
-	}
-
-	antenna, err := q.GetAntennaById(c.Request().Context(), int32(id))
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		switch store.GetAntennaType(req.AntennaTypeName) {
+		case store.AntennaTypeMuck:
+			logger.WarnContext(c.Request().Context(), "antenna type name is already exists", slog.String("antenna_type_name", req.AntennaTypeName))
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("antenna type name %s is already exists", req.AntennaTypeName)})
+		case store.AntennaTypeBoard:
+			if isSameDevice(a.Serial, antenna.Serial) {
+				logger.WarnContext(c.Request().Context(), "board antenna is already exists on the device", slog.String("serial", a.Serial))
+				return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("board antenna %s is already exists on the device", a.Serial)})
+			}
 		}
-		logger.WarnContext(c.Request().Context(), "q.GetAntennaById", "error", err, slog.Int("id", id))
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 	}
 
 	if _, err := q.GetAntennaTypeIdByAntennaTypeName(c.Request().Context(), req.AntennaTypeName); err != nil {
@@ -150,6 +160,7 @@ func HandlePostAdminAntenna(c echo.Context, conn *sql.DB) error {
 		DeviceID:        deviceID,
 		PairID:          pairID,
 		AntennaTypeName: respAntenna.AntennaTypeName,
+		SeatNumber:      nullInt32ToPtr(respAntenna.SeatNumber),
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -167,7 +178,8 @@ func cleansingObjectWithChangeAntennaType(ctx context.Context, q *query.Queries,
 
 	switch {
 	case oldType == store.AntennaTypePlayer:
-		// if oldType is player, we need to delete player, card, and hand
+		// if oldType is player, we need to delete card and hand, and the player leaves the antenna
+		// the player is kept with the history
 		antenna, err := q.GetAntennaById(ctx, antennaID)
 		if err != nil {
 			return fmt.Errorf("q.GetAntennaById(): %w", err)
@@ -177,15 +189,25 @@ func cleansingObjectWithChangeAntennaType(ctx context.Context, q *query.Queries,
 			return nil
 		}
 
-		if err := q.DeletePlayerWithHandWithCards(ctx, antenna.PlayerID.Int32); err != nil {
-			return fmt.Errorf("q.DeletePlayerWithHandWithCards(): %w", err)
+		if err := q.DeleteCardByAntennaID(ctx, antennaID); err != nil {
+			return fmt.Errorf("q.DeleteCardByAntennaID(): %w", err)
+		}
+		if err := q.DeleteHandByAntennaID(ctx, antennaID); err != nil {
+			return fmt.Errorf("q.DeleteHandByAntennaID(): %w", err)
+		}
+		if err := q.UnsetPlayerIDToAntennaByID(ctx, antennaID); err != nil {
+			return fmt.Errorf("q.UnsetPlayerIDToAntennaByID(): %w", err)
 		}
 	case oldType == store.AntennaTypeMuck:
 		// if oldType is muck, we need to delete muck
-	case oldType == store.AntennaTypeBoard:
-		// if oldType is board, we need to delete board
-		if err := q.DeleteBoardCards(ctx); err != nil {
-			return fmt.Errorf("q.DeleteBoardCards(): %w", err)
+	case oldType == store.AntennaTypeBoard || oldType == store.AntennaTypeBurn || oldType == store.AntennaTypeRabbit:
+		// if oldType is board, burn or rabbit, we need to delete cards on the antenna, other boards are kept
+		antenna, err := q.GetAntennaById(ctx, antennaID)
+		if err != nil {
+			return fmt.Errorf("q.GetAntennaById(): %w", err)
+		}
+		if err := q.DeleteCardBySerial(ctx, antenna.Serial); err != nil {
+			return fmt.Errorf("q.DeleteCardBySerial(): %w", err)
 		}
 	default:
 		return errors.New("unknown antenna type")
@@ -194,6 +216,13 @@ func cleansingObjectWithChangeAntennaType(ctx context.Context, q *query.Queries,
 	return nil
 }
 
+// isSameDevice returns true if both serials are antennas of the same device
+func isSameDevice(serialA, serialB string) bool {
+	deviceA, _, errA := store.FromSerial(serialA)
+	deviceB, _, errB := store.FromSerial(serialB)
+	return errA == nil && errB == nil && deviceA == deviceB
+}
+
 func HandleDeleteAdminAntenna(c echo.Context, conn *sql.DB) error {
 	logger := slog.With("method", "HandleDeleteAdminAntenna")
 	id, err := strconv.Atoi(c.Param("id"))
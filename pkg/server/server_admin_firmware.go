@@ -0,0 +1,290 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+type Firmware struct {
+	ID         int32     `json:"id"`
+	ClientType string    `json:"client_type"`
+	Version    string    `json:"version"`
+	SHA256     string    `json:"sha256"`
+	Size       int32     `json:"size"`
+	IsDesired  bool      `json:"is_desired"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type GetAdminFirmwareResponse struct {
+	Firmware []Firmware `json:"firmware"`
+}
+
+// HandleGetAdminFirmware returns uploaded firmware images without binary
+func HandleGetAdminFirmware(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminFirmware")
+	q := query.New(conn)
+
+	list, err := q.GetFirmwareList(c.Request().Context())
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "q.GetFirmwareList", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := GetAdminFirmwareResponse{Firmware: make([]Firmware, 0, len(list))}
+	for _, f := range list {
+		resp.Firmware = append(resp.Firmware, Firmware{
+			ID:         f.ID,
+			ClientType: f.ClientType,
+			Version:    f.Version,
+			SHA256:     f.Sha256,
+			Size:       f.Size,
+			IsDesired:  f.IsDesired,
+			CreatedAt:  f.CreatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// multipartMaxMemory is the size of a form kept in memory, the rest is stored in temporary files
+const multipartMaxMemory = 32 << 20
+
+// HandlePostAdminFirmware uploads a firmware image as multipart/form-data.
+// fields: file, client_type, version, desired (optional, "true" to roll out immediately)
+func HandlePostAdminFirmware(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminFirmware")
+	ctx := c.Request().Context()
+
+	// the limit covers the whole form, so the image is never read over it
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, config.Conf.FirmwareMaxBytes)
+	if err := c.Request().ParseMultipartForm(multipartMaxMemory); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: fmt.Sprintf("firmware is larger than %d bytes", maxErr.Limit)})
+		}
+		logger.WarnContext(ctx, "ParseMultipartForm", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid multipart form"})
+	}
+
+	clientType := c.FormValue("client_type")
+	version := c.FormValue("version")
+	if clientType == "" || version == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "client_type and version are required"})
+	}
+	desired := false
+	if v := c.FormValue("desired"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid desired: %s", v)})
+		}
+		desired = b
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		logger.WarnContext(ctx, "c.FormFile", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "file is required"})
+	}
+	f, err := fh.Open()
+	if err != nil {
+		logger.WarnContext(ctx, "fh.Open", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		logger.WarnContext(ctx, "io.ReadAll", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	if len(data) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "file is empty"})
+	}
+
+	id, err := store.AddFirmware(ctx, conn, clientType, version, data, desired)
+	if err != nil {
+		if errors.Is(err, store.ErrFirmwareExists) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(ctx, "store.AddFirmware", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	logger.InfoContext(ctx, "firmware uploaded", "id", id, "client_type", clientType, "version", version, "desired", desired)
+
+	return respondFirmware(c, conn, id, http.StatusCreated)
+}
+
+// HandlePostAdminFirmwareDesired marks the firmware as desired version of its client type
+func HandlePostAdminFirmwareDesired(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminFirmwareDesired")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := store.SetDesiredFirmware(c.Request().Context(), conn, int32(id)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "firmware not found"})
+		}
+		logger.WarnContext(c.Request().Context(), "store.SetDesiredFirmware", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return respondFirmware(c, conn, int32(id), http.StatusOK)
+}
+
+// HandleDeleteAdminFirmware deletes the firmware image
+func HandleDeleteAdminFirmware(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleDeleteAdminFirmware")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	q := query.New(conn)
+	if _, err := q.GetFirmwareByID(c.Request().Context(), int32(id)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "firmware not found"})
+		}
+		logger.WarnContext(c.Request().Context(), "q.GetFirmwareByID", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	if err := q.DeleteFirmwareByID(c.Request().Context(), int32(id)); err != nil {
+		logger.WarnContext(c.Request().Context(), "q.DeleteFirmwareByID", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func respondFirmware(c echo.Context, conn *sql.DB, id int32, status int) error {
+	f, err := query.New(conn).GetFirmwareByID(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(status, Firmware{
+		ID:         f.ID,
+		ClientType: f.ClientType,
+		Version:    f.Version,
+		SHA256:     f.Sha256,
+		Size:       f.Size,
+		IsDesired:  f.IsDesired,
+		CreatedAt:  f.CreatedAt,
+	})
+}
+
+type DeviceStatus struct {
+	DeviceID          string     `json:"device_id"`
+	ClientType        string     `json:"client_type"`
+	FirmwareVersion   string     `json:"firmware_version"`
+	DesiredVersion    string     `json:"desired_version,omitempty"`
+	OfferedVersion    string     `json:"offered_version,omitempty"`
+	DownloadedVersion string     `json:"downloaded_version,omitempty"`
+	DownloadedAt      *time.Time `json:"downloaded_at,omitempty"`
+	LastBootAt        time.Time  `json:"last_boot_at"`
+	RolloutStatus     string     `json:"rollout_status"`
+}
+
+type GetAdminDeviceResponse struct {
+	Device []DeviceStatus `json:"device"`
+}
+
+// HandleGetAdminDevice returns devices with firmware rollout status
+func HandleGetAdminDevice(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminDevice")
+	q := query.New(conn)
+
+	devices, err := q.GetDevices(c.Request().Context())
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "q.GetDevices", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	firmware, err := q.GetFirmwareList(c.Request().Context())
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "q.GetFirmwareList", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	desiredVersions := make(map[string]string) // key: client_type
+	for _, f := range firmware {
+		if f.IsDesired {
+			desiredVersions[f.ClientType] = f.Version
+		}
+	}
+
+	resp := GetAdminDeviceResponse{Device: make([]DeviceStatus, 0, len(devices))}
+	for _, d := range devices {
+		status := DeviceStatus{
+			DeviceID:          d.DeviceID,
+			ClientType:        d.ClientType,
+			FirmwareVersion:   d.FirmwareVersion,
+			DesiredVersion:    desiredVersions[d.ClientType],
+			OfferedVersion:    d.OfferedVersion.String,
+			DownloadedVersion: d.DownloadedVersion.String,
+			LastBootAt:        d.LastBootAt,
+			RolloutStatus:     store.FirmwareRolloutStatus(d, desiredVersions[d.ClientType]),
+		}
+		if d.DownloadedAt.Valid {
+			status.DownloadedAt = &d.DownloadedAt.Time
+		}
+		resp.Device = append(resp.Device, status)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// HandleGetDeviceFirmware serves the firmware binary to a device.
+// The device is identified by device_id query or x-ESP32-STA-MAC header (sent by ESP32 HTTPUpdate) to record rollout status.
+func HandleGetDeviceFirmware(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetDeviceFirmware")
+	ctx := c.Request().Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	q := query.New(conn)
+	f, err := q.GetFirmwareByID(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "firmware not found")
+		}
+		logger.WarnContext(ctx, "q.GetFirmwareByID", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	data, err := q.GetFirmwareDataByID(ctx, int32(id))
+	if err != nil {
+		logger.WarnContext(ctx, "q.GetFirmwareDataByID", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	deviceID := c.QueryParam("device_id")
+	if deviceID == "" {
+		deviceID = c.Request().Header.Get("x-ESP32-STA-MAC")
+	}
+	if deviceID != "" {
+		if err := q.SetDeviceDownloadedVersion(ctx, query.SetDeviceDownloadedVersionParams{
+			DownloadedVersion: sql.NullString{String: f.Version, Valid: true},
+			DeviceID:          deviceID,
+		}); err != nil {
+			logger.WarnContext(ctx, "q.SetDeviceDownloadedVersion", "device_id", deviceID, "error", err)
+		}
+	}
+	logger.InfoContext(ctx, "firmware downloaded", "id", f.ID, "version", f.Version, "device_id", deviceID)
+
+	c.Response().Header().Set("X-Firmware-Version", f.Version)
+	c.Response().Header().Set("X-Firmware-SHA256", f.Sha256)
+	// ESP32 HTTPUpdate verifies the image with x-MD5, so sha256 is provided for clients verifying by themselves
+	return c.Blob(http.StatusOK, "application/octet-stream", data)
+}
@@ -1,10 +1,18 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
 	"github.com/whywaita/rfid-poker/pkg/store"
 
 	"github.com/labstack/echo/v4"
@@ -12,15 +20,338 @@ import (
 
 func HandleDeleteAdminGame(c echo.Context, conn *sql.DB) error {
 	logger := slog.With("method", "HandleDeleteAdminGame")
-	if err := store.ClearGame(c.Request().Context(), conn); err != nil {
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	if err := runTableTx(c.Request().Context(), conn, func(t *tableTx) error {
+		return deleteGame(c.Request().Context(), t, GameEndPolicyManual)
+	}); err != nil {
 		logger.WarnContext(c.Request().Context(), "failed to delete game", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete game")
 	}
 
-	// Reset all antenna type timestamps
-	resetAntennaTypeTimestamps()
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+type PostAdminGameBoardRequest struct {
+	// SharedCards is the number of cards of the first board shared with the new board, e.g. 3 to run it twice after the flop
+	SharedCards int `json:"shared_cards"`
+}
+
+type PostAdminGameBoardResponse struct {
+	BoardNumber int32 `json:"board_number"`
+}
+
+type PostAdminGameRequest struct {
+	// ButtonPosition is the seat of the dealer button, optional
+	ButtonPosition *int32 `json:"button_position"`
+	Label          string `json:"label"`
+}
+
+type PostAdminGameResponse struct {
+	ID string `json:"id"`
+}
+
+// HandlePostAdminGame starts a new game before the first card is read
+func HandlePostAdminGame(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminGame")
+
+	var req PostAdminGameRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	var buttonPosition sql.NullInt32
+	if req.ButtonPosition != nil {
+		buttonPosition = sql.NullInt32{Int32: *req.ButtonPosition, Valid: true}
+	}
+	label := sql.NullString{String: req.Label, Valid: req.Label != ""}
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	gameID, err := store.StartNewGame(c.Request().Context(), conn, buttonPosition, label)
+	if err != nil {
+		if errors.Is(err, store.ErrGameInProgress) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "store.StartNewGame", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
 
 	notifyClients()
 
+	return c.JSON(http.StatusCreated, PostAdminGameResponse{ID: gameID})
+}
+
+// HandlePostAdminGamePause stops auto-clearing of the current game
+func HandlePostAdminGamePause(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminGamePause")
+
+	if err := store.PauseGame(c.Request().Context(), query.New(conn)); err != nil {
+		if errors.Is(err, store.ErrNoActiveGame) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "store.PauseGame", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	notifyClients()
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// HandlePostAdminGameResume restarts auto-clearing of the current game
+func HandlePostAdminGameResume(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminGameResume")
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	if err := store.ResumeGame(c.Request().Context(), conn); err != nil {
+		if errors.Is(err, store.ErrNoActiveGame) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "store.ResumeGame", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	// the game end skipped while paused is scheduled again
+	if err := runTableTx(c.Request().Context(), conn, func(t *tableTx) error {
+		return advanceGameState(c.Request().Context(), t, config.Conf)
+	}); err != nil {
+		logger.WarnContext(c.Request().Context(), "advanceGameState", "error", err)
+	}
+	notifyClients()
+
 	return c.JSON(http.StatusNoContent, nil)
 }
+
+// HandlePostAdminGameFinish finishes the current game without deleting it
+func HandlePostAdminGameFinish(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminGameFinish")
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	if err := runTableTx(c.Request().Context(), conn, func(t *tableTx) error {
+		return endGame(c.Request().Context(), t, GameEndPolicyManual)
+	}); err != nil {
+		logger.WarnContext(c.Request().Context(), "endGame", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+type GetAdminGameResponse struct {
+	ID             string     `json:"id"`
+	StartedAt      time.Time  `json:"started_at"`
+	State          string     `json:"state"`
+	ButtonPosition *int32     `json:"button_position"`
+	Label          string     `json:"label"`
+	PausedAt       *time.Time `json:"paused_at"`
+	// SessionID is the session the game is attached to
+	SessionID *int32 `json:"session_id"`
+	// Timeout is set when the game is cleared by the timeout
+	Timeout *AdminGameTimeout `json:"timeout,omitempty"`
+}
+
+type AdminGameTimeout struct {
+	// ExpiresAt is when the game is cleared, not set until both player and board antennas have read cards
+	ExpiresAt        *time.Time          `json:"expires_at"`
+	RemainingSeconds int                 `json:"remaining_seconds"`
+	Activities       []AdminGameActivity `json:"activities"`
+}
+
+type AdminGameActivity struct {
+	AntennaTypeName string    `json:"antenna_type_name"`
+	LastReadAt      time.Time `json:"last_read_at"`
+	TimeoutSeconds  int       `json:"timeout_seconds"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// HandleGetAdminGame returns the current game with the time remaining until it is cleared
+func HandleGetAdminGame(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminGame")
+	ctx := c.Request().Context()
+	q := query.New(conn)
+
+	game, err := q.GetCurrentGame(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "no active game"})
+		}
+		logger.WarnContext(ctx, "q.GetCurrentGame", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := GetAdminGameResponse{
+		ID:        game.ID,
+		StartedAt: game.StartedAt,
+		State:     game.State,
+		Label:     game.Label.String,
+	}
+	resp.ButtonPosition = nullInt32ToPtr(game.ButtonPosition)
+	resp.SessionID = nullInt32ToPtr(game.SessionID)
+	if game.PausedAt.Valid {
+		resp.PausedAt = &game.PausedAt.Time
+	}
+
+	if isGameTimeoutEnabled(config.Conf) {
+		timeout, err := getGameTimeout(ctx, q)
+		if err != nil {
+			logger.WarnContext(ctx, "getGameTimeout", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+		if timeout != nil {
+			resp.Timeout = newAdminGameTimeout(*timeout, time.Now())
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func newAdminGameTimeout(timeout store.GameTimeout, now time.Time) *AdminGameTimeout {
+	t := &AdminGameTimeout{
+		RemainingSeconds: int(timeout.Remaining(now).Seconds()),
+		Activities:       make([]AdminGameActivity, 0, len(timeout.Activities)),
+	}
+	if timeout.Started {
+		t.ExpiresAt = &timeout.ExpiresAt
+	}
+	for _, a := range timeout.Activities {
+		t.Activities = append(t.Activities, AdminGameActivity{
+			AntennaTypeName: a.AntennaTypeName,
+			LastReadAt:      a.LastReadAt,
+			TimeoutSeconds:  a.TimeoutSeconds,
+			ExpiresAt:       a.ExpiresAt,
+		})
+	}
+	return t
+}
+
+// HandlePostAdminGameBoard adds a board to the current game for run it twice with one board antenna
+func HandlePostAdminGameBoard(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminGameBoard")
+
+	var req PostAdminGameBoardRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	number, err := store.AddNextBoard(c.Request().Context(), conn, req.SharedCards)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidSharedCards) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "store.AddNextBoard", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	notifyClients()
+	go func() {
+		if err := store.CalcEquity(context.Background(), query.New(conn)); err != nil {
+			logger.WarnContext(context.Background(), "calcEquity", "error", err)
+		}
+		notifyClients()
+	}()
+
+	return c.JSON(http.StatusOK, PostAdminGameBoardResponse{BoardNumber: number})
+}
+
+// AdminMessageTypeGameCards is sent to admin WebSocket clients when the card location map is changed
+const AdminMessageTypeGameCards = "game_cards"
+
+type GameCard struct {
+	Suit       string     `json:"suit"`
+	Rank       string     `json:"rank"`
+	Location   string     `json:"location"` // player, board, mucked, unseen
+	Serial     string     `json:"serial,omitempty"`
+	PlayerID   int32      `json:"player_id,omitempty"`
+	PlayerName string     `json:"player_name,omitempty"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+}
+
+type GetAdminGameCardsResponse struct {
+	Cards []GameCard `json:"cards"`
+	// SeenCount is the number of cards read in the current game, the deck is complete if it is 52
+	SeenCount int `json:"seen_count"`
+}
+
+// HandleGetAdminGameCards returns where every card of the deck is in the current game
+func HandleGetAdminGameCards(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminGameCards")
+
+	resp, err := getAdminGameCards(c.Request().Context(), query.New(conn))
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "getAdminGameCards", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func getAdminGameCards(ctx context.Context, q *query.Queries) (*GetAdminGameCardsResponse, error) {
+	deck, err := store.GetDeckLocations(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("store.GetDeckLocations(): %w", err)
+	}
+
+	resp := &GetAdminGameCardsResponse{Cards: make([]GameCard, 0, len(deck))}
+	for _, d := range deck {
+		resp.Cards = append(resp.Cards, GameCard{
+			Suit:       d.Card.Suit.String(),
+			Rank:       d.Card.Rank.String(),
+			Location:   d.Location,
+			Serial:     d.Serial,
+			PlayerID:   d.PlayerID,
+			PlayerName: d.PlayerName,
+			ReadAt:     d.ReadAt,
+		})
+		if d.Location != store.CardLocationUnseen {
+			resp.SeenCount++
+		}
+	}
+
+	return resp, nil
+}
+
+// adminCardsCh is signaled by notifyClients to refresh the card location map of admin clients
+var adminCardsCh = make(chan struct{}, 1)
+
+// startAdminCardsNotifier sends the card location map to admin WebSocket clients when it is changed
+func startAdminCardsNotifier(ctx context.Context, conn *sql.DB) {
+	q := query.New(conn)
+
+	go func() {
+		var last []byte
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-adminCardsCh:
+				if !adminWSManager.hasClients() {
+					continue
+				}
+				resp, err := getAdminGameCards(ctx, q)
+				if err != nil {
+					slog.WarnContext(ctx, "failed to get card location map", "error", err)
+					continue
+				}
+				b, err := json.Marshal(resp)
+				if err != nil {
+					slog.WarnContext(ctx, "json.Marshal", "error", err)
+					continue
+				}
+				if bytes.Equal(b, last) {
+					continue
+				}
+				last = b
+				notifyAdmin(AdminMessageTypeGameCards, resp)
+			}
+		}
+	}()
+}
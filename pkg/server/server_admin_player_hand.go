@@ -1,7 +1,6 @@
 package server
 
 import (
-	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -104,28 +103,25 @@ func HandleDeleteAdminPlayerHand(c echo.Context, conn *sql.DB) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 	}
 
-	if err := store.MuckPlayer(c.Request().Context(), conn, []poker.Card{
-		{
-			Suit: poker.UnmarshalSuitString(hand.CardASuit),
-			Rank: poker.UnmarshalRankString(hand.CardARank),
-		},
-		{
-			Suit: poker.UnmarshalSuitString(hand.CardBSuit),
-			Rank: poker.UnmarshalRankString(hand.CardBRank),
-		},
+	if err := runTableTx(c.Request().Context(), conn, func(t *tableTx) error {
+		if err := store.MuckPlayer(c.Request().Context(), t.q, []poker.Card{
+			{
+				Suit: poker.UnmarshalSuitString(hand.CardASuit),
+				Rank: poker.UnmarshalRankString(hand.CardARank),
+			},
+			{
+				Suit: poker.UnmarshalSuitString(hand.CardBSuit),
+				Rank: poker.UnmarshalRankString(hand.CardBRank),
+			},
+		}); err != nil {
+			return fmt.Errorf("store.MuckPlayer(): %w", err)
+		}
+		t.calcEquity()
+		return nil
 	}); err != nil {
 		logger.WarnContext(c.Request().Context(), "store.MuckPlayer", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 	}
 
-	notifyClients()
-
-	go func() {
-		if err := store.CalcEquity(context.Background(), query.New(conn)); err != nil {
-			logger.WarnContext(c.Request().Context(), "store.CalcEquity", "error", err)
-		}
-		notifyClients()
-	}()
-
 	return c.JSON(http.StatusNoContent, nil)
 }
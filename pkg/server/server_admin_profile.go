@@ -0,0 +1,308 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"entgo.io/ent/dialect/sql/sqlgraph"
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+// Profile is a player independent of antennas
+type Profile struct {
+	ID            int32  `json:"id"`
+	Name          string `json:"name"`
+	DisplayName   string `json:"display_name"`
+	AvatarURL     string `json:"avatar_url"`
+	Country       string `json:"country"`
+	MembershipUID string `json:"membership_uid"`
+
+	// set while the player is seated
+	DeviceID   string `json:"device_id,omitempty"`
+	PairID     *int   `json:"pair_id,omitempty"`
+	SeatNumber *int32 `json:"seat_number,omitempty"`
+}
+
+type GetAdminProfilesResponse struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+func HandleGetAdminProfiles(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminProfiles")
+
+	rows, err := query.New(conn).GetPlayerProfiles(c.Request().Context())
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "q.GetPlayerProfiles", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := GetAdminProfilesResponse{Profiles: make([]Profile, 0, len(rows))}
+	for _, r := range rows {
+		profile := Profile{
+			ID:            r.ID,
+			Name:          r.Name,
+			DisplayName:   r.DisplayName.String,
+			AvatarURL:     r.AvatarUrl.String,
+			Country:       r.Country.String,
+			MembershipUID: r.MembershipUid.String,
+			SeatNumber:    nullInt32ToPtr(r.SeatNumber),
+		}
+		if r.Serial.Valid {
+			deviceID, pairID, err := store.FromSerial(r.Serial.String)
+			if err != nil {
+				logger.WarnContext(c.Request().Context(), "store.FromSerial", "error", err)
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			}
+			profile.DeviceID = deviceID
+			profile.PairID = &pairID
+		}
+		resp.Profiles = append(resp.Profiles, profile)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+type PostAdminProfileRequest struct {
+	ID            string `param:"id" json:"-"`
+	Name          string `json:"name"`
+	DisplayName   string `json:"display_name"`
+	AvatarURL     string `json:"avatar_url"`
+	Country       string `json:"country"` // ISO 3166-1 alpha-2
+	MembershipUID string `json:"membership_uid"`
+}
+
+func (r PostAdminProfileRequest) validate(cc config.Config) error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(r.Country) != 0 && len(r.Country) != 2 {
+		return errors.New("country must be a two-letter code")
+	}
+	if _, ok := cc.CardIDs[normalizeUID(r.MembershipUID)]; ok {
+		return errors.New("membership uid is a playing card")
+	}
+	return nil
+}
+
+func normalizeUID(uid string) string {
+	return strings.ReplaceAll(uid, " ", "")
+}
+
+func toNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// HandlePostAdminProfile creates a player profile
+func HandlePostAdminProfile(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminProfile")
+
+	var req PostAdminProfileRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if err := req.validate(config.Conf); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	result, err := query.New(conn).AddPlayerProfile(c.Request().Context(), query.AddPlayerProfileParams{
+		Name:          req.Name,
+		DisplayName:   toNullString(req.DisplayName),
+		AvatarUrl:     toNullString(req.AvatarURL),
+		Country:       toNullString(strings.ToUpper(req.Country)),
+		MembershipUid: toNullString(normalizeUID(req.MembershipUID)),
+	})
+	if err != nil {
+		if sqlgraph.IsUniqueConstraintError(err) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: "membership uid is already registered"})
+		}
+		logger.WarnContext(c.Request().Context(), "q.AddPlayerProfile", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "result.LastInsertId", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, Profile{
+		ID:            int32(id),
+		Name:          req.Name,
+		DisplayName:   req.DisplayName,
+		AvatarURL:     req.AvatarURL,
+		Country:       strings.ToUpper(req.Country),
+		MembershipUID: normalizeUID(req.MembershipUID),
+	})
+}
+
+// HandlePostAdminProfileByID updates a player profile
+func HandlePostAdminProfileByID(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminProfileByID")
+	q := query.New(conn)
+
+	var req PostAdminProfileRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	id, err := strconv.Atoi(req.ID)
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "strconv.Atoi", "error", err, slog.String("id", req.ID))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if err := req.validate(config.Conf); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if _, err := q.GetPlayer(c.Request().Context(), int32(id)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "player not found"})
+		}
+		logger.WarnContext(c.Request().Context(), "q.GetPlayer", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := q.UpdatePlayerProfile(c.Request().Context(), query.UpdatePlayerProfileParams{
+		Name:          req.Name,
+		DisplayName:   toNullString(req.DisplayName),
+		AvatarUrl:     toNullString(req.AvatarURL),
+		Country:       toNullString(strings.ToUpper(req.Country)),
+		MembershipUid: toNullString(normalizeUID(req.MembershipUID)),
+		ID:            int32(id),
+	}); err != nil {
+		if sqlgraph.IsUniqueConstraintError(err) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: "membership uid is already registered"})
+		}
+		logger.WarnContext(c.Request().Context(), "q.UpdatePlayerProfile", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	notifyClients()
+
+	return c.JSON(http.StatusOK, Profile{
+		ID:            int32(id),
+		Name:          req.Name,
+		DisplayName:   req.DisplayName,
+		AvatarURL:     req.AvatarURL,
+		Country:       strings.ToUpper(req.Country),
+		MembershipUID: normalizeUID(req.MembershipUID),
+	})
+}
+
+type PostAdminAntennaPlayerRequest struct {
+	ID string `param:"id" json:"-"`
+	// PlayerID is the player to seat, null to leave the seat empty
+	PlayerID *int32 `json:"player_id"`
+}
+
+// HandlePostAdminAntennaPlayer seats a player at the antenna
+func HandlePostAdminAntennaPlayer(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminAntennaPlayer")
+	ctx := c.Request().Context()
+	q := query.New(conn)
+
+	var req PostAdminAntennaPlayerRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(ctx, "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	id, err := strconv.Atoi(req.ID)
+	if err != nil {
+		logger.WarnContext(ctx, "strconv.Atoi", "error", err, slog.String("id", req.ID))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	antenna, err := q.GetAntennaById(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(ctx, "q.GetAntennaById", "error", err, slog.Int("id", id))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	if !isSeatAntennaType(antenna.AntennaTypeName) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("antenna type %s is not a seat", antenna.AntennaTypeName)})
+	}
+
+	if req.PlayerID == nil {
+		cards, err := q.GetCardBySerial(ctx, antenna.Serial)
+		if err != nil {
+			logger.WarnContext(ctx, "q.GetCardBySerial", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+		if len(cards) > 0 {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: store.ErrSeatInPlay.Error()})
+		}
+		if err := q.UnsetPlayerIDToAntennaByID(ctx, antenna.ID); err != nil {
+			logger.WarnContext(ctx, "q.UnsetPlayerIDToAntennaByID", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+	} else if err := store.SeatPlayer(ctx, conn, *req.PlayerID, antenna.Serial); err != nil {
+		switch {
+		case errors.Is(err, store.ErrPlayerNotFound):
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, store.ErrSeatInPlay):
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(ctx, "store.SeatPlayer", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	notifyClients()
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// isSeatAntennaType returns true if a player can sit at the antenna, an unknown antenna becomes a player antenna
+func isSeatAntennaType(antennaTypeName string) bool {
+	switch store.GetAntennaType(antennaTypeName) {
+	case store.AntennaTypePlayer, store.AntennaTypeUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkIn seats the player of a membership card read by a player antenna
+func checkIn(ctx context.Context, t *tableTx, uid string, serial string) (ReadOutcome, error) {
+	logger := slog.With("method", "checkIn", "uid", uid, "serial", serial)
+
+	antenna, err := t.q.GetAntennaBySerial(ctx, serial)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ReadOutcomeError, fmt.Errorf("query.GetAntennaBySerial(): %w", err)
+	}
+	if err != nil || !isSeatAntennaType(antenna.AntennaTypeName) {
+		// e.g. board antennas
+		logger.WarnContext(ctx, "unknown uid")
+		return ReadOutcomeUnknownUID, nil
+	}
+
+	player, err := store.CheckIn(ctx, t.q, uid, serial)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotMember):
+			logger.WarnContext(ctx, "unknown uid")
+			return ReadOutcomeUnknownUID, nil
+		case errors.Is(err, store.ErrSeatInPlay):
+			return ReadOutcomeSeatInPlay, nil
+		}
+		return ReadOutcomeError, fmt.Errorf("store.CheckIn(): %w", err)
+	}
+
+	logger.InfoContext(ctx, "player checked in", "player_id", player.ID, "player_name", player.Name)
+	t.notify()
+	return ReadOutcomeCheckedIn, nil
+}
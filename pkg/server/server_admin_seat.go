@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"entgo.io/ent/dialect/sql/sqlgraph"
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+type PostAdminAntennaSeatRequest struct {
+	ID string `param:"id" json:"id"`
+	// SeatNumber is the seat of the antenna, null to unset
+	SeatNumber *int32 `json:"seat_number"`
+}
+
+// HandlePostAdminAntennaSeat sets the seat number of an antenna
+func HandlePostAdminAntennaSeat(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminAntennaSeat")
+	q := query.New(conn)
+
+	var req PostAdminAntennaSeatRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	id, err := strconv.Atoi(req.ID)
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "strconv.Atoi", "error", err, slog.String("id", req.ID))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	var seatNumber sql.NullInt32
+	if req.SeatNumber != nil {
+		if !isValidSeatNumber(config.Conf, *req.SeatNumber) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("seat number must be from 1 to %d", config.Conf.TableSeats)})
+		}
+		seatNumber = sql.NullInt32{Int32: *req.SeatNumber, Valid: true}
+	}
+
+	antenna, err := q.GetAntennaById(c.Request().Context(), int32(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "q.GetAntennaById", "error", err, slog.Int("id", id))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := q.SetSeatNumberToAntennaByID(c.Request().Context(), query.SetSeatNumberToAntennaByIDParams{
+		SeatNumber: seatNumber,
+		ID:         antenna.ID,
+	}); err != nil {
+		if sqlgraph.IsUniqueConstraintError(err) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("seat %d is already assigned to another antenna", seatNumber.Int32)})
+		}
+		logger.WarnContext(c.Request().Context(), "q.SetSeatNumberToAntennaByID", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	notifyClients()
+
+	deviceID, pairID, err := store.FromSerial(antenna.Serial)
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "store.FromSerial", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, Antenna{
+		ID:              antenna.ID,
+		DeviceID:        deviceID,
+		PairID:          pairID,
+		AntennaTypeName: antenna.AntennaTypeName,
+		SeatNumber:      nullInt32ToPtr(seatNumber),
+	})
+}
+
+func isValidSeatNumber(cc config.Config, seatNumber int32) bool {
+	return seatNumber >= 1 && (cc.TableSeats <= 0 || int(seatNumber) <= cc.TableSeats)
+}
+
+// applySeatLayout sets seat numbers of antennas from the table layout in the config file
+func applySeatLayout(ctx context.Context, conn *sql.DB, cc config.Config) {
+	for serial := range cc.SeatNumberBySerial {
+		applySeatNumber(ctx, query.New(conn), cc, serial)
+	}
+}
+
+// applySeatNumber sets the seat number of the antenna from the table layout, antennas not in the layout are not changed
+func applySeatNumber(ctx context.Context, q *query.Queries, cc config.Config, serial string) {
+	seatNumber, ok := cc.SeatNumberBySerial[serial]
+	if !ok {
+		return
+	}
+	if !isValidSeatNumber(cc, int32(seatNumber)) {
+		slog.WarnContext(ctx, "invalid seat number in table layout", "serial", serial, "seat_number", seatNumber)
+		return
+	}
+
+	if err := q.SetSeatNumberToAntennaBySerial(ctx, query.SetSeatNumberToAntennaBySerialParams{
+		SeatNumber: sql.NullInt32{Int32: int32(seatNumber), Valid: true},
+		Serial:     serial,
+	}); err != nil {
+		slog.WarnContext(ctx, "failed to set seat number", "serial", serial, "seat_number", seatNumber, "error", err)
+	}
+}
+
+func nullInt32ToPtr(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+type AdminSession struct {
+	ID        int32      `json:"id"`
+	Name      string     `json:"name"`
+	Venue     string     `json:"venue"`
+	Tables    []string   `json:"tables"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	GameCount int64      `json:"game_count"`
+}
+
+type GetAdminSessionsResponse struct {
+	Sessions []AdminSession `json:"sessions"`
+}
+
+// HandleGetAdminSessions returns sessions in order of newest first
+func HandleGetAdminSessions(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminSessions")
+
+	sessions, err := query.New(conn).GetSessions(c.Request().Context())
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "q.GetSessions", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := GetAdminSessionsResponse{Sessions: make([]AdminSession, 0, len(sessions))}
+	for _, s := range sessions {
+		tables, err := store.ParseSessionTables(s.Tables)
+		if err != nil {
+			logger.WarnContext(c.Request().Context(), "store.ParseSessionTables", "error", err, slog.Int("session_id", int(s.ID)))
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+		session := AdminSession{
+			ID:        s.ID,
+			Name:      s.Name,
+			Venue:     s.Venue.String,
+			Tables:    tables,
+			StartedAt: s.StartedAt,
+			GameCount: s.GameCount,
+		}
+		if s.EndedAt.Valid {
+			session.EndedAt = &s.EndedAt.Time
+		}
+		resp.Sessions = append(resp.Sessions, session)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+type PostAdminSessionRequest struct {
+	Name   string   `json:"name"`
+	Venue  string   `json:"venue"`
+	Tables []string `json:"tables"`
+}
+
+type PostAdminSessionResponse struct {
+	ID int32 `json:"id"`
+}
+
+// HandlePostAdminSession opens a new session
+func HandlePostAdminSession(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminSession")
+
+	var req PostAdminSessionRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "name is required"})
+	}
+
+	id, err := store.OpenSession(c.Request().Context(), query.New(conn), req.Name, toNullString(req.Venue), req.Tables)
+	if err != nil {
+		if errors.Is(err, store.ErrSessionOpen) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "store.OpenSession", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, PostAdminSessionResponse{ID: id})
+}
+
+// HandlePostAdminSessionClose closes the session
+func HandlePostAdminSessionClose(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminSessionClose")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "strconv.Atoi", "error", err, slog.String("id", c.Param("id")))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := store.CloseSession(c.Request().Context(), query.New(conn), int32(id)); err != nil {
+		switch {
+		case errors.Is(err, store.ErrSessionNotFound):
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, store.ErrSessionClosed):
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "store.CloseSession", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
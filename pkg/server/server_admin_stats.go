@@ -0,0 +1,369 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+type AdminStatsFilter struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	SessionID *int32    `json:"session_id"`
+}
+
+func toAdminStatsFilter(filter store.StatsFilter) AdminStatsFilter {
+	return AdminStatsFilter{
+		From:      filter.From,
+		To:        filter.To,
+		SessionID: nullInt32ToPtr(filter.SessionID),
+	}
+}
+
+type GetAdminAllInStatsResponse struct {
+	AdminStatsFilter
+	Players []AdminAllInStats `json:"players"`
+}
+
+type AdminAllInStats struct {
+	PlayerID      int32   `json:"player_id"`
+	PlayerName    string  `json:"player_name"`
+	Count         int64   `json:"count"`
+	AverageEquity float64 `json:"average_equity"`
+	Expected      float64 `json:"expected"`
+	Actual        int64   `json:"actual"`
+	// Luck is actual minus expected chips, positive if the player ran good
+	Luck float64 `json:"luck"`
+}
+
+// HandleGetAdminAllInStats returns expected and actual chips of all-ins by players
+func HandleGetAdminAllInStats(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminAllInStats")
+
+	filter, err := parseStatsFilter(c, time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	stats, err := store.GetAllInStats(c.Request().Context(), query.New(conn), filter)
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "store.GetAllInStats", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := GetAdminAllInStatsResponse{
+		AdminStatsFilter: toAdminStatsFilter(filter),
+		Players:          make([]AdminAllInStats, 0, len(stats)),
+	}
+	for _, s := range stats {
+		resp.Players = append(resp.Players, AdminAllInStats{
+			PlayerID:      s.PlayerID,
+			PlayerName:    s.PlayerName,
+			Count:         s.Count,
+			AverageEquity: s.AverageEquity,
+			Expected:      s.Expected,
+			Actual:        s.Actual,
+			Luck:          s.Luck(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+type AdminPlayerStats struct {
+	PlayerID        int32            `json:"player_id"`
+	PlayerName      string           `json:"player_name"`
+	HandsDealt      int64            `json:"hands_dealt"`
+	HandsMucked     int64            `json:"hands_mucked"`
+	Showdowns       int64            `json:"showdowns"`
+	ShowdownsWon    int64            `json:"showdowns_won"`
+	ShowdownWinRate float64          `json:"showdown_win_rate"`
+	ShowdownEquity  float64          `json:"showdown_equity"`
+	BiggestPot      int64            `json:"biggest_pot"`
+	Won             int64            `json:"won"`
+	Contributed     int64            `json:"contributed"`
+	Net             int64            `json:"net"`
+	HandCategories  map[string]int64 `json:"hand_categories"`
+}
+
+func toAdminPlayerStats(s store.PlayerStat) AdminPlayerStats {
+	return AdminPlayerStats{
+		PlayerID:        s.PlayerID,
+		PlayerName:      s.PlayerName,
+		HandsDealt:      s.HandsDealt,
+		HandsMucked:     s.HandsMucked,
+		Showdowns:       s.Showdowns,
+		ShowdownsWon:    s.ShowdownsWon,
+		ShowdownWinRate: s.ShowdownWinRate(),
+		ShowdownEquity:  s.ShowdownEquity,
+		BiggestPot:      s.BiggestPot,
+		Won:             s.Won,
+		Contributed:     s.Contributed,
+		Net:             s.Net(),
+		HandCategories:  s.HandCategories,
+	}
+}
+
+type GetAdminPlayerStatsResponse struct {
+	AdminStatsFilter
+	AdminPlayerStats
+	BiggestPots []AdminBiggestPot  `json:"biggest_pots"`
+	History     []AdminHandHistory `json:"history"`
+}
+
+type AdminBiggestPot struct {
+	GameID       string    `json:"game_id"`
+	Won          int64     `json:"won"`
+	Pot          int64     `json:"pot"`
+	HandCategory string    `json:"hand_category"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type AdminHandHistory struct {
+	GameID       string    `json:"game_id"`
+	Equity       *float64  `json:"equity"`
+	IsMuck       bool      `json:"is_muck"`
+	Contributed  int64     `json:"contributed"`
+	Won          int64     `json:"won"`
+	Showdown     bool      `json:"showdown"`
+	ShowdownWon  bool      `json:"showdown_won"`
+	HandCategory string    `json:"hand_category"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// biggestPotsLimit is the number of biggest pots in player statistics
+const biggestPotsLimit = 5
+
+// HandleGetAdminPlayerStats returns statistics and the history of hands of a player
+func HandleGetAdminPlayerStats(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminPlayerStats")
+	ctx := c.Request().Context()
+	q := query.New(conn)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.WarnContext(ctx, "strconv.Atoi", "error", err, slog.String("id", c.Param("id")))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	filter, err := parseStatsFilter(c, time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	player, err := q.GetPlayer(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: store.ErrPlayerNotFound.Error()})
+		}
+		logger.WarnContext(ctx, "q.GetPlayer", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	playerFilter := filter
+	playerFilter.PlayerID = sql.NullInt32{Int32: player.ID, Valid: true}
+	stats, err := store.GetPlayerStats(ctx, q, playerFilter)
+	if err != nil {
+		logger.WarnContext(ctx, "store.GetPlayerStats", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	// a player without hands in the range has zero stats
+	stat := store.PlayerStat{PlayerID: player.ID, PlayerName: player.Name, HandCategories: map[string]int64{}}
+	if len(stats) > 0 {
+		stat = stats[0]
+	}
+
+	pots, err := q.GetBiggestPotsByPlayerID(ctx, query.GetBiggestPotsByPlayerIDParams{
+		PlayerID:    player.ID,
+		CreatedAt:   filter.From,
+		CreatedAt_2: filter.To,
+		SessionID:   filter.SessionID,
+		Limit:       biggestPotsLimit,
+	})
+	if err != nil {
+		logger.WarnContext(ctx, "q.GetBiggestPotsByPlayerID", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	history, err := q.GetHandHistoryByPlayerID(ctx, query.GetHandHistoryByPlayerIDParams{
+		PlayerID:    player.ID,
+		CreatedAt:   filter.From,
+		CreatedAt_2: filter.To,
+		SessionID:   filter.SessionID,
+	})
+	if err != nil {
+		logger.WarnContext(ctx, "q.GetHandHistoryByPlayerID", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := GetAdminPlayerStatsResponse{
+		AdminStatsFilter: toAdminStatsFilter(filter),
+		AdminPlayerStats: toAdminPlayerStats(stat),
+		BiggestPots:      make([]AdminBiggestPot, 0, len(pots)),
+		History:          make([]AdminHandHistory, 0, len(history)),
+	}
+	for _, p := range pots {
+		resp.BiggestPots = append(resp.BiggestPots, AdminBiggestPot{
+			GameID:       p.GameID,
+			Won:          p.Won,
+			Pot:          p.Pot,
+			HandCategory: p.HandCategory.String,
+			CreatedAt:    p.CreatedAt,
+		})
+	}
+	for _, h := range history {
+		var equity *float64
+		if h.Equity.Valid {
+			equity = &h.Equity.Float64
+		}
+		resp.History = append(resp.History, AdminHandHistory{
+			GameID:       h.GameID,
+			Equity:       equity,
+			IsMuck:       h.IsMuck,
+			Contributed:  h.Contributed,
+			Won:          h.Won,
+			Showdown:     h.Showdown,
+			ShowdownWon:  h.ShowdownWon,
+			HandCategory: h.HandCategory.String,
+			CreatedAt:    h.CreatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+type GetAdminLeaderboardResponse struct {
+	AdminStatsFilter
+	Metric  string                  `json:"metric"`
+	Players []AdminLeaderboardEntry `json:"players"`
+}
+
+type AdminLeaderboardEntry struct {
+	Rank  int     `json:"rank"`
+	Value float64 `json:"value"`
+	AdminPlayerStats
+}
+
+// HandleGetAdminLeaderboard returns players ranked by the metric
+func HandleGetAdminLeaderboard(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminLeaderboard")
+	ctx := c.Request().Context()
+
+	filter, err := parseStatsFilter(c, time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	metric := c.QueryParam("metric")
+	if metric == "" {
+		metric = config.Conf.LeaderboardMetric
+	}
+	minHands := int64(config.Conf.LeaderboardMinHands)
+	if v := c.QueryParam("min_hands"); v != "" {
+		if minHands, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid min_hands: %s", err)})
+		}
+	}
+	limit := 0
+	if v := c.QueryParam("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid limit: %s", v)})
+		}
+	}
+
+	stats, err := store.GetPlayerStats(ctx, query.New(conn), filter)
+	if err != nil {
+		logger.WarnContext(ctx, "store.GetPlayerStats", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	ranked, err := store.RankPlayers(stats, metric, minHands)
+	if err != nil {
+		if errors.Is(err, store.ErrUnknownMetric) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(ctx, "store.RankPlayers", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	resp := GetAdminLeaderboardResponse{
+		AdminStatsFilter: toAdminStatsFilter(filter),
+		Metric:           metric,
+		Players:          make([]AdminLeaderboardEntry, 0, len(ranked)),
+	}
+	value := store.LeaderboardMetrics[metric]
+	for i, s := range ranked {
+		resp.Players = append(resp.Players, AdminLeaderboardEntry{
+			Rank:             i + 1,
+			Value:            value(s),
+			AdminPlayerStats: toAdminPlayerStats(s),
+		})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// parseStatsFilter parses from, to, and session_id of the query
+func parseStatsFilter(c echo.Context, now time.Time) (store.StatsFilter, error) {
+	from, to, err := parseDateRange(c.QueryParam("from"), c.QueryParam("to"), now)
+	if err != nil {
+		return store.StatsFilter{}, err
+	}
+	filter := store.StatsFilter{From: from, To: to}
+	if v := c.QueryParam("session_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return store.StatsFilter{}, fmt.Errorf("invalid session_id: %w", err)
+		}
+		filter.SessionID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+	return filter, nil
+}
+
+// parseDateRange parses from and to as a date (2006-01-02) or RFC 3339.
+// from is the beginning of time and to is now if not set, to of a date includes the whole day.
+func parseDateRange(fromParam, toParam string, now time.Time) (time.Time, time.Time, error) {
+	from := time.Unix(0, 0).UTC()
+	to := now
+	if fromParam != "" {
+		t, _, err := parseDate(fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+		}
+		from = t
+	}
+	if toParam != "" {
+		t, isDate, err := parseDate(toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+		}
+		if isDate {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+func parseDate(s string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, false, nil
+}
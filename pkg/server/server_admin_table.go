@@ -0,0 +1,364 @@
+package server
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+type AdminTable struct {
+	// GameID is empty if there is no active game
+	GameID   string           `json:"game_id"`
+	Street   string           `json:"street"`
+	Seats    []AdminTableSeat `json:"seats"`
+	Pots     []SendPot        `json:"pots"`
+	PotTotal int64            `json:"pot_total"`
+}
+
+type AdminTableSeat struct {
+	SeatNumber  int32  `json:"seat_number"`
+	PlayerID    *int32 `json:"player_id"`
+	PlayerName  string `json:"player_name"`
+	Stack       int64  `json:"stack"`
+	Contributed int64  `json:"contributed"`
+	Bet         int64  `json:"bet"`
+	Folded      bool   `json:"folded"`
+	AllIn       bool   `json:"all_in"`
+}
+
+// HandleGetAdminTable returns stacks of seats and pots of the current game
+func HandleGetAdminTable(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminTable")
+
+	table, err := store.GetTable(c.Request().Context(), query.New(conn))
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "store.GetTable", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := AdminTable{
+		GameID:   table.GameID,
+		Street:   string(table.Street),
+		Seats:    make([]AdminTableSeat, 0, len(table.Seats)),
+		Pots:     toSendPots(table.Pots),
+		PotTotal: table.PotTotal,
+	}
+	for _, s := range table.Seats {
+		resp.Seats = append(resp.Seats, AdminTableSeat{
+			SeatNumber:  s.SeatNumber,
+			PlayerID:    nullInt32ToPtr(s.PlayerID),
+			PlayerName:  s.PlayerName,
+			Stack:       s.Stack,
+			Contributed: s.Contributed,
+			Bet:         s.Bet,
+			Folded:      s.Folded,
+			AllIn:       s.AllIn,
+		})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func toSendPots(pots []store.Pot) []SendPot {
+	sendPots := make([]SendPot, 0, len(pots))
+	for _, p := range pots {
+		sendPots = append(sendPots, SendPot{
+			Amount: p.Amount,
+			Seats:  p.Seats,
+		})
+	}
+	return sendPots
+}
+
+type PostAdminTableStackRequest struct {
+	SeatNumber int32 `json:"seat_number"`
+	Stack      int64 `json:"stack"`
+}
+
+// HandlePostAdminTableStack sets the chips of the player at a seat, e.g. on buy-in or rebuy
+func HandlePostAdminTableStack(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminTableStack")
+	q := query.New(conn)
+
+	var req PostAdminTableStackRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Stack < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "stack must not be negative"})
+	}
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	seat, err := q.GetSeatBySeatNumber(c.Request().Context(), sql.NullInt32{Int32: req.SeatNumber, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: store.ErrSeatNotFound.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "q.GetSeatBySeatNumber", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	// the stack belongs to the player, it moves with the player to another seat
+	if !seat.PlayerID.Valid {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "no player is seated"})
+	}
+	if err := q.SetStackToPlayer(c.Request().Context(), query.SetStackToPlayerParams{
+		Stack: req.Stack,
+		ID:    seat.PlayerID.Int32,
+	}); err != nil {
+		logger.WarnContext(c.Request().Context(), "q.SetStackToPlayer", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	notifyClients()
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+type PostAdminGameActionRequest struct {
+	SeatNumber int32  `json:"seat_number"`
+	Action     string `json:"action"`
+	// Amount is the chips put into the pot by the action, not needed for check, fold, and allin
+	Amount int64 `json:"amount"`
+}
+
+type AdminPlayerAction struct {
+	ID         int32     `json:"id"`
+	SeatNumber int32     `json:"seat_number"`
+	PlayerID   *int32    `json:"player_id"`
+	Street     string    `json:"street"`
+	Action     string    `json:"action"`
+	Amount     int64     `json:"amount"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func toAdminPlayerAction(a query.PlayerAction) AdminPlayerAction {
+	return AdminPlayerAction{
+		ID:         a.ID,
+		SeatNumber: a.SeatNumber,
+		PlayerID:   nullInt32ToPtr(a.PlayerID),
+		Street:     a.Street,
+		Action:     a.Action,
+		Amount:     a.Amount,
+		CreatedAt:  a.CreatedAt,
+	}
+}
+
+// HandlePostAdminGameAction records a blind, ante, bet, call, raise, check, fold or all-in of a seat
+func HandlePostAdminGameAction(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminGameAction")
+	ctx := c.Request().Context()
+
+	var req PostAdminGameActionRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(ctx, "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	var action *query.PlayerAction
+	if err := runTableTx(ctx, conn, func(t *tableTx) error {
+		var err error
+		action, err = store.RecordAction(ctx, t.q, req.SeatNumber, store.Action(req.Action), req.Amount)
+		if err != nil {
+			return err
+		}
+		t.notify()
+
+		if store.Action(action.Action) == store.ActionFold {
+			// equities change without the folded hand, and the game may end with one player left
+			t.calcEquity()
+			if err := advanceGameState(ctx, t, config.Conf); err != nil {
+				logger.WarnContext(ctx, "advanceGameState", "error", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidAction), errors.Is(err, store.ErrInsufficientStack):
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, store.ErrSeatNotFound):
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(ctx, "store.RecordAction", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, toAdminPlayerAction(*action))
+}
+
+type GetAdminGameActionResponse struct {
+	Actions []AdminPlayerAction `json:"actions"`
+}
+
+// HandleGetAdminGameAction returns actions of the current game in order
+func HandleGetAdminGameAction(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminGameAction")
+	ctx := c.Request().Context()
+	q := query.New(conn)
+
+	game, err := q.GetCurrentGame(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "no active game"})
+		}
+		logger.WarnContext(ctx, "q.GetCurrentGame", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	actions, err := q.GetPlayerActionsByGameID(ctx, game.ID)
+	if err != nil {
+		logger.WarnContext(ctx, "q.GetPlayerActionsByGameID", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := GetAdminGameActionResponse{Actions: make([]AdminPlayerAction, 0, len(actions))}
+	for _, a := range actions {
+		resp.Actions = append(resp.Actions, toAdminPlayerAction(a))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// HandleDeleteAdminGameAction undoes the last action of the current game
+func HandleDeleteAdminGameAction(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleDeleteAdminGameAction")
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	var action *query.PlayerAction
+	if err := runTableTx(c.Request().Context(), conn, func(t *tableTx) error {
+		var err error
+		action, err = store.UndoLastAction(c.Request().Context(), t.q)
+		if err != nil {
+			return err
+		}
+		t.notify()
+
+		if store.Action(action.Action) == store.ActionFold {
+			// the hand of the fold is back, equities change with it
+			t.calcEquity()
+		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, store.ErrNoActiveGame) || errors.Is(err, store.ErrNoPlayerAction) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(c.Request().Context(), "store.UndoLastAction", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, toAdminPlayerAction(*action))
+}
+
+type AdminUnawardedPot struct {
+	ID     int32  `json:"id"`
+	GameID string `json:"game_id"`
+	Amount int64  `json:"amount"`
+	// PlayerIDs is players eligible to win the pot, any player can win it if empty
+	PlayerIDs []int32   `json:"player_ids"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetAdminUnawardedPotsResponse struct {
+	Pots []AdminUnawardedPot `json:"pots"`
+}
+
+// HandleGetAdminUnawardedPots returns pots not awarded when games finished
+func HandleGetAdminUnawardedPots(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminUnawardedPots")
+
+	pots, err := store.GetUnawardedPots(c.Request().Context(), query.New(conn))
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "store.GetUnawardedPots", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	resp := GetAdminUnawardedPotsResponse{Pots: make([]AdminUnawardedPot, 0, len(pots))}
+	for _, p := range pots {
+		resp.Pots = append(resp.Pots, AdminUnawardedPot{
+			ID:        p.ID,
+			GameID:    p.GameID,
+			Amount:    p.Amount,
+			PlayerIDs: p.PlayerIDs,
+			Reason:    string(p.Reason),
+			CreatedAt: p.CreatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+type PostAdminUnawardedPotAwardRequest struct {
+	// PlayerIDs is winners of the pot, they split the pot equally
+	PlayerIDs []int32 `json:"player_ids"`
+}
+
+type AdminPotAward struct {
+	PlayerID int32 `json:"player_id"`
+	Amount   int64 `json:"amount"`
+}
+
+type PostAdminUnawardedPotAwardResponse struct {
+	Awards []AdminPotAward `json:"awards"`
+}
+
+// HandlePostAdminUnawardedPotAward awards a pot not awarded when the game finished to winners chosen by the dealer
+func HandlePostAdminUnawardedPotAward(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminUnawardedPotAward")
+	ctx := c.Request().Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	var req PostAdminUnawardedPotAwardRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(ctx, "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if len(req.PlayerIDs) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "player_ids is required"})
+	}
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	won, err := store.AwardUnawardedPot(ctx, conn, int32(id), req.PlayerIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrPotNotFound):
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, store.ErrPotAwarded):
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, store.ErrNotEligible):
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+		logger.WarnContext(ctx, "store.AwardUnawardedPot", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	notifyClients()
+
+	resp := PostAdminUnawardedPotAwardResponse{Awards: make([]AdminPotAward, 0, len(won))}
+	for playerID, amount := range won {
+		resp.Awards = append(resp.Awards, AdminPotAward{PlayerID: playerID, Amount: amount})
+	}
+	slices.SortFunc(resp.Awards, func(a, b AdminPotAward) int { return cmp.Compare(a.PlayerID, b.PlayerID) })
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+type AdminTournament struct {
+	ID            int32            `json:"id"`
+	StartingStack int64            `json:"starting_stack"`
+	StartedAt     time.Time        `json:"started_at"`
+	Levels        []SendBlindLevel `json:"levels"`
+	SendTournament
+}
+
+// getAdminTournament returns the running tournament with the clock and standings
+func getAdminTournament(ctx context.Context, q *query.Queries) (*AdminTournament, error) {
+	t, err := store.GetRunningTournament(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("store.GetRunningTournament(): %w", err)
+	}
+	table, err := store.GetTable(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("store.GetTable(): %w", err)
+	}
+
+	resp := &AdminTournament{
+		ID:             t.ID,
+		StartingStack:  t.StartingStack,
+		StartedAt:      t.StartedAt,
+		Levels:         make([]SendBlindLevel, 0, len(t.BlindLevels)),
+		SendTournament: toSendTournament(*t, store.CalcTournamentStandings(*t, table), time.Now()),
+	}
+	for _, l := range t.BlindLevels {
+		resp.Levels = append(resp.Levels, toSendBlindLevel(l))
+	}
+	return resp, nil
+}
+
+func toSendTournament(t store.Tournament, standings store.TournamentStandings, now time.Time) SendTournament {
+	clock := t.Clock(now)
+	send := SendTournament{
+		Name:              t.Name,
+		Level:             clock.Level + 1,
+		SmallBlind:        clock.Blinds.SmallBlind,
+		BigBlind:          clock.Blinds.BigBlind,
+		Ante:              clock.Blinds.Ante,
+		RemainingSeconds:  int(clock.Remaining.Seconds()),
+		Paused:            clock.Paused,
+		Entries:           standings.Entries,
+		PlayersRemaining:  standings.Remaining,
+		PlayersEliminated: standings.Eliminated,
+		AverageStack:      standings.AverageStack,
+	}
+	if clock.Next != nil {
+		next := toSendBlindLevel(*clock.Next)
+		send.NextLevel = &next
+		send.LevelEndsAt = &clock.EndsAt
+	}
+	return send
+}
+
+func toSendBlindLevel(l store.BlindLevel) SendBlindLevel {
+	return SendBlindLevel{
+		SmallBlind: l.SmallBlind,
+		BigBlind:   l.BigBlind,
+		Ante:       l.Ante,
+		Minutes:    l.Minutes,
+	}
+}
+
+// tournamentLevels returns the blind structure of the config
+func tournamentLevels(cc config.Config) []store.BlindLevel {
+	levels := make([]store.BlindLevel, 0, len(cc.TournamentLevels))
+	for _, l := range cc.TournamentLevels {
+		levels = append(levels, store.BlindLevel{
+			SmallBlind: l.SmallBlind,
+			BigBlind:   l.BigBlind,
+			Ante:       l.Ante,
+			Minutes:    l.Minutes,
+		})
+	}
+	return levels
+}
+
+// tournamentErrorStatus returns the HTTP status of errors of tournament controls
+func tournamentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrNoTournament):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrTournamentRunning), errors.Is(err, store.ErrLastBlindLevel):
+		return http.StatusConflict
+	case errors.Is(err, store.ErrInvalidBlindLevel):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// HandleGetAdminTournament returns the running tournament
+func HandleGetAdminTournament(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminTournament")
+
+	resp, err := getAdminTournament(c.Request().Context(), query.New(conn))
+	if err != nil {
+		status := tournamentErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			logger.WarnContext(c.Request().Context(), "getAdminTournament", "error", err)
+		}
+		return c.JSON(status, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+type PostAdminTournamentRequest struct {
+	Name string `json:"name"`
+	// StartingStack is set to stacks of seated players if not 0
+	StartingStack int64 `json:"starting_stack"`
+	// Entries is the number of players, seated players are counted if 0
+	Entries int32 `json:"entries"`
+	// Levels is the blind structure, tournament_levels of the config if empty
+	Levels []SendBlindLevel `json:"levels"`
+}
+
+// HandlePostAdminTournament starts a tournament and the clock on the first level
+func HandlePostAdminTournament(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminTournament")
+	ctx := c.Request().Context()
+
+	var req PostAdminTournamentRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(ctx, "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "name is required"})
+	}
+	if req.StartingStack < 0 || req.Entries < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "starting_stack and entries must not be negative"})
+	}
+
+	levels := tournamentLevels(config.Conf)
+	if len(req.Levels) > 0 {
+		levels = make([]store.BlindLevel, 0, len(req.Levels))
+		for _, l := range req.Levels {
+			levels = append(levels, store.BlindLevel{
+				SmallBlind: l.SmallBlind,
+				BigBlind:   l.BigBlind,
+				Ante:       l.Ante,
+				Minutes:    l.Minutes,
+			})
+		}
+	}
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	if _, err := store.StartTournament(ctx, conn, req.Name, levels, req.StartingStack, req.Entries); err != nil {
+		status := tournamentErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			logger.WarnContext(ctx, "store.StartTournament", "error", err)
+		}
+		return c.JSON(status, ErrorResponse{Error: err.Error()})
+	}
+	notifyClients()
+
+	return respondAdminTournament(c, conn, http.StatusCreated)
+}
+
+// HandlePostAdminTournamentPause stops the tournament clock, e.g. on a break
+func HandlePostAdminTournamentPause(c echo.Context, conn *sql.DB) error {
+	return handleTournamentControl(c, conn, "HandlePostAdminTournamentPause", func(ctx context.Context, q *query.Queries) error {
+		return store.PauseTournament(ctx, q)
+	})
+}
+
+// HandlePostAdminTournamentResume restarts the tournament clock with the time remaining when it was paused
+func HandlePostAdminTournamentResume(c echo.Context, conn *sql.DB) error {
+	return handleTournamentControl(c, conn, "HandlePostAdminTournamentResume", func(ctx context.Context, q *query.Queries) error {
+		return store.ResumeTournament(ctx, q)
+	})
+}
+
+// HandlePostAdminTournamentSkip moves the tournament to the next level
+func HandlePostAdminTournamentSkip(c echo.Context, conn *sql.DB) error {
+	return handleTournamentControl(c, conn, "HandlePostAdminTournamentSkip", func(ctx context.Context, q *query.Queries) error {
+		return store.SkipTournamentLevel(ctx, q, time.Now())
+	})
+}
+
+type PostAdminTournamentLevelRequest struct {
+	// Level is from 1
+	Level int `json:"level"`
+}
+
+// HandlePostAdminTournamentLevel moves the tournament to a level, e.g. to go back a level skipped by mistake
+func HandlePostAdminTournamentLevel(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminTournamentLevel")
+
+	var req PostAdminTournamentLevelRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	return handleTournamentControl(c, conn, "HandlePostAdminTournamentLevel", func(ctx context.Context, q *query.Queries) error {
+		return store.SetTournamentLevel(ctx, q, req.Level-1)
+	})
+}
+
+// HandlePostAdminTournamentFinish ends the tournament
+func HandlePostAdminTournamentFinish(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandlePostAdminTournamentFinish")
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	if err := runTableTx(c.Request().Context(), conn, func(t *tableTx) error {
+		if err := store.FinishTournament(c.Request().Context(), t.q); err != nil {
+			return err
+		}
+		t.notify()
+		return nil
+	}); err != nil {
+		status := tournamentErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			logger.WarnContext(c.Request().Context(), "store.FinishTournament", "error", err)
+		}
+		return c.JSON(status, ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// handleTournamentControl runs a control of the clock in a transaction and returns the tournament after it
+func handleTournamentControl(c echo.Context, conn *sql.DB, method string, control func(ctx context.Context, q *query.Queries) error) error {
+	logger := slog.With("method", method)
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	if err := runTableTx(c.Request().Context(), conn, func(t *tableTx) error {
+		if err := control(c.Request().Context(), t.q); err != nil {
+			return err
+		}
+		t.notify()
+		return nil
+	}); err != nil {
+		status := tournamentErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			logger.WarnContext(c.Request().Context(), "tournament control", "error", err)
+		}
+		return c.JSON(status, ErrorResponse{Error: err.Error()})
+	}
+
+	return respondAdminTournament(c, conn, http.StatusOK)
+}
+
+func respondAdminTournament(c echo.Context, conn *sql.DB, status int) error {
+	resp, err := getAdminTournament(c.Request().Context(), query.New(conn))
+	if err != nil {
+		slog.WarnContext(c.Request().Context(), "getAdminTournament", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(status, resp)
+}
+
+// startTournamentClock starts a goroutine that pushes the tournament to clients when the level changes
+func startTournamentClock(ctx context.Context, conn *sql.DB) {
+	q := query.New(conn)
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+
+		var lastID int32
+		lastLevel := -1
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t, err := store.GetRunningTournament(ctx, q)
+				if err != nil {
+					if !errors.Is(err, store.ErrNoTournament) {
+						slog.WarnContext(ctx, "failed to get running tournament", "error", err)
+					}
+					continue
+				}
+
+				level := t.Clock(time.Now()).Level
+				if t.ID == lastID && level == lastLevel {
+					continue
+				}
+				if t.ID == lastID {
+					slog.InfoContext(ctx, "Tournament level changed",
+						slog.String("event", "tournament_level_changed"),
+						slog.Int("tournament_id", int(t.ID)),
+						slog.Int("level", level+1))
+					notifyClients()
+				}
+				lastID, lastLevel = t.ID, level
+			}
+		}
+	}()
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// adminWriteTimeout is the timeout to send a message to an admin WebSocket client
+const adminWriteTimeout = 5 * time.Second
+
+// AdminMessage is a message for admin WebSocket clients
+type AdminMessage struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// adminWSClient is an admin WebSocket connection with the queue of messages to send
+type adminWSClient struct {
+	conn  *websocket.Conn
+	queue chan []byte
+	// slow is closed when the queue is full, the client is disconnected rather than missing alerts silently
+	slow     chan struct{}
+	slowOnce sync.Once
+}
+
+func newAdminWSClient(conn *websocket.Conn) *adminWSClient {
+	return &adminWSClient{
+		conn:  conn,
+		queue: make(chan []byte, max(config.Conf.WebSocketQueueSize, 1)),
+		slow:  make(chan struct{}),
+	}
+}
+
+// AdminWebSocketManager manages WebSocket connections of admin clients
+type AdminWebSocketManager struct {
+	mu      sync.Mutex
+	clients map[*adminWSClient]struct{}
+}
+
+var adminWSManager = &AdminWebSocketManager{
+	clients: make(map[*adminWSClient]struct{}),
+}
+
+func (m *AdminWebSocketManager) addClient(client *adminWSClient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[client] = struct{}{}
+}
+
+func (m *AdminWebSocketManager) removeClient(client *adminWSClient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clients, client)
+}
+
+func (m *AdminWebSocketManager) hasClients() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.clients) > 0
+}
+
+// broadcast queues a message to all admin WebSocket clients without blocking
+func (m *AdminWebSocketManager) broadcast(msg AdminMessage) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		slog.Warn("failed to marshal admin message", "type", msg.Type, "error", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for client := range m.clients {
+		m.enqueue(client, b)
+	}
+}
+
+// send queues a message to a client without blocking
+func (m *AdminWebSocketManager) send(client *adminWSClient, msg AdminMessage) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		slog.Warn("failed to marshal admin message", "type", msg.Type, "error", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enqueue(client, b)
+}
+
+// enqueue queues a message, the client is closed if the queue is full.
+// The caller must hold m.mu.
+func (m *AdminWebSocketManager) enqueue(client *adminWSClient, b []byte) {
+	select {
+	case client.queue <- b:
+	default:
+		client.slowOnce.Do(func() {
+			close(client.slow)
+		})
+	}
+}
+
+// serve sends queued messages to the client until the client goes away
+func (m *AdminWebSocketManager) serve(ctx context.Context, client *adminWSClient) {
+	logger := slog.With("method", "AdminWebSocketManager.serve")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.slow:
+			logger.WarnContext(ctx, "closing slow admin WebSocket client", "queue_size", cap(client.queue))
+			client.conn.Close(websocket.StatusPolicyViolation, "slow client")
+			return
+		case b := <-client.queue:
+			wctx, cancel := context.WithTimeout(ctx, adminWriteTimeout)
+			err := client.conn.Write(wctx, websocket.MessageText, b)
+			cancel()
+			if err != nil {
+				logger.WarnContext(ctx, "failed to send admin message to WebSocket", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// notifyAdmin sends an event to admin WebSocket clients
+func notifyAdmin(msgType string, data any) {
+	adminWSManager.broadcast(AdminMessage{Type: msgType, Data: data})
+}
+
+// adminWS is a WebSocket for admin clients, it receives alerts and updates for floor staff
+func adminWS(c echo.Context, conn *sql.DB) error {
+	wsConn, err := websocket.Accept(c.Response(), c.Request(), &websocket.AcceptOptions{
+		OriginPatterns: []string{"*"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to accept WebSocket: %w", err)
+	}
+	defer wsConn.Close(websocket.StatusNormalClosure, "")
+
+	client := newAdminWSClient(wsConn)
+	adminWSManager.addClient(client)
+	defer adminWSManager.removeClient(client)
+
+	// send the current card location map first, it is queued after changes broadcast in the meantime
+	cards, err := getAdminGameCards(c.Request().Context(), query.New(conn))
+	if err != nil {
+		c.Logger().Errorf(err.Error())
+	} else {
+		adminWSManager.send(client, AdminMessage{Type: AdminMessageTypeGameCards, Data: cards})
+	}
+
+	// admin clients only receive, CloseRead handles control frames until the connection is closed
+	ctx := wsConn.CloseRead(c.Request().Context())
+	adminWSManager.serve(ctx, client)
+	return nil
+}
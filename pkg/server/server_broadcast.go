@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// broadcastDelaySeconds is the delay of the public feed, it is changed by POST /admin/broadcast without restarting
+var broadcastDelaySeconds atomic.Int64
+
+func getBroadcastDelay() time.Duration {
+	return time.Duration(broadcastDelaySeconds.Load()) * time.Second
+}
+
+// maxBroadcastDelaySeconds is the maximum delay of the public feed
+const maxBroadcastDelaySeconds = 3600
+
+// delayedFrame is a frame of the table captured at a time
+type delayedFrame struct {
+	capturedAt time.Time
+	frame      *wsFrame
+}
+
+// DelayBuffer keeps frames until they are released to the public feed
+type DelayBuffer struct {
+	mu      sync.Mutex
+	pending []delayedFrame
+}
+
+var delayedFeed = &DelayBuffer{}
+
+func (d *DelayBuffer) push(capturedAt time.Time, frame *wsFrame) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = append(d.pending, delayedFrame{capturedAt: capturedAt, frame: frame})
+}
+
+// release returns frames captured before now minus the delay in order, a shorter delay releases them at once
+func (d *DelayBuffer) release(now time.Time, delay time.Duration) []*wsFrame {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var frames []*wsFrame
+	for len(d.pending) > 0 && !d.pending[0].capturedAt.Add(delay).After(now) {
+		frames = append(frames, d.pending[0].frame)
+		d.pending = d.pending[1:]
+	}
+	return frames
+}
+
+// startBroadcaster starts a goroutine that sends the table on each change.
+// The producer feed receives it immediately, and the public feed receives it after the broadcast delay.
+func startBroadcaster(ctx context.Context, conn *sql.DB) {
+	broadcastDelaySeconds.Store(int64(config.Conf.BroadcastDelaySeconds))
+	q := query.New(conn)
+
+	// messages of a change are numbered from the last message of the previous change
+	var prev *Send
+	var seq int64
+	capture := func() {
+		send, err := getSend(ctx, q)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to build update for WebSocket", "error", err)
+			return
+		}
+		frame, err := newWSFrame(prev, send, seq)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to build update for WebSocket", "error", err)
+			return
+		}
+		prev, seq = send, frame.seq
+
+		// the body has hole cards before the delay, so it is not logged
+		slog.With("method", "startBroadcaster").DebugContext(ctx, "Send to message", slog.Int64("seq", seq), slog.Int("messages", len(frame.messages)))
+		wsHub.snapshots.Add(1)
+		wsHub.broadcast(frame, true)
+		delayedFeed.push(time.Now(), frame)
+	}
+	release := func() {
+		for _, frame := range delayedFeed.release(time.Now(), getBroadcastDelay()) {
+			wsHub.broadcast(frame, false)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		slog.InfoContext(ctx, "broadcaster started", "delay", getBroadcastDelay())
+
+		// the public feed has a snapshot to start from after the delay
+		capture()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-wsHub.notifyCh:
+				capture()
+				release()
+			case <-ticker.C:
+				release()
+			}
+		}
+	}()
+}
+
+// hasProducerToken returns true if the request has the producer token in the Authorization header
+func hasProducerToken(c echo.Context) bool {
+	token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(config.Conf.ProducerToken)) != 1 {
+		slog.WarnContext(c.Request().Context(), "invalid producer token", "remote_addr", c.RealIP(), "path", c.Path())
+		return false
+	}
+	return true
+}
+
+// requireProducerToken allows requests with the producer token, all requests are forbidden if the token is not set
+func requireProducerToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if config.Conf.ProducerToken == "" {
+			return c.JSON(http.StatusForbidden, ErrorResponse{Error: "producer token is not set"})
+		}
+		if !hasProducerToken(c) {
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid producer token"})
+		}
+		return next(c)
+	}
+}
+
+// requireProducerTokenIfSet allows requests with the producer token if the token is set, e.g. for hole cards without the delay
+func requireProducerTokenIfSet(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if config.Conf.ProducerToken != "" && !hasProducerToken(c) {
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid producer token"})
+		}
+		return next(c)
+	}
+}
+
+// producerWS sends the table without the broadcast delay to producers authenticated by the producer token
+func producerWS(c echo.Context) error {
+	if config.Conf.ProducerToken == "" {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "producer feed is disabled"})
+	}
+	if !hasProducerToken(c) {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid producer token"})
+	}
+
+	client, err := newWSClient(c, true)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	wsConn, err := websocket.Accept(c.Response(), c.Request(), &websocket.AcceptOptions{
+		OriginPatterns: []string{"*"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to accept WebSocket: %w", err)
+	}
+	defer wsConn.Close(websocket.StatusNormalClosure, "")
+
+	client.conn = wsConn
+	wsHub.serve(c.Request().Context(), client)
+	return nil
+}
+
+type AdminBroadcast struct {
+	DelaySeconds int `json:"delay_seconds"`
+	// Clients is connections of the public feed, Producers is connections of the producer feed
+	Clients   int `json:"clients"`
+	Producers int `json:"producers"`
+}
+
+// HandleGetAdminBroadcast returns the broadcast delay and connections of feeds
+func HandleGetAdminBroadcast(c echo.Context) error {
+	clients, producers := wsHub.count()
+	return c.JSON(http.StatusOK, AdminBroadcast{
+		DelaySeconds: int(broadcastDelaySeconds.Load()),
+		Clients:      clients,
+		Producers:    producers,
+	})
+}
+
+type PostAdminBroadcastRequest struct {
+	DelaySeconds *int `json:"delay_seconds"`
+}
+
+// HandlePostAdminBroadcast changes the broadcast delay, snapshots already captured are released with the new delay
+func HandlePostAdminBroadcast(c echo.Context) error {
+	logger := slog.With("method", "HandlePostAdminBroadcast")
+
+	var req PostAdminBroadcastRequest
+	if err := c.Bind(&req); err != nil {
+		logger.WarnContext(c.Request().Context(), "c.Bind", "error", err)
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if req.DelaySeconds == nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "delay_seconds is required"})
+	}
+	if *req.DelaySeconds < 0 || *req.DelaySeconds > maxBroadcastDelaySeconds {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("delay_seconds must be from 0 to %d", maxBroadcastDelaySeconds)})
+	}
+
+	old := broadcastDelaySeconds.Swap(int64(*req.DelaySeconds))
+	slog.InfoContext(c.Request().Context(), "Broadcast delay changed",
+		slog.String("event", "broadcast_delay_changed"),
+		slog.Int64("old_delay_seconds", old),
+		slog.Int("delay_seconds", *req.DelaySeconds))
+
+	return HandleGetAdminBroadcast(c)
+}
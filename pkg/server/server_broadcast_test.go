@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+)
+
+func TestProducerTokenMiddleware(t *testing.T) {
+	ok := func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		query         string
+		wantRequired  int
+		wantIfSet     int
+	}{
+		{
+			name:         "token not set",
+			wantRequired: http.StatusForbidden,
+			wantIfSet:    http.StatusNoContent,
+		},
+		{
+			name:          "valid header",
+			token:         "secret",
+			authorization: "Bearer secret",
+			wantRequired:  http.StatusNoContent,
+			wantIfSet:     http.StatusNoContent,
+		},
+		{
+			name:          "invalid header",
+			token:         "secret",
+			authorization: "Bearer wrong",
+			wantRequired:  http.StatusUnauthorized,
+			wantIfSet:     http.StatusUnauthorized,
+		},
+		{
+			name:         "query string is not accepted",
+			token:        "secret",
+			query:        "?token=secret",
+			wantRequired: http.StatusUnauthorized,
+			wantIfSet:    http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := config.Conf
+			defer func() { config.Conf = orig }()
+			config.Conf.ProducerToken = tt.token
+
+			for _, m := range []struct {
+				name       string
+				middleware echo.MiddlewareFunc
+				want       int
+			}{
+				{name: "requireProducerToken", middleware: requireProducerToken, want: tt.wantRequired},
+				{name: "requireProducerTokenIfSet", middleware: requireProducerTokenIfSet, want: tt.wantIfSet},
+			} {
+				req := httptest.NewRequest(http.MethodGet, "/admin/game/cards"+tt.query, nil)
+				if tt.authorization != "" {
+					req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+				}
+				rec := httptest.NewRecorder()
+				if err := m.middleware(ok)(echo.New().NewContext(req, rec)); err != nil {
+					t.Fatalf("%s: %+v", m.name, err)
+				}
+				if rec.Code != m.want {
+					t.Errorf("%s: status = %d, want %d", m.name, rec.Code, m.want)
+				}
+			}
+		})
+	}
+}
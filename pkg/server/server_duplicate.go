@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/whywaita/poker-go"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+// Policies of a card read at a second location in one game
+const (
+	// DuplicateCardPolicyReject keeps the card at the first location, and the read is answered as card_in_play
+	DuplicateCardPolicyReject = "reject"
+	// DuplicateCardPolicyMove moves the card from the first location to the new location
+	DuplicateCardPolicyMove = "move"
+	// DuplicateCardPolicyFlag keeps the card at the first location, and only alerts
+	DuplicateCardPolicyFlag = "flag"
+)
+
+// AdminMessageTypeCardAlert is sent to admin WebSocket clients when a duplicate card is detected
+const AdminMessageTypeCardAlert = "card_alert"
+
+// CardAlert is an alert of a card seen at a second location in one game.
+// It is a misdeal, or a duplicate tag if the card is still present at the previous antenna.
+type CardAlert struct {
+	Card                    SendCard  `json:"card"`
+	Serial                  string    `json:"serial"`
+	AntennaTypeName         string    `json:"antenna_type_name"`
+	PreviousSerial          string    `json:"previous_serial"`
+	PreviousAntennaTypeName string    `json:"previous_antenna_type_name"`
+	PreviousStillPresent    bool      `json:"previous_still_present"`
+	Policy                  string    `json:"policy"`
+	DetectedAt              time.Time `json:"detected_at"`
+}
+
+// checkDuplicateCard detects the card stored at another location in the current game, and applies DuplicateCardPolicy.
+// It returns true with the outcome if the read must not be processed further.
+func checkDuplicateCard(ctx context.Context, t *tableTx, cc config.Config, card poker.Card, serial, antennaTypeName string) (bool, ReadOutcome, error) {
+	logger := slog.With("method", "checkDuplicateCard")
+
+	location, err := store.GetCardLocation(ctx, t.q, card)
+	if err != nil {
+		return true, ReadOutcomeError, fmt.Errorf("store.GetCardLocation(): %w", err)
+	}
+	if location == nil || location.Serial == serial {
+		return false, "", nil
+	}
+	if isHandCardToMuck(antennaTypeName, location) {
+		// cards of a hand are expected to be mucked
+		return false, "", nil
+	}
+
+	policy := cc.DuplicateCardPolicy
+	if policy != DuplicateCardPolicyMove && policy != DuplicateCardPolicyFlag {
+		policy = DuplicateCardPolicyReject
+	}
+
+	alert := CardAlert{
+		Card: SendCard{
+			Suit: card.Suit.String(),
+			Rank: card.Rank.String(),
+		},
+		Serial:                  serial,
+		AntennaTypeName:         antennaTypeName,
+		PreviousSerial:          location.Serial,
+		PreviousAntennaTypeName: location.AntennaTypeName,
+		PreviousStillPresent:    t.hasPresence(location.Serial, card),
+		Policy:                  policy,
+		DetectedAt:              time.Now(),
+	}
+	logger.WarnContext(ctx, "duplicate card detected",
+		"event", "duplicate_card_detected",
+		"card", fmt.Sprintf("%s%s", card.Rank.String(), card.Suit.String()),
+		"serial", serial,
+		"previous_serial", location.Serial,
+		"previous_still_present", alert.PreviousStillPresent,
+		"policy", policy)
+	notifyAdmin(AdminMessageTypeCardAlert, alert)
+
+	switch policy {
+	case DuplicateCardPolicyMove:
+		if err := store.RemoveCardFromPlay(ctx, t.q, location.ID, location.HandID); err != nil {
+			return true, ReadOutcomeError, fmt.Errorf("store.RemoveCardFromPlay(): %w", err)
+		}
+		if location.HandID.Valid {
+			t.calcEquity()
+		}
+		t.notify()
+		return false, "", nil
+	case DuplicateCardPolicyFlag:
+		return true, ReadOutcomeCardFlagged, nil
+	default:
+		return true, ReadOutcomeCardInPlay, nil
+	}
+}
+
+// isHandCardToMuck returns true if the card of a player's hand is read by the muck antenna
+func isHandCardToMuck(antennaTypeName string, location *query.GetCardLocationByRankSuitRow) bool {
+	return store.GetAntennaType(antennaTypeName) == store.AntennaTypeMuck &&
+		store.GetAntennaType(location.AntennaTypeName) == store.AntennaTypePlayer &&
+		location.HandID.Valid
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+)
+
+// events streams the table of the public feed as Server-Sent Events, for tools that can't use WebSocket.
+// The id of an event is the epoch and the sequence number, a client reconnecting with Last-Event-ID resumes from it.
+// ?table= selects the table, 404 if the server does not serve it.
+func events(c echo.Context) error {
+	if table := c.QueryParam("table"); table != "" && table != config.Conf.TableName {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: fmt.Sprintf("table %q is not served", table)})
+	}
+
+	client, err := newWSClient(c, false)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if id := c.Request().Header.Get("Last-Event-ID"); id != "" {
+		epoch, since, ok := strings.Cut(id, ":")
+		seq, err := strconv.ParseInt(since, 10, 64)
+		if !ok || err != nil || seq < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid Last-Event-ID %q", id)})
+		}
+		client.epoch, client.since = epoch, seq
+	}
+	client.sse = true
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	// disable buffering of reverse proxies (nginx)
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	wsHub.serveSSE(c.Request().Context(), client, w)
+	return nil
+}
+
+// serveSSE writes queued messages to the stream until the client goes away, a comment is written instead of ping to keep the connection
+func (h *WebSocketHub) serveSSE(ctx context.Context, client *wsClient, w *echo.Response) {
+	logger := slog.With("method", "serveSSE", "remote_addr", client.remoteAddr)
+	rc := http.NewResponseController(w)
+
+	h.register(client)
+	defer h.unregister(client)
+
+	var ping <-chan time.Time
+	if config.Conf.WebSocketPingSeconds > 0 {
+		ticker := time.NewTicker(time.Duration(config.Conf.WebSocketPingSeconds) * time.Second)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	write := func(format string, a ...any) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return fmt.Errorf("rc.SetWriteDeadline(): %w", err)
+		}
+		if _, err := fmt.Fprintf(w, format, a...); err != nil {
+			return fmt.Errorf("fmt.Fprintf(): %w", err)
+		}
+		if err := rc.Flush(); err != nil {
+			return fmt.Errorf("rc.Flush(): %w", err)
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.slow:
+			logger.WarnContext(ctx, "closing slow SSE client", "queue_size", cap(client.queue))
+			return
+		case msg := <-client.queue:
+			if err := write("id: %s:%d\nevent: %s\ndata: %s\n\n", wsEpoch, msg.seq, msg.typ, msg.body); err != nil {
+				logger.WarnContext(ctx, "failed to send update to SSE", "error", err)
+				return
+			}
+			client.sent.Add(1)
+			h.sent.Add(1)
+		case <-ping:
+			if err := write(": ping\n\n"); err != nil {
+				logger.WarnContext(ctx, "failed to send ping to SSE", "error", err)
+				return
+			}
+		}
+	}
+}
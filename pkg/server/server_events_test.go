@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+)
+
+func TestEvents_TableNotServed(t *testing.T) {
+	orig := config.Conf
+	defer func() { config.Conf = orig }()
+	config.Conf.TableName = "Table 1"
+
+	req := httptest.NewRequest(http.MethodGet, "/events?table=Table%202", nil)
+	rec := httptest.NewRecorder()
+	if err := events(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("events(): %+v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+// ReadOutcome is the result of a card read, returned to the device
+type ReadOutcome string
+
+const (
+	// ReadOutcomeAccepted is a card stored (or removed) as expected
+	ReadOutcomeAccepted ReadOutcome = "accepted"
+	// ReadOutcomeDuplicate is a card already stored at the same antenna
+	ReadOutcomeDuplicate ReadOutcome = "duplicate"
+	// ReadOutcomeUnconfirmed is a card waiting for more consecutive reads
+	ReadOutcomeUnconfirmed ReadOutcome = "unconfirmed"
+	// ReadOutcomeHandComplete is the second card of a player, the hand is registered
+	ReadOutcomeHandComplete ReadOutcome = "hand_complete"
+	// ReadOutcomeHandFull is a third card on a player antenna
+	ReadOutcomeHandFull ReadOutcome = "hand_full"
+	// ReadOutcomeCardInPlay is a card already in play at another antenna
+	ReadOutcomeCardInPlay ReadOutcome = "card_in_play"
+	// ReadOutcomeCardFlagged is a card already in play at another antenna, kept there and alerted (DuplicateCardPolicy: flag)
+	ReadOutcomeCardFlagged ReadOutcome = "card_flagged"
+	// ReadOutcomeBoardFull is a sixth card on the board
+	ReadOutcomeBoardFull ReadOutcome = "board_full"
+	// ReadOutcomeHandInProgress is a rabbit card read before the hand ends
+	ReadOutcomeHandInProgress ReadOutcome = "hand_in_progress"
+	// ReadOutcomeUnknownUID is a UID not in card_ids
+	ReadOutcomeUnknownUID ReadOutcome = "unknown_uid"
+	// ReadOutcomeAntennaUnassigned is a card read by an antenna without antenna type
+	ReadOutcomeAntennaUnassigned ReadOutcome = "antenna_unassigned"
+	// ReadOutcomeCheckedIn is a membership card, the player is seated at the antenna
+	ReadOutcomeCheckedIn ReadOutcome = "checked_in"
+	// ReadOutcomeSeatInPlay is a membership card read by a seat with cards in play
+	ReadOutcomeSeatInPlay ReadOutcome = "seat_in_play"
+	// ReadOutcomeSeatEmpty is a card read by a player antenna without a seated player
+	ReadOutcomeSeatEmpty ReadOutcome = "seat_empty"
+	// ReadOutcomeError is a failure in the server, the device may retry
+	ReadOutcomeError ReadOutcome = "error"
+)
+
+// Feedback is a suggested action for the device to signal the outcome to the dealer
+type Feedback struct {
+	LED  string `json:"led"`  // off, green, blue, yellow, red
+	Beep string `json:"beep"` // none, short, double, long
+}
+
+// Feedback returns the suggested feedback of the outcome
+func (o ReadOutcome) Feedback() Feedback {
+	switch o {
+	case ReadOutcomeAccepted:
+		return Feedback{LED: "green", Beep: "short"}
+	case ReadOutcomeDuplicate:
+		return Feedback{LED: "green", Beep: "none"}
+	case ReadOutcomeUnconfirmed:
+		return Feedback{LED: "off", Beep: "none"}
+	case ReadOutcomeHandComplete:
+		return Feedback{LED: "blue", Beep: "double"}
+	case ReadOutcomeCheckedIn:
+		return Feedback{LED: "green", Beep: "double"}
+	case ReadOutcomeUnknownUID, ReadOutcomeAntennaUnassigned, ReadOutcomeSeatEmpty:
+		return Feedback{LED: "yellow", Beep: "long"}
+	case ReadOutcomeCardFlagged:
+		return Feedback{LED: "yellow", Beep: "short"}
+	default:
+		// misdeal or error
+		return Feedback{LED: "red", Beep: "long"}
+	}
+}
+
+// Message returns a human readable description of the outcome
+func (o ReadOutcome) Message() string {
+	switch o {
+	case ReadOutcomeAccepted:
+		return "success to receive card"
+	case ReadOutcomeDuplicate:
+		return "card is already received"
+	case ReadOutcomeUnconfirmed:
+		return "card is not confirmed yet"
+	case ReadOutcomeHandComplete:
+		return "hand is complete"
+	case ReadOutcomeHandFull:
+		return "player already has two cards"
+	case ReadOutcomeCardInPlay:
+		return "card is already in play at another antenna"
+	case ReadOutcomeCardFlagged:
+		return "card is already in play at another antenna, floor is notified"
+	case ReadOutcomeBoardFull:
+		return "board already has five cards"
+	case ReadOutcomeHandInProgress:
+		return "hand has not ended"
+	case ReadOutcomeUnknownUID:
+		return "unknown card uid"
+	case ReadOutcomeAntennaUnassigned:
+		return "antenna type is not assigned"
+	case ReadOutcomeCheckedIn:
+		return "player is checked in"
+	case ReadOutcomeSeatInPlay:
+		return "seat has cards in play"
+	case ReadOutcomeSeatEmpty:
+		return "no player is seated at the antenna"
+	default:
+		return "failed to process card"
+	}
+}
+
+// PostCardResponse is the response to a card read
+type PostCardResponse struct {
+	Result   ReadOutcome `json:"result"`
+	Message  string      `json:"message"`
+	Feedback Feedback    `json:"feedback"`
+}
+
+func newPostCardResponse(outcome ReadOutcome) PostCardResponse {
+	return PostCardResponse{
+		Result:   outcome,
+		Message:  outcome.Message(),
+		Feedback: outcome.Feedback(),
+	}
+}
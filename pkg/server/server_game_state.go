@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+// Policies of when the game ends
+const (
+	GameEndPolicyTimeout       = "timeout"
+	GameEndPolicyOnePlayerLeft = "one_player_left"
+	GameEndPolicyRiverComplete = "river_complete"
+	GameEndPolicyBoardRemoved  = "board_removed"
+	GameEndPolicyManual        = "manual"
+)
+
+// gameEndPolicy returns the policy in effect, EndGameOnBoardCleared is kept for compatibility
+func gameEndPolicy(cc config.Config) string {
+	if cc.EndGameOnBoardCleared {
+		return GameEndPolicyBoardRemoved
+	}
+	switch cc.GameEndPolicy {
+	case GameEndPolicyOnePlayerLeft, GameEndPolicyRiverComplete, GameEndPolicyBoardRemoved, GameEndPolicyManual:
+		return cc.GameEndPolicy
+	default:
+		return GameEndPolicyTimeout
+	}
+}
+
+// advanceGameState moves the current game forward to the state its cards have reached, and ends the game by the policy.
+// The caller must hold ingestMu.
+func advanceGameState(ctx context.Context, t *tableTx, cc config.Config) error {
+	q := t.q
+	progress, err := store.GetGameProgress(ctx, q)
+	if err != nil {
+		return fmt.Errorf("store.GetGameProgress(): %w", err)
+	}
+	if progress == nil {
+		return nil
+	}
+
+	game := progress.Game
+	changed := false
+	for target := progress.DealtState(); store.GameState(game.State).Before(target); {
+		next := store.GameState(game.State).Next()
+		if err := store.TransitionGameState(ctx, q, game, next); err != nil {
+			return fmt.Errorf("store.TransitionGameState(): %w", err)
+		}
+		game.State = string(next)
+		changed = true
+	}
+
+	switch gameEndPolicy(cc) {
+	case GameEndPolicyOnePlayerLeft:
+		if progress.OnePlayerLeft() {
+			return endGame(ctx, t, GameEndPolicyOnePlayerLeft)
+		}
+	case GameEndPolicyRiverComplete:
+		if store.GameState(game.State) == store.GameStateRiver {
+			if err := store.TransitionGameState(ctx, q, game, store.GameStateShowdown); err != nil {
+				return fmt.Errorf("store.TransitionGameState(): %w", err)
+			}
+			game.State = string(store.GameStateShowdown)
+			changed = true
+		}
+		if store.GameState(game.State) == store.GameStateShowdown {
+			// scheduled again after restarting the server
+			scheduleGameEnd(t.conn, game.ID, time.Duration(cc.GameEndDelaySeconds)*time.Second)
+		}
+	}
+
+	if changed {
+		t.notify()
+	}
+	return nil
+}
+
+// endGame finishes the current game, hands are archived and the table is cleared for the next game.
+// The finished game is kept, deleteGame deletes it.
+func endGame(ctx context.Context, t *tableTx, reason string) error {
+	return closeGame(ctx, t, reason, store.FinishCurrentGame)
+}
+
+// deleteGame is the same as endGame, but the game is deleted
+func deleteGame(ctx context.Context, t *tableTx, reason string) error {
+	return closeGame(ctx, t, reason, store.ClearGame)
+}
+
+// closeGame ends the current game by clear in t, the game is never left half finished
+func closeGame(ctx context.Context, t *tableTx, reason string, clear func(context.Context, *query.Queries) error) error {
+	q := t.q
+
+	game, err := q.GetCurrentGame(ctx)
+	switch {
+	case err == nil && store.GameState(game.State) == store.GameStateRiver:
+		// the hand reached the showdown
+		if err := store.TransitionGameState(ctx, q, game, store.GameStateShowdown); err != nil {
+			return fmt.Errorf("store.TransitionGameState(): %w", err)
+		}
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+
+	if err := clear(ctx, q); err != nil {
+		return fmt.Errorf("clear(): %w", err)
+	}
+	t.gameEnded()
+	t.notify()
+
+	slog.InfoContext(ctx, "game ended", "event", "game_ended", "reason", reason)
+	return nil
+}
+
+// pendingGameEnd is a game end scheduled by "river_complete"
+var pendingGameEnd struct {
+	mu     sync.Mutex
+	gameID string
+	timer  *time.Timer
+}
+
+func scheduleGameEnd(conn *sql.DB, gameID string, delay time.Duration) {
+	pendingGameEnd.mu.Lock()
+	defer pendingGameEnd.mu.Unlock()
+
+	if pendingGameEnd.timer != nil && pendingGameEnd.gameID == gameID {
+		return
+	}
+	if pendingGameEnd.timer != nil {
+		pendingGameEnd.timer.Stop()
+	}
+
+	pendingGameEnd.gameID = gameID
+	pendingGameEnd.timer = time.AfterFunc(delay, func() {
+		ctx := context.Background()
+		ingestMu.Lock()
+		defer ingestMu.Unlock()
+
+		pendingGameEnd.mu.Lock()
+		if pendingGameEnd.gameID == gameID {
+			pendingGameEnd.timer = nil
+			pendingGameEnd.gameID = ""
+		}
+		pendingGameEnd.mu.Unlock()
+
+		game, err := query.New(conn).GetCurrentGame(ctx)
+		if err != nil || game.ID != gameID {
+			// already ended
+			return
+		}
+		if game.PausedAt.Valid {
+			// scheduled again on resume
+			return
+		}
+		if err := runTableTx(ctx, conn, func(t *tableTx) error {
+			return endGame(ctx, t, GameEndPolicyRiverComplete)
+		}); err != nil {
+			slog.WarnContext(ctx, "failed to end game", "game_id", gameID, "error", err)
+		}
+	})
+}
+
+func cancelGameEnd() {
+	pendingGameEnd.mu.Lock()
+	defer pendingGameEnd.mu.Unlock()
+
+	if pendingGameEnd.timer != nil {
+		pendingGameEnd.timer.Stop()
+		pendingGameEnd.timer = nil
+		pendingGameEnd.gameID = ""
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/whywaita/poker-go"
 	"github.com/whywaita/rfid-poker/pkg/config"
@@ -23,6 +26,7 @@ type PostCardRequest struct {
 	UID      string `json:"uid"`
 	DeviceID string `json:"device_id"`
 	PairID   int    `json:"pair_id"`
+	Event    string `json:"event"` // detected (default) or removed
 }
 
 func HandleCards(c echo.Context, conn *sql.DB) error {
@@ -34,60 +38,199 @@ func HandleCards(c echo.Context, conn *sql.DB) error {
 		logger.WarnContext(c.Request().Context(), "invalid request body", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
+	if !isValidCardEvent(input.Event) {
+		logger.WarnContext(c.Request().Context(), "invalid event", "event", input.Event)
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid event: %s", input.Event))
+	}
+
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	var outcome ReadOutcome
+	if err := runTableTx(c.Request().Context(), conn, func(t *tableTx) error {
+		var err error
+		outcome, err = receiveCard(c.Request().Context(), t, config.Conf, input)
+		return err
+	}); err != nil {
+		logger.WarnContext(c.Request().Context(), "failed to receive card", "error", err)
+		return c.JSON(http.StatusInternalServerError, newPostCardResponse(ReadOutcomeError))
+	}
+
+	return c.JSON(http.StatusOK, newPostCardResponse(outcome))
+}
+
+// ingestMu serializes card reads, so a batch from one device is applied without interleaving with other reads
+var ingestMu sync.Mutex
+
+// tableTx is a transaction of changes to the table, clients are notified after it is committed
+type tableTx struct {
+	q    *query.Queries
+	conn *sql.DB
+
+	changed bool
+	equity  bool
+	ended   bool
+	present map[string]map[poker.Card]time.Time // antennas with cards put or lifted in the transaction
+}
+
+// notify notifies clients of the change after the commit
+func (t *tableTx) notify() {
+	t.changed = true
+}
+
+// calcEquity calculates equities of hands after the commit, and notifies clients
+func (t *tableTx) calcEquity() {
+	t.changed = true
+	t.equity = true
+}
+
+// gameEnded cancels the game end scheduled by "river_complete" after the commit
+func (t *tableTx) gameEnded() {
+	t.ended = true
+}
+
+// runTableTx runs apply in a transaction, nothing is applied if apply returns an error
+func runTableTx(ctx context.Context, conn *sql.DB, apply func(t *tableTx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("conn.BeginTx(): %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil || err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	t := &tableTx{q: query.New(tx), conn: conn}
+	if err = apply(t); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit(): %w", err)
+	}
+
+	if t.ended {
+		cancelGameEnd()
+	}
+	if t.present != nil {
+		presence.apply(t.present)
+	}
+	if t.changed {
+		notifyClients()
+	}
+	if t.equity {
+		go func() {
+			if err := store.CalcEquity(context.Background(), query.New(conn)); err != nil {
+				slog.WarnContext(context.Background(), "calcEquity", "error", err)
+			}
+			notifyClients()
+		}()
+	}
+	return nil
+}
+
+// receiveCard registers the antenna if needed and processes a card read in t.
+// The caller must hold ingestMu.
+func receiveCard(ctx context.Context, t *tableTx, cc config.Config, input PostCardRequest) (ReadOutcome, error) {
+	logger := slog.With("method", "receiveCard")
 
 	uid := strings.ReplaceAll(input.UID, " ", "")
 	logger = logger.With("device_id", input.DeviceID, "pair_id", input.PairID, "uid", input.UID)
 
+	if input.Event == CardEventRemoved {
+		reads.reset(input.DeviceID, input.PairID, uid)
+	} else if ok, outcome := reads.allow(cc, input.DeviceID, input.PairID, uid, time.Now()); !ok {
+		logger.DebugContext(ctx, "read is filtered", "outcome", outcome)
+		return outcome, nil
+	}
+
+	// forget the read on failure, so the device can retry it
+	fail := func(msg string) (ReadOutcome, error) {
+		reads.reset(input.DeviceID, input.PairID, uid)
+		return ReadOutcomeError, errors.New(msg)
+	}
+
 	// First, check if this device_id corresponds to a board antenna
 	// Board antennas should be treated as one board regardless of pair_id
-	boardAntenna, boardErr := store.GetBoardAntennaByDeviceID(c.Request().Context(), conn, input.DeviceID)
+	boardAntenna, boardErr := store.GetBoardAntennaByDeviceID(ctx, t.q, input.DeviceID)
 	if boardErr == nil {
 		// This is a board device, use the existing board antenna
 		// Don't register as a new device, just proceed with processing
-		logger.InfoContext(c.Request().Context(), "using existing board antenna", "serial", boardAntenna.Serial)
+		logger.InfoContext(ctx, "using existing board antenna", "serial", boardAntenna.Serial)
 	} else if errors.Is(boardErr, sql.ErrNoRows) {
 		// Not a board device, check if antenna exists with device_id-pair_id
-		_, err := store.GetAntennaBySerial(c.Request().Context(), conn, input.DeviceID, input.PairID)
+		_, err := store.GetAntennaBySerial(ctx, t.q, input.DeviceID, input.PairID)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				// Register as new device
-				if err := store.RegisterNewDevice(c.Request().Context(), conn, input.DeviceID, input.PairID); err != nil {
-					logger.WarnContext(c.Request().Context(), "failed to register new device", "error", err)
-					return echo.NewHTTPError(http.StatusInternalServerError, "failed to register new device")
+				if err := store.RegisterNewDevice(ctx, t.q, input.DeviceID, input.PairID); err != nil {
+					logger.WarnContext(ctx, "failed to register new device", "error", err)
+					return fail("failed to register new device")
 				}
+				applySeatNumber(ctx, t.q, cc, store.ToSerial(input.DeviceID, input.PairID))
 			} else {
-				logger.WarnContext(c.Request().Context(), "failed to get antenna", "error", err)
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to get antenna")
+				logger.WarnContext(ctx, "failed to get antenna", "error", err)
+				return fail("failed to get antenna")
 			}
 		}
 	} else {
-		logger.WarnContext(c.Request().Context(), "failed to check board antenna", "error", boardErr)
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check board antenna")
+		logger.WarnContext(ctx, "failed to check board antenna", "error", boardErr)
+		return fail("failed to check board antenna")
+	}
+
+	if input.Event == CardEventRemoved {
+		if _, err := playercards.LoadPlayerCard(uid, cc.CardIDs); err != nil {
+			// e.g. a membership card, only playing cards are tracked on antennas
+			logger.WarnContext(ctx, "unknown uid")
+			return ReadOutcomeUnknownUID, nil
+		}
+		if err := processCardRemoved(ctx, t, cc, uid, input.DeviceID, input.PairID); err != nil {
+			logger.WarnContext(ctx, "failed to process removed card", "error", err)
+			return fail("failed to process removed card")
+		}
+		if err := advanceGameState(ctx, t, cc); err != nil {
+			logger.WarnContext(ctx, "failed to advance game state", "error", err)
+		}
+		return ReadOutcomeAccepted, nil
 	}
 
-	if err := processCard(c.Request().Context(), conn, config.Conf, uid, input.DeviceID, input.PairID); err != nil {
-		logger.WarnContext(c.Request().Context(), "failed to process card", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to process card")
+	outcome, err := processCard(ctx, t, cc, uid, input.DeviceID, input.PairID)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to process card", "error", err)
+		return fail("failed to process card")
+	}
+	if err := advanceGameState(ctx, t, cc); err != nil {
+		logger.WarnContext(ctx, "failed to advance game state", "error", err)
 	}
 
-	return c.JSON(http.StatusOK, "success to receive card")
+	return outcome, nil
 }
 
-func processCard(ctx context.Context, conn *sql.DB, cc config.Config, uid string, deviceID string, pairID int) error {
+func isValidCardEvent(event string) bool {
+	switch event {
+	case "", CardEventDetected, CardEventRemoved:
+		return true
+	default:
+		return false
+	}
+}
+
+func processCard(ctx context.Context, t *tableTx, cc config.Config, uid string, deviceID string, pairID int) (ReadOutcome, error) {
 	logger := slog.With("method", "processCard")
 	pcard, err := playercards.LoadPlayerCard(uid, cc.CardIDs)
 	if err != nil {
-		return fmt.Errorf("playercards.LoadPlayerCard(%s, cardConfigs): %w", uid, err)
+		// not a playing card, may be a membership card to check in
+		return checkIn(ctx, t, uid, store.ToSerial(deviceID, pairID))
 	}
 	card, err := playercards.UnmarshalPlayerCard(pcard)
 	if err != nil {
-		return fmt.Errorf("playercards.UnmarshalPlayerCard(%s): %w", pcard, err)
+		return ReadOutcomeError, fmt.Errorf("playercards.UnmarshalPlayerCard(%s): %w", pcard, err)
 	}
 
 	// Check if this device_id corresponds to a board antenna
 	// If so, use the board antenna's serial instead of device_id-pair_id
 	var serial string
-	boardAntenna, boardErr := store.GetBoardAntennaByDeviceID(ctx, conn, deviceID)
+	boardAntenna, boardErr := store.GetBoardAntennaByDeviceID(ctx, t.q, deviceID)
 	if boardErr == nil {
 		// This is a board device, use the board antenna's serial
 		serial = boardAntenna.Serial
@@ -96,150 +239,184 @@ func processCard(ctx context.Context, conn *sql.DB, cc config.Config, uid string
 		// Not a board device, use device_id-pair_id as serial
 		serial = store.ToSerial(deviceID, pairID)
 	}
+	t.addPresence(serial, card)
 
-	tx, err := conn.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("conn.BeginTx(): %w", err)
-	}
-	defer func() {
-		if r := recover(); r != nil || err != nil {
-			tx.Rollback()
-		}
-	}()
-
-	qWithTx := query.New(tx)
-
-	antenna, err := qWithTx.GetAntennaBySerial(ctx, serial)
+	antenna, err := t.q.GetAntennaBySerial(ctx, serial)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("query.GetAntennaBySerial(): %w", err)
+		return ReadOutcomeError, fmt.Errorf("query.GetAntennaBySerial(): %w", err)
 	}
 
 	// if unknown, register new player
 	if strings.EqualFold(antenna.AntennaTypeName, "unknown") {
-		resultPlayer, err := qWithTx.AddPlayer(ctx, fmt.Sprintf("player-%s-%d", deviceID, pairID))
+		resultPlayer, err := t.q.AddPlayer(ctx, fmt.Sprintf("player-%s-%d", deviceID, pairID))
 		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("query.AddPlayer(): %w", err)
+			return ReadOutcomeError, fmt.Errorf("query.AddPlayer(): %w", err)
 		}
 		playerID, err := resultPlayer.LastInsertId()
 		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("resultPlayer.LastInsertId(): %w", err)
+			return ReadOutcomeError, fmt.Errorf("resultPlayer.LastInsertId(): %w", err)
 		}
-		if err := qWithTx.SetPlayerIDToAntennaBySerial(ctx, query.SetPlayerIDToAntennaBySerialParams{
+		if err := t.q.SetPlayerIDToAntennaBySerial(ctx, query.SetPlayerIDToAntennaBySerialParams{
 			PlayerID: sql.NullInt32{Int32: int32(playerID), Valid: true},
 			Serial:   serial,
 		}); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("query.SetPlayerIDToAntennaBySerial(): %w", err)
+			return ReadOutcomeError, fmt.Errorf("query.SetPlayerIDToAntennaBySerial(): %w", err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("tx.Commit(): %w", err)
-	}
-
 	// Get the antenna again using the same logic as above
 	var newAntenna *query.GetAntennaBySerialRow
 	if boardErr == nil {
 		// Board antenna - get directly by serial
-		q := query.New(conn)
-		antenna, err := q.GetAntennaBySerial(ctx, serial)
+		antenna, err := t.q.GetAntennaBySerial(ctx, serial)
 		if err != nil {
-			return fmt.Errorf("query.GetAntennaBySerial(): %w", err)
+			return ReadOutcomeError, fmt.Errorf("query.GetAntennaBySerial(): %w", err)
 		}
 		newAntenna = &antenna
 	} else {
 		// Regular antenna - use the helper function
-		newAntenna, err = store.GetAntennaBySerial(ctx, conn, deviceID, pairID)
+		newAntenna, err = store.GetAntennaBySerial(ctx, t.q, deviceID, pairID)
+		if err != nil {
+			return ReadOutcomeError, fmt.Errorf("store.GetAntennaBySerial(): %w", err)
+		}
+	}
+
+	if store.GetAntennaType(newAntenna.AntennaTypeName) == store.AntennaTypePlayer && !newAntenna.PlayerID.Valid {
+		// the seat is left empty by the admin, a player must check in first
+		logger.WarnContext(ctx, "no player is seated, rejecting card", "serial", serial)
+		return ReadOutcomeSeatEmpty, nil
+	}
+
+	if store.GetAntennaType(newAntenna.AntennaTypeName) != store.AntennaTypeUnknown {
+		stop, outcome, err := checkDuplicateCard(ctx, t, cc, card, serial, newAntenna.AntennaTypeName)
 		if err != nil {
-			return fmt.Errorf("store.GetAntennaBySerial(): %w", err)
+			return ReadOutcomeError, fmt.Errorf("checkDuplicateCard(): %w", err)
+		}
+		if stop {
+			return outcome, nil
 		}
 	}
 
+	outcome := ReadOutcomeAccepted
 	switch newAntenna.AntennaTypeName {
 	case "player":
-		storedCards, err := store.GetCardBySerial(ctx, conn, serial)
+		storedCards, err := store.GetCardBySerial(ctx, t.q, serial)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("store.GetCardBySerial(): %w", err)
+			return ReadOutcomeError, fmt.Errorf("store.GetCardBySerial(): %w", err)
 		}
 
 		switch {
+		case slices.Contains(storedCards, card):
+			// if same card, do nothing
+			return ReadOutcomeDuplicate, nil
 		case len(storedCards) == 0:
-			if err := store.AddCard(ctx, conn, card, serial); err != nil {
-				return fmt.Errorf("store.AddCard(): %w", err)
+			if err := store.AddCard(ctx, t.q, card, serial); err != nil {
+				if errors.Is(err, store.ErrCardInPlay) {
+					return ReadOutcomeCardInPlay, nil
+				}
+				return ReadOutcomeError, fmt.Errorf("store.AddCard(): %w", err)
 			}
+			t.notify()
 		case len(storedCards) == 1:
-			// if same card, do nothing
-			if storedCards[0].Rank == card.Rank && storedCards[0].Suit == card.Suit {
-				return nil
-			}
-
-			if err := store.AddHand(ctx, conn, []poker.Card{storedCards[0], card}, serial); err != nil {
-				return fmt.Errorf("store.AddHand(): %w", err)
+			if err := store.AddHand(ctx, t.q, []poker.Card{storedCards[0], card}, serial); err != nil {
+				return ReadOutcomeError, fmt.Errorf("store.AddHand(): %w", err)
 			}
-			notifyClients()
-
-			go func() {
-				if err := store.CalcEquity(context.Background(), query.New(conn)); err != nil {
-					logger.WarnContext(context.Background(), "calcEquity", "error", err)
-				}
-				notifyClients()
-			}()
+			outcome = ReadOutcomeHandComplete
+			t.calcEquity()
+		default:
+			logger.WarnContext(ctx, "player already has a hand, rejecting card",
+				"serial", serial,
+				"card", fmt.Sprintf("%s%s", card.Rank.String(), card.Suit.String()))
+			return ReadOutcomeHandFull, nil
 		}
 	case "muck":
-		storedCards, err := store.GetCardBySerial(ctx, conn, serial)
-		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("store.GetCardBySerial(): %w", err)
+		location, err := store.GetCardLocation(ctx, t.q, card)
+		if err != nil {
+			return ReadOutcomeError, fmt.Errorf("store.GetCardLocation(): %w", err)
 		}
 		switch {
-		case len(storedCards) == 0:
-			if err := store.AddCard(ctx, conn, card, serial); err != nil {
-				return fmt.Errorf("store.AddCard(): %w", err)
+		case location == nil:
+			// not dealt to anyone, e.g. exposed card
+			if err := store.AddCard(ctx, t.q, card, serial); err != nil {
+				if errors.Is(err, store.ErrCardInPlay) {
+					return ReadOutcomeCardInPlay, nil
+				}
+				return ReadOutcomeError, fmt.Errorf("store.AddCard(): %w", err)
 			}
-		case len(storedCards) == 1 && storedCards[0].Rank != card.Rank && storedCards[0].Suit != card.Suit: // not same card
-			if err := store.MuckPlayer(ctx, conn, []poker.Card{storedCards[0], card}); err != nil {
-				return fmt.Errorf("store.MuckPlayer(): %w", err)
+			t.notify()
+		case isHandCardToMuck(newAntenna.AntennaTypeName, location) && !location.IsMuck.Bool:
+			// one card is enough to know the hand
+			if err := store.MuckPlayer(ctx, t.q, []poker.Card{card}); err != nil {
+				return ReadOutcomeError, fmt.Errorf("store.MuckPlayer(): %w", err)
 			}
-			notifyClients()
-
-			go func() {
-				if err := store.CalcEquity(context.Background(), query.New(conn)); err != nil {
-					logger.WarnContext(context.Background(), "calcEquity", "error", err)
-				}
-				notifyClients()
-			}()
+			t.calcEquity()
+		default:
+			// already mucked
+			outcome = ReadOutcomeDuplicate
 		}
 	case "board":
 		// Send anyway if board
-		isUpdated, err := store.AddBoard(ctx, conn, []poker.Card{card}, serial)
+		isUpdated, err := store.AddBoard(ctx, t.q, []poker.Card{card}, serial)
 		if err != nil {
 			if errors.Is(err, store.ErrBoardCardLimitExceeded) {
 				// Board card limit exceeded, reject the request without saving
 				logger.WarnContext(ctx, "board card limit exceeded, rejecting card",
 					"serial", serial,
 					"card", fmt.Sprintf("%s%s", card.Rank.String(), card.Suit.String()))
-				return nil // Don't return error to avoid 500, just ignore the card
+				return ReadOutcomeBoardFull, nil // Don't return error to avoid 500, just ignore the card
+			}
+			if errors.Is(err, store.ErrCardInPlay) {
+				return ReadOutcomeCardInPlay, nil
 			}
-			return fmt.Errorf("store.AddBoard(): %w", err)
+			return ReadOutcomeError, fmt.Errorf("store.AddBoard(): %w", err)
 		}
-		notifyClients()
-
-		go func(isUpdated bool) {
-			if isUpdated {
-				if err := store.CalcEquity(context.Background(), query.New(conn)); err != nil {
-					logger.WarnContext(context.Background(), "calcEquity", "error", err)
-				}
-				notifyClients()
+		if isUpdated {
+			t.calcEquity()
+		} else {
+			outcome = ReadOutcomeDuplicate
+			t.notify()
+		}
+	case "burn":
+		// a burned card must not appear again in the game, checkDuplicateCard alerts it
+		if err := store.AddCard(ctx, t.q, card, serial); err != nil {
+			if errors.Is(err, store.ErrCardInPlay) {
+				return ReadOutcomeDuplicate, nil
+			}
+			return ReadOutcomeError, fmt.Errorf("store.AddCard(): %w", err)
+		}
+		t.notify()
+	case "rabbit":
+		if err := store.AddRabbit(ctx, t.q, card, serial); err != nil {
+			if errors.Is(err, store.ErrBoardCardLimitExceeded) {
+				logger.WarnContext(ctx, "board is already complete, rejecting rabbit card",
+					"serial", serial,
+					"card", fmt.Sprintf("%s%s", card.Rank.String(), card.Suit.String()))
+				return ReadOutcomeBoardFull, nil
+			}
+			if errors.Is(err, store.ErrHandInProgress) {
+				logger.WarnContext(ctx, "hand has not ended, rejecting rabbit card",
+					"serial", serial,
+					"card", fmt.Sprintf("%s%s", card.Rank.String(), card.Suit.String()))
+				return ReadOutcomeHandInProgress, nil
 			}
-		}(isUpdated)
+			if errors.Is(err, store.ErrCardInPlay) {
+				return ReadOutcomeDuplicate, nil
+			}
+			return ReadOutcomeError, fmt.Errorf("store.AddRabbit(): %w", err)
+		}
+		// rabbit cards are shown, but not used for equity
+		t.notify()
 	case "unknown":
 		logger.WarnContext(ctx, "unknown type antenna", "serial", serial)
+		outcome = ReadOutcomeAntennaUnassigned
 	}
 
 	// Update the last card read time for timeout detection
-	updateLastCardReadTime(newAntenna.AntennaTypeName)
+	if outcome != ReadOutcomeAntennaUnassigned {
+		if err := updateLastCardReadTime(ctx, t, newAntenna.AntennaTypeName); err != nil {
+			logger.WarnContext(ctx, "failed to update last card read time", "error", err)
+		}
+	}
 
-	return nil
+	return outcome, nil
 }
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// cardReadRetention is how long idempotency keys are kept for retried requests
+const cardReadRetention = 24 * time.Hour
+
+const (
+	CardReadStatusAccepted  = "accepted"
+	CardReadStatusDuplicate = "duplicate"
+)
+
+type PostCardsRequest struct {
+	DeviceID string          `json:"device_id"`
+	Reads    []PostCardsRead `json:"reads"`
+}
+
+// PostCardsRead is a single card read in a batch.
+// IdempotencyKey is required and must be unique per device (e.g. "<boot id>-<seq>"), a read with an already processed key is not applied again.
+type PostCardsRead struct {
+	Seq            int64  `json:"seq"`
+	IdempotencyKey string `json:"idempotency_key"`
+	UID            string `json:"uid"`
+	PairID         int    `json:"pair_id"`
+	Event          string `json:"event"` // detected (default) or removed
+}
+
+type PostCardsResponse struct {
+	Results []PostCardsResult `json:"results"`
+}
+
+type PostCardsResult struct {
+	Seq            int64  `json:"seq"`
+	IdempotencyKey string `json:"idempotency_key"`
+	Status         string `json:"status"`
+
+	PostCardResponse
+}
+
+// HandleBatchCards handle several card reads from one device in a single request
+func HandleBatchCards(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleBatchCards")
+	ctx := c.Request().Context()
+	defer c.Request().Body.Close()
+
+	input := PostCardsRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&input); err != nil {
+		logger.WarnContext(ctx, "invalid request body", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	logger = logger.With("device_id", input.DeviceID)
+
+	if err := validateBatchCards(input); err != nil {
+		logger.WarnContext(ctx, "invalid batch", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	resp, err := receiveBatchCards(ctx, conn, config.Conf, input)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to receive cards", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to receive cards"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// receiveBatchCards applies reads of a validated batch in seq order in one transaction.
+// Reads and their idempotency keys are stored together, nothing is applied if any read fails, so the device can retry the whole batch.
+func receiveBatchCards(ctx context.Context, conn *sql.DB, cc config.Config, input PostCardsRequest) (*PostCardsResponse, error) {
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	if err := query.New(conn).DeleteCardReadCreatedBefore(ctx, time.Now().Add(-cardReadRetention)); err != nil {
+		slog.WarnContext(ctx, "failed to delete old card reads", "error", err)
+	}
+
+	sorted := make([]PostCardsRead, len(input.Reads))
+	copy(sorted, input.Reads)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Seq < sorted[j].Seq
+	})
+
+	resp := &PostCardsResponse{Results: make([]PostCardsResult, 0, len(sorted))}
+	if err := runTableTx(ctx, conn, func(t *tableTx) error {
+		for _, read := range sorted {
+			result, err := receiveBatchCard(ctx, t, cc, input.DeviceID, read)
+			if err != nil {
+				return fmt.Errorf("receiveBatchCard(seq: %d): %w", read.Seq, err)
+			}
+			resp.Results = append(resp.Results, *result)
+		}
+		return nil
+	}); err != nil {
+		// the batch is rolled back, forget all reads so the retry is not filtered
+		for _, read := range sorted {
+			reads.reset(input.DeviceID, read.PairID, strings.ReplaceAll(read.UID, " ", ""))
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// validateBatchCards rejects the whole batch before anything is applied.
+// An unknown UID is not checked here, it is answered as unknown_uid per read.
+func validateBatchCards(input PostCardsRequest) error {
+	if input.DeviceID == "" {
+		return errors.New("device_id is required")
+	}
+	if len(input.Reads) == 0 {
+		return errors.New("reads is required")
+	}
+
+	keys := make(map[string]struct{}, len(input.Reads))
+	for _, read := range input.Reads {
+		if read.IdempotencyKey == "" {
+			return fmt.Errorf("idempotency_key is required (seq: %d)", read.Seq)
+		}
+		if _, ok := keys[read.IdempotencyKey]; ok {
+			return fmt.Errorf("duplicated idempotency_key in request: %s", read.IdempotencyKey)
+		}
+		keys[read.IdempotencyKey] = struct{}{}
+
+		if !isValidCardEvent(read.Event) {
+			return fmt.Errorf("invalid event (seq: %d, event: %s)", read.Seq, read.Event)
+		}
+	}
+
+	return nil
+}
+
+// receiveBatchCard process a read in a batch, or return the stored result if the read is already processed.
+// The caller must hold ingestMu.
+func receiveBatchCard(ctx context.Context, t *tableTx, cc config.Config, deviceID string, read PostCardsRead) (*PostCardsResult, error) {
+	stored, err := t.q.GetCardReadByIdempotencyKey(ctx, query.GetCardReadByIdempotencyKeyParams{
+		DeviceID:       deviceID,
+		IdempotencyKey: read.IdempotencyKey,
+	})
+	switch {
+	case err == nil:
+		var result PostCardsResult
+		if err := json.Unmarshal([]byte(stored.Response), &result); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+		}
+		result.Seq = read.Seq
+		result.Status = CardReadStatusDuplicate
+		return &result, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("q.GetCardReadByIdempotencyKey(): %w", err)
+	}
+
+	outcome, err := receiveCard(ctx, t, cc, PostCardRequest{
+		UID:      read.UID,
+		DeviceID: deviceID,
+		PairID:   read.PairID,
+		Event:    read.Event,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("receiveCard(): %w", err)
+	}
+	result := &PostCardsResult{
+		Seq:              read.Seq,
+		IdempotencyKey:   read.IdempotencyKey,
+		Status:           CardReadStatusAccepted,
+		PostCardResponse: newPostCardResponse(outcome),
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(): %w", err)
+	}
+	if err := t.q.AddCardRead(ctx, query.AddCardReadParams{
+		DeviceID:       deviceID,
+		IdempotencyKey: read.IdempotencyKey,
+		Response:       string(b),
+	}); err != nil {
+		return nil, fmt.Errorf("q.AddCardRead(): %w", err)
+	}
+
+	return result, nil
+}
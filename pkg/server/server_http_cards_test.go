@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+)
+
+func TestReceiveBatchCards_StoredKey(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New(): %+v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM card_read")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM card_read")).
+		WithArgs("device-1", "boot-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "device_id", "idempotency_key", "response", "created_at"}).
+			AddRow(1, "device-1", "boot-1", `{"seq":1,"idempotency_key":"boot-1","status":"accepted","result":"hand_complete"}`, time.Now()))
+	mock.ExpectCommit()
+
+	resp, err := receiveBatchCards(context.Background(), conn, config.Config{}, PostCardsRequest{
+		DeviceID: "device-1",
+		Reads:    []PostCardsRead{{Seq: 2, IdempotencyKey: "boot-1", UID: "04 01", PairID: 1}},
+	})
+	if err != nil {
+		t.Fatalf("receiveBatchCards(): %+v", err)
+	}
+
+	if len(resp.Results) != 1 {
+		t.Fatalf("len(results) = %d, want 1", len(resp.Results))
+	}
+	got := resp.Results[0]
+	if got.Status != CardReadStatusDuplicate {
+		t.Errorf("status = %s, want %s", got.Status, CardReadStatusDuplicate)
+	}
+	if got.Seq != 2 {
+		t.Errorf("seq = %d, want 2", got.Seq)
+	}
+	if got.Result != ReadOutcomeHandComplete {
+		t.Errorf("result = %s, want %s (stored result)", got.Result, ReadOutcomeHandComplete)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("read with a stored key must not be applied again: %+v", err)
+	}
+}
+
+func TestReceiveBatchCards_RollbackOnFailure(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New(): %+v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM card_read")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM card_read")).
+		WithArgs("device-2", "boot-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "device_id", "idempotency_key", "response", "created_at"}))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM antenna")).WillReturnError(errors.New("connection lost"))
+	mock.ExpectRollback()
+
+	_, err = receiveBatchCards(context.Background(), conn, config.Config{}, PostCardsRequest{
+		DeviceID: "device-2",
+		Reads:    []PostCardsRead{{Seq: 1, IdempotencyKey: "boot-1", UID: "04 02", PairID: 1}},
+	})
+	if err == nil {
+		t.Fatal("receiveBatchCards() must fail")
+	}
+
+	// the key is not stored, so the retry is applied
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("batch must be rolled back without storing the key: %+v", err)
+	}
+}
+
+func TestValidateBatchCards_KeyRequired(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads []PostCardsRead
+	}{
+		{
+			name:  "missing key",
+			reads: []PostCardsRead{{Seq: 1, UID: "04 01"}},
+		},
+		{
+			name: "duplicated key",
+			reads: []PostCardsRead{
+				{Seq: 1, IdempotencyKey: "boot-1", UID: "04 01"},
+				{Seq: 2, IdempotencyKey: "boot-1", UID: "04 02"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBatchCards(PostCardsRequest{DeviceID: "device-1", Reads: tt.reads})
+			if err == nil {
+				t.Error("validateBatchCards() must reject the batch")
+			}
+		})
+	}
+}
+
+func TestValidateBatchCards_UnknownUID(t *testing.T) {
+	// unknown UIDs are answered per read, the batch is not rejected
+	err := validateBatchCards(PostCardsRequest{
+		DeviceID: "device-1",
+		Reads: []PostCardsRead{
+			{Seq: 1, IdempotencyKey: "boot-1", UID: "unknown"},
+			{Seq: 2, IdempotencyKey: "boot-2", UID: "04 01"},
+		},
+	})
+	if err != nil {
+		t.Errorf("validateBatchCards() = %+v, want nil", err)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -10,12 +11,33 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/whywaita/rfid-poker/pkg/query"
 	"github.com/whywaita/rfid-poker/pkg/store"
 )
 
 type Device struct {
 	DeviceID string `json:"device_id"`
 	PairIDs  []int  `json:"pair_ids"`
+
+	// ClientType and FirmwareVersion are optional, sent by devices that support self-update
+	ClientType      string `json:"client_type,omitempty"`
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+}
+
+// FirmwareOffer is a firmware the device should update to
+type FirmwareOffer struct {
+	ID      int32  `json:"id"`
+	Version string `json:"version"`
+	URL     string `json:"url"` // path of the server, e.g. /device/firmware/1
+	SHA256  string `json:"sha256"`
+	Size    int32  `json:"size"`
+}
+
+// BootResponse is the response to a device that sends client_type
+type BootResponse struct {
+	Message           string         `json:"message"`
+	RegisteredAntenna []string       `json:"registered_antenna"`
+	Firmware          *FirmwareOffer `json:"firmware,omitempty"`
 }
 
 // HandleDeviceBoot handle booting device
@@ -30,28 +52,79 @@ func HandleDeviceBoot(c echo.Context, conn *sql.DB) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
 
-	logger = logger.With("device_id", input.DeviceID)
+	registeredAntenna, err := bootDevice(ctx, conn, input)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to boot device", "device_id", input.DeviceID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if input.ClientType != "" {
+		resp, err := newBootResponse(ctx, conn, input, registeredAntenna)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to check firmware", "device_id", input.DeviceID, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+
+	return c.JSON(http.StatusOK, bootMessage(registeredAntenna))
+}
+
+func bootMessage(registeredAntenna []string) string {
+	if len(registeredAntenna) == 0 {
+		return "already registered antenna, ok"
+	}
+	return fmt.Sprintf("registered antenna: %v", registeredAntenna)
+}
+
+// newBootResponse records the firmware of the device and advertises the desired firmware if the device is outdated
+func newBootResponse(ctx context.Context, conn *sql.DB, input Device, registeredAntenna []string) (*BootResponse, error) {
+	resp := &BootResponse{
+		Message:           bootMessage(registeredAntenna),
+		RegisteredAntenna: registeredAntenna,
+	}
+	if resp.RegisteredAntenna == nil {
+		resp.RegisteredAntenna = []string{}
+	}
 
+	firmware, err := store.CheckFirmwareUpdate(ctx, conn, input.DeviceID, input.ClientType, input.FirmwareVersion)
+	if err != nil {
+		return nil, fmt.Errorf("store.CheckFirmwareUpdate(): %w", err)
+	}
+	if firmware != nil {
+		resp.Firmware = &FirmwareOffer{
+			ID:      firmware.ID,
+			Version: firmware.Version,
+			URL:     fmt.Sprintf("/device/firmware/%d", firmware.ID),
+			SHA256:  firmware.Sha256,
+			Size:    firmware.Size,
+		}
+	}
+
+	return resp, nil
+}
+
+// bootDevice registers antennas of the device that are not registered yet, and returns serials of registered antennas
+func bootDevice(ctx context.Context, conn *sql.DB, input Device) ([]string, error) {
+	logger := slog.With("method", "bootDevice", "device_id", input.DeviceID)
+
+	q := query.New(conn)
 	var registeredAntenna []string
 	for _, pairID := range input.PairIDs {
 		logger = logger.With("pair_id", pairID)
-		_, err := store.GetAntennaBySerial(ctx, conn, input.DeviceID, pairID)
+		_, err := store.GetAntennaBySerial(ctx, q, input.DeviceID, pairID)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			logger.WarnContext(ctx, "failed to get antenna", "error", err)
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get antenna")
+			return nil, errors.New("failed to get antenna")
 		}
 		if errors.Is(err, sql.ErrNoRows) {
-			err := store.RegisterNewDevice(ctx, conn, input.DeviceID, pairID)
+			err := store.RegisterNewDevice(ctx, q, input.DeviceID, pairID)
 			if err != nil {
 				logger.WarnContext(ctx, "failed to register new antenna", "error", err)
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to register new antenna")
+				return nil, errors.New("failed to register new antenna")
 			}
 			registeredAntenna = append(registeredAntenna, store.ToSerial(input.DeviceID, pairID))
 		}
 	}
-	if len(registeredAntenna) == 0 {
-		return c.JSON(http.StatusOK, "already registered antenna, ok")
-	}
 
-	return c.JSON(http.StatusOK, fmt.Sprintf("registered antenna: %v", registeredAntenna))
+	return registeredAntenna, nil
 }
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+)
+
+const (
+	MQTTModeExternal = "external"
+	MQTTModeEmbedded = "embedded"
+)
+
+// Topics under <prefix>/<device_id>/
+const (
+	mqttTopicBoot     = "boot"
+	mqttTopicCard     = "card"
+	mqttTopicCards    = "cards"
+	mqttTopicResponse = "response"
+)
+
+// mqttQueueSize is the number of messages waiting to be processed, messages over it are dropped
+const mqttQueueSize = 256
+
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+// MQTTResponse is published to <prefix>/<device_id>/response after a message from the device is processed
+type MQTTResponse struct {
+	Type   string `json:"type"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// mqttTransport is a connection to an MQTT broker
+type mqttTransport interface {
+	Subscribe(filter string, handler func(topic string, payload []byte)) error
+	Publish(topic string, payload []byte) error
+	Close() error
+}
+
+// startMQTT connects to the broker configured in cc and subscribes topics of devices.
+// It returns nil if MQTT is disabled.
+func startMQTT(ctx context.Context, conn *sql.DB, cc config.Config) (mqttTransport, error) {
+	var transport mqttTransport
+	var err error
+	switch cc.MQTTMode {
+	case "":
+		return nil, nil
+	case MQTTModeExternal:
+		transport, err = newExternalMQTT(cc)
+	case MQTTModeEmbedded:
+		transport, err = newEmbeddedMQTT(cc)
+	default:
+		return nil, fmt.Errorf("unknown MQTT mode: %s", cc.MQTTMode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start MQTT (mode: %s): %w", cc.MQTTMode, err)
+	}
+
+	// messages are processed in order by a worker, the handler of the client must not wait for the database nor a publish
+	messages := make(chan mqttMessage, mqttQueueSize)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-messages:
+				handleMQTTMessage(ctx, conn, transport, cc, msg.topic, msg.payload)
+			}
+		}
+	}()
+
+	for _, kind := range []string{mqttTopicBoot, mqttTopicCard, mqttTopicCards} {
+		filter := fmt.Sprintf("%s/+/%s", cc.MQTTTopicPrefix, kind)
+		if err := transport.Subscribe(filter, func(topic string, payload []byte) {
+			select {
+			case messages <- mqttMessage{topic: topic, payload: payload}:
+			default:
+				slog.WarnContext(ctx, "MQTT message queue is full, dropping message", "topic", topic)
+			}
+		}); err != nil {
+			transport.Close()
+			return nil, fmt.Errorf("failed to subscribe (filter: %s): %w", filter, err)
+		}
+	}
+
+	slog.InfoContext(ctx, "MQTT started", "mode", cc.MQTTMode, "topic_prefix", cc.MQTTTopicPrefix)
+	return transport, nil
+}
+
+// handleMQTTMessage process a message from a device through the same path as the HTTP handlers
+func handleMQTTMessage(ctx context.Context, conn *sql.DB, transport mqttTransport, cc config.Config, topic string, payload []byte) {
+	logger := slog.With("method", "handleMQTTMessage", "topic", topic)
+
+	deviceID, kind, err := parseMQTTTopic(cc.MQTTTopicPrefix, topic)
+	if err != nil {
+		logger.WarnContext(ctx, "invalid topic", "error", err)
+		return
+	}
+	logger = logger.With("device_id", deviceID)
+
+	resp := MQTTResponse{Type: kind}
+	switch kind {
+	case mqttTopicBoot:
+		input := Device{}
+		if err := json.Unmarshal(payload, &input); err != nil {
+			resp.Error = "invalid request body"
+			break
+		}
+		input.DeviceID = deviceID
+		registeredAntenna, err := bootDevice(ctx, conn, input)
+		if err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		if input.ClientType == "" {
+			resp.Result = registeredAntenna
+			break
+		}
+		bootResp, err := newBootResponse(ctx, conn, input, registeredAntenna)
+		if err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		resp.Result = bootResp
+	case mqttTopicCard:
+		input := PostCardRequest{}
+		if err := json.Unmarshal(payload, &input); err != nil {
+			resp.Error = "invalid request body"
+			break
+		}
+		input.DeviceID = deviceID
+		if !isValidCardEvent(input.Event) {
+			resp.Error = fmt.Sprintf("invalid event: %s", input.Event)
+			break
+		}
+		var outcome ReadOutcome
+		ingestMu.Lock()
+		err := runTableTx(ctx, conn, func(t *tableTx) error {
+			var err error
+			outcome, err = receiveCard(ctx, t, cc, input)
+			return err
+		})
+		ingestMu.Unlock()
+		if err != nil {
+			resp.Error = "failed to receive card"
+			outcome = ReadOutcomeError
+			logger.WarnContext(ctx, "failed to receive card", "error", err)
+		}
+		resp.Result = newPostCardResponse(outcome)
+	case mqttTopicCards:
+		input := PostCardsRequest{}
+		if err := json.Unmarshal(payload, &input); err != nil {
+			resp.Error = "invalid request body"
+			break
+		}
+		input.DeviceID = deviceID
+		if err := validateBatchCards(input); err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		result, err := receiveBatchCards(ctx, conn, cc, input)
+		if err != nil {
+			resp.Error = "failed to receive card"
+			logger.WarnContext(ctx, "failed to receive cards", "error", err)
+			break
+		}
+		resp.Result = result
+	}
+	if resp.Error != "" {
+		logger.WarnContext(ctx, "failed to process MQTT message", "type", kind, "error", resp.Error)
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		logger.WarnContext(ctx, "json.Marshal", "error", err)
+		return
+	}
+	if err := transport.Publish(fmt.Sprintf("%s/%s/%s", cc.MQTTTopicPrefix, deviceID, mqttTopicResponse), b); err != nil {
+		logger.WarnContext(ctx, "failed to publish response", "error", err)
+	}
+}
+
+// parseMQTTTopic parses <prefix>/<device_id>/<kind>
+func parseMQTTTopic(prefix, topic string) (string, string, error) {
+	rest, ok := strings.CutPrefix(topic, prefix+"/")
+	if !ok {
+		return "", "", fmt.Errorf("topic does not have prefix %s", prefix)
+	}
+	deviceID, kind, ok := strings.Cut(rest, "/")
+	if !ok || deviceID == "" || strings.Contains(kind, "/") {
+		return "", "", errors.New("invalid topic format")
+	}
+	return deviceID, kind, nil
+}
+
+// externalMQTT is a client of an external broker
+type externalMQTT struct {
+	client paho.Client
+
+	mu            sync.Mutex
+	subscriptions map[string]paho.MessageHandler
+}
+
+func newExternalMQTT(cc config.Config) (*externalMQTT, error) {
+	if cc.MQTTBrokerURL == "" {
+		return nil, errors.New("MQTTBrokerURL is required")
+	}
+
+	m := &externalMQTT{
+		subscriptions: make(map[string]paho.MessageHandler),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cc.MQTTBrokerURL).
+		SetClientID(fmt.Sprintf("rfid-poker-%s", uuid.New().String())).
+		SetUsername(cc.MQTTUsername).
+		SetPassword(cc.MQTTPassword).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(m.resubscribe).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Warn("lost connection to MQTT broker", "error", err)
+		})
+	m.client = paho.NewClient(opts)
+
+	token := m.client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		return nil, errors.New("timeout to connect MQTT broker")
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("client.Connect(): %w", err)
+	}
+
+	return m, nil
+}
+
+// resubscribe subscribes all topics again, subscriptions are lost when the client reconnects
+func (m *externalMQTT) resubscribe(client paho.Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for filter, handler := range m.subscriptions {
+		token := client.Subscribe(filter, 1, handler)
+		if token.Wait() && token.Error() != nil {
+			slog.Warn("failed to resubscribe", "filter", filter, "error", token.Error())
+		}
+	}
+}
+
+func (m *externalMQTT) Subscribe(filter string, handler func(topic string, payload []byte)) error {
+	h := func(_ paho.Client, msg paho.Message) {
+		handler(msg.Topic(), msg.Payload())
+	}
+
+	m.mu.Lock()
+	m.subscriptions[filter] = h
+	m.mu.Unlock()
+
+	token := m.client.Subscribe(filter, 1, h)
+	token.Wait()
+	return token.Error()
+}
+
+func (m *externalMQTT) Publish(topic string, payload []byte) error {
+	token := m.client.Publish(topic, 1, false, payload)
+	token.Wait()
+	return token.Error()
+}
+
+func (m *externalMQTT) Close() error {
+	m.client.Disconnect(250)
+	return nil
+}
+
+// embeddedMQTT is a broker running in this process
+type embeddedMQTT struct {
+	server *mqtt.Server
+
+	mu             sync.Mutex
+	subscriptionID int
+}
+
+func newEmbeddedMQTT(cc config.Config) (*embeddedMQTT, error) {
+	if cc.MQTTUsername == "" && !isLoopbackAddr(cc.MQTTListenAddr) {
+		return nil, fmt.Errorf("MQTTUsername is required to listen on %s, anyone in the network could send card reads", cc.MQTTListenAddr)
+	}
+
+	server := mqtt.New(&mqtt.Options{
+		InlineClient: true,
+		Logger:       slog.Default(),
+	})
+
+	if cc.MQTTUsername != "" {
+		if err := server.AddHook(new(auth.Hook), &auth.Options{
+			Ledger: &auth.Ledger{
+				Auth: auth.AuthRules{
+					{Username: auth.RString(cc.MQTTUsername), Password: auth.RString(cc.MQTTPassword), Allow: true},
+				},
+			},
+		}); err != nil {
+			return nil, fmt.Errorf("server.AddHook(auth.Hook): %w", err)
+		}
+	} else {
+		if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+			return nil, fmt.Errorf("server.AddHook(auth.AllowHook): %w", err)
+		}
+	}
+
+	if err := server.AddListener(listeners.NewTCP(listeners.Config{
+		ID:      "rfid-poker",
+		Address: cc.MQTTListenAddr,
+	})); err != nil {
+		return nil, fmt.Errorf("server.AddListener(): %w", err)
+	}
+
+	if err := server.Serve(); err != nil {
+		return nil, fmt.Errorf("server.Serve(): %w", err)
+	}
+
+	return &embeddedMQTT{server: server}, nil
+}
+
+// isLoopbackAddr returns true if addr (host:port) is only reachable from this host
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (m *embeddedMQTT) Subscribe(filter string, handler func(topic string, payload []byte)) error {
+	m.mu.Lock()
+	m.subscriptionID++
+	id := m.subscriptionID
+	m.mu.Unlock()
+
+	return m.server.Subscribe(filter, id, func(_ *mqtt.Client, _ packets.Subscription, pk packets.Packet) {
+		handler(pk.TopicName, pk.Payload)
+	})
+}
+
+func (m *embeddedMQTT) Publish(topic string, payload []byte) error {
+	return m.server.Publish(topic, payload, false, 1)
+}
+
+func (m *embeddedMQTT) Close() error {
+	return m.server.Close()
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+)
+
+func TestStartMQTT_Embedded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cc := config.Config{
+		MQTTMode:        MQTTModeEmbedded,
+		MQTTListenAddr:  "127.0.0.1:0",
+		MQTTTopicPrefix: "test",
+	}
+	// messages below are rejected before the database is used
+	transport, err := startMQTT(ctx, nil, cc)
+	if err != nil {
+		t.Fatalf("startMQTT(): %+v", err)
+	}
+	defer transport.Close()
+
+	responses := make(chan MQTTResponse, 1)
+	if err := transport.Subscribe("test/device-1/response", func(_ string, payload []byte) {
+		var resp MQTTResponse
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			t.Errorf("json.Unmarshal(): %+v", err)
+		}
+		responses <- resp
+	}); err != nil {
+		t.Fatalf("transport.Subscribe(): %+v", err)
+	}
+
+	tests := []struct {
+		name    string
+		kind    string
+		payload string
+		want    MQTTResponse
+	}{
+		{
+			name:    "invalid event",
+			kind:    mqttTopicCard,
+			payload: `{"uid": "04", "pair_id": 1, "event": "lifted"}`,
+			want:    MQTTResponse{Type: mqttTopicCard, Error: "invalid event: lifted"},
+		},
+		{
+			name:    "batch without idempotency key",
+			kind:    mqttTopicCards,
+			payload: `{"reads": [{"seq": 1, "uid": "04", "pair_id": 1}]}`,
+			want:    MQTTResponse{Type: mqttTopicCards, Error: "idempotency_key is required (seq: 1)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := transport.Publish("test/device-1/"+tt.kind, []byte(tt.payload)); err != nil {
+				t.Fatalf("transport.Publish(): %+v", err)
+			}
+
+			select {
+			case got := <-responses:
+				if got.Type != tt.want.Type || got.Error != tt.want.Error {
+					t.Errorf("response = %+v, want %+v", got, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout to receive the response")
+			}
+		})
+	}
+}
+
+func TestNewEmbeddedMQTT_ListenAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		username string
+		wantErr  bool
+	}{
+		{name: "loopback", addr: "127.0.0.1:0"},
+		{name: "all interfaces without auth", addr: ":0", wantErr: true},
+		{name: "all interfaces with auth", addr: ":0", username: "reader"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newEmbeddedMQTT(config.Config{MQTTListenAddr: tt.addr, MQTTUsername: tt.username, MQTTPassword: "secret"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newEmbeddedMQTT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if m != nil {
+				m.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/whywaita/poker-go"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/playercards"
+	"github.com/whywaita/rfid-poker/pkg/query"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+// Events of a card read
+const (
+	// CardEventDetected is a card put on the antenna (default)
+	CardEventDetected = "detected"
+	// CardEventRemoved is a card no longer present on the antenna
+	CardEventRemoved = "removed"
+)
+
+// presenceTracker tracks cards physically present on each antenna
+type presenceTracker struct {
+	mu       sync.RWMutex
+	antennas map[string]map[poker.Card]time.Time // key: serial, value: card -> detected time
+}
+
+var presence = &presenceTracker{
+	antennas: make(map[string]map[poker.Card]time.Time),
+}
+
+// cards returns a copy of cards on the antenna, and false if no card has ever been reported on it
+func (p *presenceTracker) cards(serial string) (map[poker.Card]time.Time, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cards, ok := p.antennas[serial]
+	if !ok {
+		return nil, false
+	}
+	result := make(map[poker.Card]time.Time, len(cards))
+	for card, t := range cards {
+		result[card] = t
+	}
+	return result, true
+}
+
+// apply replaces cards of the antennas with changed, called after the transaction that changed them is committed
+func (p *presenceTracker) apply(changed map[string]map[poker.Card]time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for serial, cards := range changed {
+		p.antennas[serial] = cards
+	}
+}
+
+// count returns the number of cards on the antenna, and false if no card has ever been reported on it
+func (p *presenceTracker) count(serial string) (int, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cards, ok := p.antennas[serial]
+	return len(cards), ok
+}
+
+// snapshot returns a copy of present cards
+func (p *presenceTracker) snapshot() map[string]map[poker.Card]time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make(map[string]map[poker.Card]time.Time, len(p.antennas))
+	for serial, cards := range p.antennas {
+		c := make(map[poker.Card]time.Time, len(cards))
+		for card, t := range cards {
+			c[card] = t
+		}
+		result[serial] = c
+	}
+	return result
+}
+
+// presentCards returns cards on the antenna as changed in t, and false if no card has ever been reported on it
+func (t *tableTx) presentCards(serial string) (map[poker.Card]time.Time, bool) {
+	if cards, ok := t.present[serial]; ok {
+		return cards, true
+	}
+	return presence.cards(serial)
+}
+
+// addPresence records the card put on the antenna, it is applied to presence after the commit
+func (t *tableTx) addPresence(serial string, card poker.Card) {
+	cards, ok := t.presentCards(serial)
+	if !ok {
+		cards = make(map[poker.Card]time.Time)
+	}
+	if _, ok := cards[card]; !ok {
+		cards[card] = time.Now()
+	}
+	if t.present == nil {
+		t.present = make(map[string]map[poker.Card]time.Time)
+	}
+	t.present[serial] = cards
+}
+
+// removePresence records the card lifted off the antenna, it is applied to presence after the commit.
+// It returns the number of cards remaining, and false if no card has ever been reported on the antenna.
+func (t *tableTx) removePresence(serial string, card poker.Card) (int, bool) {
+	cards, ok := t.presentCards(serial)
+	if !ok {
+		return 0, false
+	}
+	delete(cards, card)
+	if t.present == nil {
+		t.present = make(map[string]map[poker.Card]time.Time)
+	}
+	t.present[serial] = cards
+	return len(cards), true
+}
+
+// hasPresence returns true if the card is present on the antenna as changed in t
+func (t *tableTx) hasPresence(serial string, card poker.Card) bool {
+	cards, _ := t.presentCards(serial)
+	_, ok := cards[card]
+	return ok
+}
+
+// resolveSerial returns the serial of the antenna, all pair_ids of a board device are treated as one board antenna
+func resolveSerial(ctx context.Context, q *query.Queries, deviceID string, pairID int) string {
+	boardAntenna, err := store.GetBoardAntennaByDeviceID(ctx, q, deviceID)
+	if err == nil {
+		return boardAntenna.Serial
+	}
+	return store.ToSerial(deviceID, pairID)
+}
+
+// processCardRemoved handles a card lifted off the antenna
+func processCardRemoved(ctx context.Context, t *tableTx, cc config.Config, uid string, deviceID string, pairID int) error {
+	logger := slog.With("method", "processCardRemoved")
+
+	pcard, err := playercards.LoadPlayerCard(uid, cc.CardIDs)
+	if err != nil {
+		return fmt.Errorf("playercards.LoadPlayerCard(%s, cardConfigs): %w", uid, err)
+	}
+	card, err := playercards.UnmarshalPlayerCard(pcard)
+	if err != nil {
+		return fmt.Errorf("playercards.UnmarshalPlayerCard(%s): %w", pcard, err)
+	}
+
+	serial := resolveSerial(ctx, t.q, deviceID, pairID)
+	remaining, known := t.removePresence(serial, card)
+	if !known {
+		// e.g. after a restart, the board may still have cards that were not reported since
+		logger.WarnContext(ctx, "card removed from antenna without presence, ignore it",
+			"serial", serial,
+			"card", fmt.Sprintf("%s%s", card.Rank.String(), card.Suit.String()))
+		return nil
+	}
+	logger.InfoContext(ctx, "card removed from antenna",
+		"serial", serial,
+		"card", fmt.Sprintf("%s%s", card.Rank.String(), card.Suit.String()),
+		"remaining", remaining)
+	t.notify()
+
+	if remaining > 0 {
+		return nil
+	}
+
+	antenna, err := t.q.GetAntennaBySerial(ctx, serial)
+	if err != nil {
+		return fmt.Errorf("q.GetAntennaBySerial(): %w", err)
+	}
+	if store.GetAntennaType(antenna.AntennaTypeName) == store.AntennaTypeBoard {
+		if err := onBoardCleared(ctx, t, cc); err != nil {
+			return fmt.Errorf("onBoardCleared(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+// onBoardCleared is called when all cards are swept off the board antenna
+func onBoardCleared(ctx context.Context, t *tableTx, cc config.Config) error {
+	board, err := store.GetBoard(ctx, t.q)
+	if err != nil {
+		return fmt.Errorf("store.GetBoard(): %w", err)
+	}
+	if len(board) == 0 {
+		// board has not been dealt in the current game
+		return nil
+	}
+
+	slog.InfoContext(ctx, "board cleared", "event", "board_cleared", "board_count", len(board))
+	if gameEndPolicy(cc) != GameEndPolicyBoardRemoved {
+		return nil
+	}
+
+	if err := endGame(ctx, t, GameEndPolicyBoardRemoved); err != nil {
+		return fmt.Errorf("endGame(): %w", err)
+	}
+
+	return nil
+}
+
+type PresenceCard struct {
+	Suit       string    `json:"suit"`
+	Rank       string    `json:"rank"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+type PresenceAntenna struct {
+	ID              int32          `json:"id"`
+	DeviceID        string         `json:"device_id"`
+	PairID          int            `json:"pair_id"`
+	AntennaTypeName string         `json:"antenna_type_name"`
+	Cards           []PresenceCard `json:"cards"`
+}
+
+type GetAdminPresenceResponse struct {
+	Antenna []PresenceAntenna `json:"antenna"`
+}
+
+// HandleGetAdminPresence returns cards present on each antenna
+func HandleGetAdminPresence(c echo.Context, conn *sql.DB) error {
+	logger := slog.With("method", "HandleGetAdminPresence")
+	q := query.New(conn)
+
+	antenna, err := q.GetAntenna(c.Request().Context())
+	if err != nil {
+		logger.WarnContext(c.Request().Context(), "q.GetAntenna", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+
+	present := presence.snapshot()
+	resp := GetAdminPresenceResponse{Antenna: make([]PresenceAntenna, 0, len(antenna))}
+	for _, a := range antenna {
+		deviceID, pairID, err := store.FromSerial(a.Serial)
+		if err != nil {
+			logger.WarnContext(c.Request().Context(), "store.FromSerial", "error", err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+
+		cards := make([]PresenceCard, 0, len(present[a.Serial]))
+		for card, detectedAt := range present[a.Serial] {
+			cards = append(cards, PresenceCard{
+				Suit:       card.Suit.String(),
+				Rank:       card.Rank.String(),
+				DetectedAt: detectedAt,
+			})
+		}
+		sort.SliceStable(cards, func(i, j int) bool {
+			return cards[i].DetectedAt.Before(cards[j].DetectedAt)
+		})
+
+		resp.Antenna = append(resp.Antenna, PresenceAntenna{
+			ID:              a.ID,
+			DeviceID:        deviceID,
+			PairID:          pairID,
+			AntennaTypeName: a.AntennaTypeName,
+			Cards:           cards,
+		})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/whywaita/poker-go"
+)
+
+func TestTableTx_Presence(t *testing.T) {
+	orig := presence
+	defer func() { presence = orig }()
+	presence = &presenceTracker{antennas: make(map[string]map[poker.Card]time.Time)}
+
+	ace := poker.Card{Rank: poker.RankAce, Suit: poker.Spades}
+	king := poker.Card{Rank: poker.RankKing, Suit: poker.Spades}
+
+	tx := &tableTx{}
+	if _, known := tx.removePresence("board", ace); known {
+		t.Errorf("removePresence() of an antenna without presence is known")
+	}
+
+	tx.addPresence("board", ace)
+	tx.addPresence("board", king)
+	if !tx.hasPresence("board", ace) {
+		t.Errorf("hasPresence() = false, want true in the transaction")
+	}
+	if _, ok := presence.count("board"); ok {
+		t.Errorf("presence is changed before the commit")
+	}
+	if remaining, known := tx.removePresence("board", ace); remaining != 1 || !known {
+		t.Errorf("removePresence() = (%d, %t), want (1, true)", remaining, known)
+	}
+
+	presence.apply(tx.present)
+	if count, ok := presence.count("board"); count != 1 || !ok {
+		t.Errorf("count() = (%d, %t), want (1, true)", count, ok)
+	}
+
+	// a rolled back transaction is never applied
+	rolledBack := &tableTx{}
+	rolledBack.removePresence("board", king)
+	if count, _ := presence.count("board"); count != 1 {
+		t.Errorf("count() = %d, want 1", count)
+	}
+}
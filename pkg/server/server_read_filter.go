@@ -0,0 +1,170 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+	"github.com/whywaita/rfid-poker/pkg/store"
+)
+
+// readFilterPruneAfter is how long a key without reads is kept
+const readFilterPruneAfter = 1 * time.Minute
+
+type readFilterKey struct {
+	deviceID string
+	pairID   int
+	uid      string
+}
+
+type readFilterState struct {
+	lastSeen time.Time
+	count    int  // consecutive reads
+	passed   bool // already passed to processing
+}
+
+type ReadFilterCounter struct {
+	Passed      int64 `json:"passed"`
+	Debounced   int64 `json:"debounced"`
+	Unconfirmed int64 `json:"unconfirmed"`
+}
+
+// readFilter drops repeated and unconfirmed reads before they hit the database
+type readFilter struct {
+	mu       sync.Mutex
+	states   map[readFilterKey]*readFilterState
+	counters map[string]*ReadFilterCounter // key: serial (device_id-pair_id)
+}
+
+var reads = &readFilter{
+	states:   make(map[readFilterKey]*readFilterState),
+	counters: make(map[string]*ReadFilterCounter),
+}
+
+// allow returns true if the read should be processed, or the outcome of the dropped read.
+// A read is processed once it is read ReadConfirmCount times in a row (each within ReadConfirmWindowMillis),
+// and repeats within ReadDebounceMillis of the previous read are dropped after that.
+func (f *readFilter) allow(cc config.Config, deviceID string, pairID int, uid string, now time.Time) (bool, ReadOutcome) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.prune(now)
+
+	serial := store.ToSerial(deviceID, pairID)
+	counter, ok := f.counters[serial]
+	if !ok {
+		counter = &ReadFilterCounter{}
+		f.counters[serial] = counter
+	}
+
+	confirmCount := cc.ReadConfirmCount
+	if n, ok := cc.ReadConfirmCountBySerial[serial]; ok {
+		confirmCount = n
+	}
+	debounce := time.Duration(cc.ReadDebounceMillis) * time.Millisecond
+	confirmWindow := time.Duration(cc.ReadConfirmWindowMillis) * time.Millisecond
+
+	key := readFilterKey{deviceID: deviceID, pairID: pairID, uid: uid}
+	state, ok := f.states[key]
+	if !ok {
+		state = &readFilterState{}
+		f.states[key] = state
+	}
+
+	if state.passed {
+		if now.Sub(state.lastSeen) < debounce {
+			state.lastSeen = now
+			counter.Debounced++
+			return false, ReadOutcomeDuplicate
+		}
+		// read again after the window, treat as a new read
+		*state = readFilterState{}
+	}
+
+	if state.count > 0 && now.Sub(state.lastSeen) > confirmWindow {
+		// not consecutive, start over
+		*state = readFilterState{}
+	}
+	state.count++
+	state.lastSeen = now
+
+	if state.count < confirmCount {
+		counter.Unconfirmed++
+		return false, ReadOutcomeUnconfirmed
+	}
+
+	state.passed = true
+	counter.Passed++
+	return true, ReadOutcomeAccepted
+}
+
+// reset forgets the card on the antenna, e.g. the card is removed or failed to process
+func (f *readFilter) reset(deviceID string, pairID int, uid string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.states, readFilterKey{deviceID: deviceID, pairID: pairID, uid: uid})
+}
+
+// prune removes keys without reads for a while. The caller must hold f.mu.
+func (f *readFilter) prune(now time.Time) {
+	for key, state := range f.states {
+		if now.Sub(state.lastSeen) > readFilterPruneAfter {
+			delete(f.states, key)
+		}
+	}
+}
+
+// countersSnapshot returns a copy of counters
+func (f *readFilter) countersSnapshot() map[string]ReadFilterCounter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make(map[string]ReadFilterCounter, len(f.counters))
+	for serial, counter := range f.counters {
+		result[serial] = *counter
+	}
+	return result
+}
+
+type ReaderStat struct {
+	DeviceID string `json:"device_id"`
+	PairID   int    `json:"pair_id"`
+	ReadFilterCounter
+}
+
+type GetAdminReaderStatsResponse struct {
+	Total   ReadFilterCounter `json:"total"`
+	Readers []ReaderStat      `json:"readers"`
+}
+
+// HandleGetAdminReaderStats returns counters of reads filtered by the server
+func HandleGetAdminReaderStats(c echo.Context) error {
+	resp := GetAdminReaderStatsResponse{Readers: []ReaderStat{}}
+	for serial, counter := range reads.countersSnapshot() {
+		deviceID, pairID, err := store.FromSerial(serial)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+		resp.Readers = append(resp.Readers, ReaderStat{
+			DeviceID:          deviceID,
+			PairID:            pairID,
+			ReadFilterCounter: counter,
+		})
+		resp.Total.Passed += counter.Passed
+		resp.Total.Debounced += counter.Debounced
+		resp.Total.Unconfirmed += counter.Unconfirmed
+	}
+	sort.SliceStable(resp.Readers, func(i, j int) bool {
+		if resp.Readers[i].DeviceID != resp.Readers[j].DeviceID {
+			return resp.Readers[i].DeviceID < resp.Readers[j].DeviceID
+		}
+		return resp.Readers[i].PairID < resp.Readers[j].PairID
+	})
+
+	return c.JSON(http.StatusOK, resp)
+}
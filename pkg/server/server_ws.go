@@ -1,16 +1,18 @@
 package server
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log/slog"
+	"net/http"
+	"slices"
 	"sort"
-	"sync"
+	"time"
 
+	"github.com/whywaita/poker-go"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
 	"github.com/whywaita/rfid-poker/pkg/query"
 	"github.com/whywaita/rfid-poker/pkg/store"
 
@@ -18,54 +20,103 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// WebSocketManager manages WebSocket connections
-type WebSocketManager struct {
-	mu       sync.Mutex
-	clients  map[*websocket.Conn]struct{}
-	notifyCh chan struct{}
+// Send is struct for WebSocket sending
+type Send struct {
+	// Table is the name of the table, empty if not set
+	Table string `json:"table"`
+	// GameID is the current game, empty if no game
+	GameID string `json:"game_id"`
+	// State is the state of the current game, "waiting" if no game
+	State string `json:"state"`
+	// Button is the seat of the dealer button
+	Button  *int32       `json:"button"`
+	Players []SendPlayer `json:"players"`
+	// Board is the first board, Boards has all boards on run it twice or double-board games
+	Board  []SendCard  `json:"board"`
+	Boards []SendBoard `json:"boards"`
+	// Rabbit is cards revealed after the hand ends, not a part of the board
+	Rabbit []SendCard `json:"rabbit"`
+	// AutoClear is when the game is cleared by the timeout, not set if the timer is not running
+	AutoClear *SendAutoClear `json:"auto_clear"`
+	// Pots is the main pot and side pots in order, PotTotal is the sum of them
+	Pots     []SendPot `json:"pots"`
+	PotTotal int64     `json:"pot_total"`
+	// Tournament is the blind clock and standings of the running tournament, not set without a tournament
+	Tournament *SendTournament `json:"tournament"`
 }
 
-var wsManager = &WebSocketManager{
-	clients:  make(map[*websocket.Conn]struct{}),
-	notifyCh: make(chan struct{}, 1000), // with buffer
+type SendPot struct {
+	Amount int64 `json:"amount"`
+	// Seats is seats eligible to win the pot
+	Seats []int32 `json:"seats"`
 }
 
-func (m *WebSocketManager) addClient(ws *websocket.Conn) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.clients[ws] = struct{}{}
+type SendTournament struct {
+	Name string `json:"name"`
+	// Level is the current blind level from 1
+	Level      int   `json:"level"`
+	SmallBlind int64 `json:"small_blind"`
+	BigBlind   int64 `json:"big_blind"`
+	Ante       int64 `json:"ante"`
+	// LevelEndsAt is when the level ends, NextLevel is blinds of the next level. Both are not set on the last level
+	LevelEndsAt      *time.Time      `json:"level_ends_at"`
+	RemainingSeconds int             `json:"remaining_seconds"`
+	NextLevel        *SendBlindLevel `json:"next_level"`
+	// Paused is true while the clock is paused, LevelEndsAt is moved on resume
+	Paused bool `json:"paused"`
+
+	Entries           int   `json:"entries"`
+	PlayersRemaining  int   `json:"players_remaining"`
+	PlayersEliminated int   `json:"players_eliminated"`
+	AverageStack      int64 `json:"average_stack"`
 }
 
-func (m *WebSocketManager) removeClient(ws *websocket.Conn) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	delete(m.clients, ws)
+type SendBlindLevel struct {
+	SmallBlind int64 `json:"small_blind"`
+	BigBlind   int64 `json:"big_blind"`
+	Ante       int64 `json:"ante"`
+	Minutes    int   `json:"minutes"`
 }
 
-// broadcast sends a message to all WebSocket clients
-func (m *WebSocketManager) broadcast(q *query.Queries) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	for client := range m.clients {
-		go func(ws *websocket.Conn) {
-			if err := sendPlayer(context.Background(), q, ws); err != nil {
-				slog.WarnContext(context.Background(), "failed to send update to WebSocket", "error", err)
-			}
-		}(client)
-	}
-}
-
-// Send is struct for WebSocket sending
-type Send struct {
-	Players []SendPlayer `json:"players"`
-	Board   []SendCard   `json:"board"`
+type SendAutoClear struct {
+	ExpiresAt        time.Time `json:"expires_at"`
+	RemainingSeconds int       `json:"remaining_seconds"`
+	// Paused is true while the dealer pauses auto-clearing, ExpiresAt is moved on resume
+	Paused bool `json:"paused"`
 }
 
 type SendPlayer struct {
 	Name   string     `json:"name"`
 	Hand   []SendCard `json:"hand"`
 	Equity float64    `json:"equity"`
+
+	// PickedUp is true if the player has lifted the cards off the antenna
+	PickedUp bool `json:"picked_up"`
+	// BoardEquity is the equity on each board in order of Boards, Equity is the average of them
+	BoardEquity []float64 `json:"board_equity"`
+
+	// Seat is the seat number of the player, 0 if the antenna has no seat
+	Seat int32 `json:"seat"`
+	// Position is the position from the dealer button (BTN, SB, BB, UTG, ...)
+	Position string `json:"position"`
+
+	// profile of the player, empty if not set
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	Country     string `json:"country"`
+
+	// chips of the player, Bet is the chips bet on the current street
+	Stack  int64 `json:"stack"`
+	Bet    int64 `json:"bet"`
+	Folded bool  `json:"folded"`
+	AllIn  bool  `json:"all_in"`
+}
+
+type SendBoard struct {
+	Number int32      `json:"number"`
+	Cards  []SendCard `json:"cards"`
+	// Winners is names of players winning the board, set when the board is complete
+	Winners []string `json:"winners"`
 }
 
 type SendCard struct {
@@ -73,8 +124,11 @@ type SendCard struct {
 	Rank string `json:"rank"`
 }
 
-func ws(c echo.Context, conn *sql.DB) error {
-	q := query.New(conn)
+func ws(c echo.Context) error {
+	client, err := newWSClient(c, false)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
 
 	wsConn, err := websocket.Accept(c.Response(), c.Request(), &websocket.AcceptOptions{
 		OriginPatterns: []string{"*"},
@@ -84,71 +138,99 @@ func ws(c echo.Context, conn *sql.DB) error {
 	}
 	defer wsConn.Close(websocket.StatusNormalClosure, "")
 
-	wsManager.addClient(wsConn)
-	defer wsManager.removeClient(wsConn)
-
-	ctx := c.Request().Context()
-
-	if err := sendPlayer(ctx, q, wsConn); err != nil {
-		c.Logger().Errorf(err.Error())
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-wsManager.notifyCh:
-			wsManager.broadcast(q)
-		}
-	}
+	// the public feed starts from the last update released after the broadcast delay
+	client.conn = wsConn
+	wsHub.serve(c.Request().Context(), client)
+	return nil
 }
 
 func notifyClients() {
 	select {
-	case wsManager.notifyCh <- struct{}{}:
+	case wsHub.notifyCh <- struct{}{}:
 	default:
-		// skip if the channel is full
+		// refresh is already pending, it sends all changes before it
+	}
+	select {
+	case adminCardsCh <- struct{}{}:
+	default:
+		// refresh is already pending
 	}
 }
 
-func sendPlayer(ctx context.Context, q *query.Queries, ws *websocket.Conn) error {
-	send, err := getSend(ctx, q)
-	if err != nil {
-		return fmt.Errorf("getSend(): %w", err)
+func getSend(ctx context.Context, q *query.Queries) (*Send, error) {
+	send := &Send{Table: config.Conf.TableName, State: string(store.GameStateWaiting)}
+	game, err := q.GetCurrentGame(ctx)
+	switch {
+	case err == nil:
+		send.GameID = game.ID
+		send.State = game.State
+		send.Button = nullInt32ToPtr(game.ButtonPosition)
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("q.GetCurrentGame(): %w", err)
 	}
 
-	b, err := json.Marshal(send)
+	data, err := store.GetStored(ctx, q)
 	if err != nil {
-		return fmt.Errorf("json.Marshal(%v): %w", send, err)
+		return nil, fmt.Errorf("GetStored(): %w", err)
 	}
 
-	slog.With("method", "sendPlayer").Info("Send to message", slog.String("body", string(b)))
-	w, err := ws.Writer(ctx, websocket.MessageText)
+	boards, err := store.GetBoards(ctx, q)
 	if err != nil {
-		return fmt.Errorf("ws.Writer(): %w", err)
+		return nil, fmt.Errorf("GetBoards(): %w", err)
 	}
+	board := boards[0].Cards
 
-	if _, err := io.Copy(w, bytes.NewBuffer(b)); err != nil {
-		return fmt.Errorf("io.Copy(): %w", err)
+	handEquities, err := q.GetHandEquities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetHandEquities(): %w", err)
 	}
-
-	if err := w.Close(); err != nil {
-		return fmt.Errorf("w.Close(): %w", err)
+	boardEquity := make(map[int32]map[int32]float64) // key: hand_id, board_number
+	for _, he := range handEquities {
+		if _, ok := boardEquity[he.HandID]; !ok {
+			boardEquity[he.HandID] = make(map[int32]float64)
+		}
+		boardEquity[he.HandID][he.BoardNumber] = he.Equity
 	}
 
-	return nil
-}
-
-func getSend(ctx context.Context, q *query.Queries) (*Send, error) {
-	send := &Send{}
-	data, err := store.GetStored(ctx, q)
+	profiles, err := q.GetPlayerProfiles(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("GetStored(): %w", err)
+		return nil, fmt.Errorf("q.GetPlayerProfiles(): %w", err)
+	}
+	players := make(map[int32]query.GetPlayerProfilesRow, len(profiles)) // key: player_id
+	serials := make(map[int32]string, len(profiles))
+	seats := make(map[int32]int32, len(profiles))
+	occupied := make([]int32, 0, len(profiles))
+	for _, d := range profiles {
+		players[d.ID] = d
+		serials[d.ID] = d.Serial.String
+		if d.SeatNumber.Valid {
+			seats[d.ID] = d.SeatNumber.Int32
+			occupied = append(occupied, d.SeatNumber.Int32)
+		}
+	}
+	var positions map[int32]string
+	if send.Button != nil {
+		positions = store.Positions(occupied, *send.Button)
 	}
 
-	board, err := store.GetBoard(ctx, q)
+	table, err := store.GetTable(ctx, q)
 	if err != nil {
-		return nil, fmt.Errorf("GetBoard(): %w", err)
+		return nil, fmt.Errorf("GetTable(): %w", err)
+	}
+	tableSeats := make(map[int32]store.TableSeat, len(table.Seats)) // key: seat_number
+	for _, ts := range table.Seats {
+		tableSeats[ts.SeatNumber] = ts
+	}
+	send.Pots = toSendPots(table.Pots)
+	send.PotTotal = table.PotTotal
+
+	tournament, err := store.GetRunningTournament(ctx, q)
+	switch {
+	case err == nil:
+		t := toSendTournament(*tournament, store.CalcTournamentStandings(*tournament, table), time.Now())
+		send.Tournament = &t
+	case !errors.Is(err, store.ErrNoTournament):
+		return nil, fmt.Errorf("GetRunningTournament(): %w", err)
 	}
 
 	for _, s := range data {
@@ -165,10 +247,52 @@ func getSend(ctx context.Context, q *query.Queries) (*Send, error) {
 			return hand[i].Rank < hand[j].Rank
 		})
 
+		count, reported := presence.count(serials[s.PlayerID])
+
+		equities := make([]float64, 0, len(boards))
+		for _, b := range boards {
+			equities = append(equities, boardEquity[s.HandID][b.Number])
+		}
+
 		send.Players = append(send.Players, SendPlayer{
-			Name:   s.PlayerName,
-			Hand:   hand,
-			Equity: s.Equity,
+			Name:        s.PlayerName,
+			Hand:        hand,
+			Equity:      s.Equity,
+			PickedUp:    reported && count == 0,
+			BoardEquity: equities,
+			Seat:        seats[s.PlayerID],
+			Position:    positions[seats[s.PlayerID]],
+			DisplayName: players[s.PlayerID].DisplayName.String,
+			AvatarURL:   players[s.PlayerID].AvatarUrl.String,
+			Country:     players[s.PlayerID].Country.String,
+			Stack:       tableSeats[seats[s.PlayerID]].Stack,
+			Bet:         tableSeats[seats[s.PlayerID]].Bet,
+			Folded:      tableSeats[seats[s.PlayerID]].Folded,
+			AllIn:       tableSeats[seats[s.PlayerID]].AllIn,
+		})
+	}
+
+	// players are in order of seat, players without a seat are last
+	sort.SliceStable(send.Players, func(i, j int) bool {
+		a, b := send.Players[i].Seat, send.Players[j].Seat
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+
+	for _, b := range boards {
+		cards := make([]SendCard, 0, len(b.Cards))
+		for _, card := range b.Cards {
+			cards = append(cards, SendCard{
+				Suit: card.Suit.String(),
+				Rank: card.Rank.String(),
+			})
+		}
+		send.Boards = append(send.Boards, SendBoard{
+			Number:  b.Number,
+			Cards:   cards,
+			Winners: boardWinners(data, b.Cards),
 		})
 	}
 
@@ -179,5 +303,51 @@ func getSend(ctx context.Context, q *query.Queries) (*Send, error) {
 		})
 	}
 
+	rabbit, err := store.GetRabbit(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("GetRabbit(): %w", err)
+	}
+	for _, card := range rabbit {
+		send.Rabbit = append(send.Rabbit, SendCard{
+			Suit: card.Suit.String(),
+			Rank: card.Rank.String(),
+		})
+	}
+
+	if isGameTimeoutEnabled(config.Conf) {
+		timeout, err := getGameTimeout(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("getGameTimeout(): %w", err)
+		}
+		if timeout != nil && timeout.Started {
+			send.AutoClear = &SendAutoClear{
+				ExpiresAt:        timeout.ExpiresAt,
+				RemainingSeconds: int(timeout.Remaining(time.Now()).Seconds()),
+				Paused:           timeout.PausedAt.Valid,
+			}
+		}
+	}
+
 	return send, nil
 }
+
+// boardWinners returns names of players with the best hand on the complete board
+func boardWinners(players []store.Stored, board []poker.Card) []string {
+	winners := []string{}
+	if len(board) != 5 {
+		return winners
+	}
+
+	best := -1
+	for _, p := range players {
+		power := poker.NewBestMadeHand(append(slices.Clone(p.Hand), board...)).Power()
+		switch {
+		case power > best:
+			best = power
+			winners = []string{p.PlayerName}
+		case power == best:
+			winners = append(winners, p.PlayerName)
+		}
+	}
+	return winners
+}
@@ -0,0 +1,270 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// WSProtocolEvents is the protocol of /ws sending typed messages with sequence numbers, the default protocol sends Send only
+const WSProtocolEvents = "2"
+
+// wsEpoch identifies sequence numbers of the server process, they start again from 1 after a restart
+var wsEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+const (
+	WSMessageSnapshot      = "snapshot"
+	WSMessageCardAdded     = "card_added"
+	WSMessageHandCompleted = "hand_completed"
+	WSMessageHandMucked    = "hand_mucked"
+	WSMessageEquityUpdated = "equity_updated"
+	WSMessageGameStarted   = "game_started"
+	WSMessageGameCleared   = "game_cleared"
+)
+
+// WSMessage is a message of the events protocol of /ws
+type WSMessage struct {
+	Type string `json:"type"`
+	// Seq is increased by one for each message, a client resumes from it with Epoch after reconnecting
+	Seq   int64  `json:"seq"`
+	Epoch string `json:"epoch"`
+	// GameID is the game of the message, empty if no game
+	GameID string `json:"game_id"`
+	Data   any    `json:"data"`
+}
+
+type WSCardAdded struct {
+	// Location is "board" or "player"
+	Location string   `json:"location"`
+	Board    int32    `json:"board,omitempty"`
+	Player   string   `json:"player,omitempty"`
+	Seat     int32    `json:"seat,omitempty"`
+	Card     SendCard `json:"card"`
+}
+
+type WSHandCompleted struct {
+	Player string     `json:"player"`
+	Seat   int32      `json:"seat"`
+	Hand   []SendCard `json:"hand"`
+}
+
+type WSHandMucked struct {
+	Player string `json:"player"`
+	Seat   int32  `json:"seat"`
+}
+
+type WSEquityUpdated struct {
+	Players []WSPlayerEquity `json:"players"`
+}
+
+type WSPlayerEquity struct {
+	Player      string    `json:"player"`
+	Seat        int32     `json:"seat"`
+	Equity      float64   `json:"equity"`
+	BoardEquity []float64 `json:"board_equity"`
+}
+
+type WSGameStarted struct {
+	State  string `json:"state"`
+	Button *int32 `json:"button"`
+}
+
+// WSClientMessage is a message from a client of the events protocol
+type WSClientMessage struct {
+	// Type is "resume" (messages after Seq of Epoch) or "snapshot" (the whole table)
+	Type  string `json:"type"`
+	Seq   int64  `json:"seq"`
+	Epoch string `json:"epoch"`
+}
+
+// diffSend returns messages of changes from prev to cur, without sequence numbers.
+// A snapshot is added if the table has changed other than by the typed messages.
+func diffSend(prev, cur *Send) []WSMessage {
+	var messages []WSMessage
+	if prev == nil {
+		prev = &Send{}
+	}
+
+	if prev.GameID != cur.GameID {
+		if prev.GameID != "" {
+			messages = append(messages, WSMessage{Type: WSMessageGameCleared, GameID: prev.GameID, Data: struct{}{}})
+		}
+		if cur.GameID != "" {
+			messages = append(messages, WSMessage{Type: WSMessageGameStarted, GameID: cur.GameID, Data: WSGameStarted{
+				State:  cur.State,
+				Button: cur.Button,
+			}})
+		}
+		// cards of the new game are all added
+		prev = &Send{GameID: cur.GameID}
+	}
+
+	prevPlayers := make(map[string]SendPlayer, len(prev.Players)) // key: name
+	for _, p := range prev.Players {
+		prevPlayers[p.Name] = p
+	}
+	curPlayers := make(map[string]struct{}, len(cur.Players))
+	for _, p := range cur.Players {
+		curPlayers[p.Name] = struct{}{}
+		before, ok := prevPlayers[p.Name]
+		if !ok {
+			// a hand is sent when both cards are read
+			messages = append(messages, WSMessage{Type: WSMessageHandCompleted, GameID: cur.GameID, Data: WSHandCompleted{
+				Player: p.Name,
+				Seat:   p.Seat,
+				Hand:   p.Hand,
+			}})
+			continue
+		}
+		for _, card := range p.Hand {
+			if !slices.Contains(before.Hand, card) {
+				messages = append(messages, WSMessage{Type: WSMessageCardAdded, GameID: cur.GameID, Data: WSCardAdded{
+					Location: "player",
+					Player:   p.Name,
+					Seat:     p.Seat,
+					Card:     card,
+				}})
+			}
+		}
+	}
+	for _, p := range prev.Players {
+		if _, ok := curPlayers[p.Name]; !ok {
+			messages = append(messages, WSMessage{Type: WSMessageHandMucked, GameID: cur.GameID, Data: WSHandMucked{
+				Player: p.Name,
+				Seat:   p.Seat,
+			}})
+		}
+	}
+
+	prevBoards := make(map[int32][]SendCard, len(prev.Boards)) // key: board number
+	for _, b := range prev.Boards {
+		prevBoards[b.Number] = b.Cards
+	}
+	for _, b := range cur.Boards {
+		for _, card := range b.Cards {
+			if !slices.Contains(prevBoards[b.Number], card) {
+				messages = append(messages, WSMessage{Type: WSMessageCardAdded, GameID: cur.GameID, Data: WSCardAdded{
+					Location: "board",
+					Board:    b.Number,
+					Card:     card,
+				}})
+			}
+		}
+	}
+
+	if equityChanged(prevPlayers, cur.Players) {
+		equities := WSEquityUpdated{Players: make([]WSPlayerEquity, 0, len(cur.Players))}
+		for _, p := range cur.Players {
+			equities.Players = append(equities.Players, WSPlayerEquity{
+				Player:      p.Name,
+				Seat:        p.Seat,
+				Equity:      p.Equity,
+				BoardEquity: p.BoardEquity,
+			})
+		}
+		messages = append(messages, WSMessage{Type: WSMessageEquityUpdated, GameID: cur.GameID, Data: equities})
+	}
+
+	if !reflect.DeepEqual(untypedTable(prev), untypedTable(cur)) {
+		messages = append(messages, WSMessage{Type: WSMessageSnapshot, GameID: cur.GameID, Data: cur})
+	}
+	return messages
+}
+
+// equityChanged returns true if the equity of a player in cur is changed
+func equityChanged(prev map[string]SendPlayer, cur []SendPlayer) bool {
+	for _, p := range cur {
+		before, ok := prev[p.Name]
+		if !ok {
+			if p.Equity != 0 {
+				return true
+			}
+			continue
+		}
+		if before.Equity != p.Equity || !slices.Equal(before.BoardEquity, p.BoardEquity) {
+			return true
+		}
+	}
+	return false
+}
+
+// untypedTable returns the table without parts sent by typed messages, and without remaining seconds and the expiry of timers.
+// The expiry of auto-clearing is extended by every card read.
+func untypedTable(s *Send) Send {
+	t := *s
+	t.Board = nil
+	t.Boards = make([]SendBoard, 0, len(s.Boards))
+	for _, b := range s.Boards {
+		b.Cards = nil
+		t.Boards = append(t.Boards, b)
+	}
+	t.Players = make([]SendPlayer, 0, len(s.Players))
+	for _, p := range s.Players {
+		p.Hand, p.Equity, p.BoardEquity = nil, 0, nil
+		t.Players = append(t.Players, p)
+	}
+	if s.AutoClear != nil {
+		autoClear := *s.AutoClear
+		autoClear.ExpiresAt, autoClear.RemainingSeconds = time.Time{}, 0
+		t.AutoClear = &autoClear
+	}
+	if s.Tournament != nil {
+		tournament := *s.Tournament
+		tournament.RemainingSeconds = 0
+		t.Tournament = &tournament
+	}
+	return t
+}
+
+// wsEncoded is a marshaled message with its sequence number and type
+type wsEncoded struct {
+	seq  int64
+	typ  string
+	body []byte
+}
+
+// wsFrame is the table after a change, with messages of the events protocol describing the change
+type wsFrame struct {
+	// snapshot is Send for clients of the default protocol
+	snapshot wsEncoded
+	messages []wsEncoded
+	// seq is the sequence number of the last message, snapshotMessage is the whole table at seq
+	seq             int64
+	snapshotMessage wsEncoded
+}
+
+// newWSFrame numbers messages of a change from seq+1 and marshals them
+func newWSFrame(prev, cur *Send, seq int64) (*wsFrame, error) {
+	snapshot, err := json.Marshal(cur)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(%v): %w", cur, err)
+	}
+
+	frame := &wsFrame{seq: seq}
+	for _, msg := range diffSend(prev, cur) {
+		frame.seq++
+		msg.Seq, msg.Epoch = frame.seq, wsEpoch
+		b, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal(%v): %w", msg, err)
+		}
+		frame.messages = append(frame.messages, wsEncoded{seq: msg.Seq, typ: msg.Type, body: b})
+	}
+	frame.snapshot = wsEncoded{seq: frame.seq, typ: WSMessageSnapshot, body: snapshot}
+
+	b, err := json.Marshal(WSMessage{
+		Type:   WSMessageSnapshot,
+		Seq:    frame.seq,
+		Epoch:  wsEpoch,
+		GameID: cur.GameID,
+		Data:   cur,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(): %w", err)
+	}
+	frame.snapshotMessage = wsEncoded{seq: frame.seq, typ: WSMessageSnapshot, body: b}
+	return frame, nil
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffSend_AutoClearExpiry(t *testing.T) {
+	now := time.Now()
+	prev := &Send{GameID: "g1", AutoClear: &SendAutoClear{ExpiresAt: now, RemainingSeconds: 10}}
+
+	// a card read extends the timer
+	extended := &Send{GameID: "g1", AutoClear: &SendAutoClear{ExpiresAt: now.Add(5 * time.Second), RemainingSeconds: 10}}
+	if got := diffSend(prev, extended); len(got) != 0 {
+		t.Errorf("diffSend() = %+v, want no message for the new expiry", got)
+	}
+
+	paused := &Send{GameID: "g1", AutoClear: &SendAutoClear{ExpiresAt: now, RemainingSeconds: 10, Paused: true}}
+	if got := diffSend(prev, paused); len(got) != 1 || got[0].Type != WSMessageSnapshot {
+		t.Errorf("diffSend() = %+v, want a snapshot", got)
+	}
+}
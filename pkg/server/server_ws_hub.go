@@ -0,0 +1,441 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/labstack/echo/v4"
+
+	"github.com/whywaita/rfid-poker/pkg/config"
+)
+
+// wsWriteTimeout is the timeout to send a message or a ping to a WebSocket client
+const wsWriteTimeout = 5 * time.Second
+
+const (
+	WebSocketSlowClientPolicyDrop  = "drop"
+	WebSocketSlowClientPolicyClose = "close"
+)
+
+// wsSlowClientPolicy returns what to do when the queue of a client is full
+func wsSlowClientPolicy(cc config.Config) string {
+	if cc.WebSocketSlowClientPolicy == WebSocketSlowClientPolicyClose {
+		return WebSocketSlowClientPolicyClose
+	}
+	return WebSocketSlowClientPolicyDrop
+}
+
+// wsHistorySize is the number of messages of the events protocol kept to resume
+const wsHistorySize = 1024
+
+// wsClient is a WebSocket connection or an SSE stream with the queue of messages to send
+type wsClient struct {
+	// conn is nil for an SSE stream
+	conn        *websocket.Conn
+	sse         bool
+	remoteAddr  string
+	connectedAt time.Time
+	// producer is true for the producer feed, false for the public feed
+	producer bool
+	// events is true for the events protocol, since is the sequence number of epoch to resume from (-1 to start from a snapshot)
+	events bool
+	since  int64
+	epoch  string
+
+	queue chan wsEncoded
+	// slow is closed when the client is closed by the slow client policy
+	slow     chan struct{}
+	slowOnce sync.Once
+
+	sent    atomic.Int64
+	dropped atomic.Int64
+}
+
+// newWSClient returns a client with the protocol of the request, the connection is set after accepting it
+func newWSClient(c echo.Context, producer bool) (*wsClient, error) {
+	client := &wsClient{
+		remoteAddr:  c.RealIP(),
+		connectedAt: time.Now(),
+		producer:    producer,
+		since:       -1,
+		queue:       make(chan wsEncoded, max(config.Conf.WebSocketQueueSize, 1)),
+		slow:        make(chan struct{}),
+	}
+
+	switch protocol := c.QueryParam("protocol"); protocol {
+	case "", "1":
+	case WSProtocolEvents:
+		client.events = true
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", protocol)
+	}
+	if since := c.QueryParam("since"); since != "" {
+		seq, err := strconv.ParseInt(since, 10, 64)
+		if err != nil || seq < 0 {
+			return nil, fmt.Errorf("invalid since %q", since)
+		}
+		client.since = seq
+	}
+	client.epoch = c.QueryParam("epoch")
+	return client, nil
+}
+
+// WebSocketHub sends messages built once per change to the queue of each WebSocket client
+type WebSocketHub struct {
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+	// last is the last frame of the public feed (false) and the producer feed (true), new clients start from it
+	last map[bool]*wsFrame
+	// history is messages of the events protocol sent to the feed, to resume from a sequence number
+	history map[bool][]wsEncoded
+	// notifyCh has a pending refresh, changes while it is pending are sent by the refresh
+	notifyCh chan struct{}
+
+	snapshots      atomic.Int64
+	connections    atomic.Int64
+	disconnections atomic.Int64
+	sent           atomic.Int64
+	dropped        atomic.Int64
+	closedSlow     atomic.Int64
+	resumed        atomic.Int64
+}
+
+var wsHub = &WebSocketHub{
+	clients:  make(map[*wsClient]struct{}),
+	last:     make(map[bool]*wsFrame),
+	history:  make(map[bool][]wsEncoded),
+	notifyCh: make(chan struct{}, 1),
+}
+
+func (h *WebSocketHub) register(client *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[client] = struct{}{}
+	h.connections.Add(1)
+
+	frame := h.last[client.producer]
+	switch {
+	case frame == nil:
+		// the first change is sent
+	case !client.events:
+		// the client resuming from the last frame has the table
+		if client.epoch != wsEpoch || client.since != frame.seq {
+			h.enqueue(client, frame.snapshot)
+		}
+	default:
+		h.resume(client, client.epoch, client.since)
+	}
+}
+
+func (h *WebSocketHub) unregister(client *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, client)
+	h.disconnections.Add(1)
+}
+
+// count returns the number of clients of the public feed and the producer feed
+func (h *WebSocketHub) count() (clients int, producers int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if client.producer {
+			producers++
+		} else {
+			clients++
+		}
+	}
+	return clients, producers
+}
+
+// broadcast queues a frame to all clients of the public feed or the producer feed
+func (h *WebSocketHub) broadcast(frame *wsFrame, producer bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last[producer] = frame
+	history := append(h.history[producer], frame.messages...)
+	if len(history) > wsHistorySize {
+		history = slices.Clone(history[len(history)-wsHistorySize:])
+	}
+	h.history[producer] = history
+
+	for client := range h.clients {
+		if client.producer != producer {
+			continue
+		}
+		if !client.events {
+			h.enqueue(client, frame.snapshot)
+			continue
+		}
+		// messages of a frame are queued together
+		if cap(client.queue)-len(client.queue) < len(frame.messages) {
+			h.overflow(client, frame)
+			continue
+		}
+		for _, msg := range frame.messages {
+			client.queue <- msg
+		}
+	}
+}
+
+// resume queues messages after seq to a client of the events protocol, or a snapshot if they are not kept.
+// seq of another epoch (e.g. before a restart of the server) is not resumed.
+// The caller must hold h.mu.
+func (h *WebSocketHub) resume(client *wsClient, epoch string, seq int64) {
+	frame := h.last[client.producer]
+	if frame == nil {
+		return
+	}
+	history := h.history[client.producer]
+	if epoch != wsEpoch || seq < 0 || seq > frame.seq || (len(history) > 0 && seq < history[0].seq-1) || (len(history) == 0 && seq != frame.seq) {
+		h.enqueueSnapshot(client, frame)
+		return
+	}
+
+	h.resumed.Add(1)
+	i, _ := slices.BinarySearchFunc(history, seq+1, func(e wsEncoded, target int64) int {
+		return cmp.Compare(e.seq, target)
+	})
+	if cap(client.queue)-len(client.queue) < len(history)-i {
+		// too many messages to resume, a snapshot is smaller
+		h.enqueueSnapshot(client, frame)
+		return
+	}
+	for _, msg := range history[i:] {
+		client.queue <- msg
+	}
+}
+
+// enqueueSnapshot replaces queued messages with the whole table of the frame.
+// The caller must hold h.mu.
+func (h *WebSocketHub) enqueueSnapshot(client *wsClient, frame *wsFrame) {
+	for len(client.queue) > 0 {
+		select {
+		case <-client.queue:
+			client.dropped.Add(1)
+			h.dropped.Add(1)
+		default:
+		}
+	}
+	h.enqueue(client, frame.snapshotMessage)
+}
+
+// overflow applies the slow client policy to a client of the events protocol without room for messages.
+// Messages are replaced with a snapshot instead of dropped, as a client can't apply messages after a gap.
+// The caller must hold h.mu.
+func (h *WebSocketHub) overflow(client *wsClient, frame *wsFrame) {
+	if wsSlowClientPolicy(config.Conf) == WebSocketSlowClientPolicyClose {
+		h.closeSlow(client)
+		return
+	}
+	h.enqueueSnapshot(client, frame)
+}
+
+// closeSlow closes the client by the slow client policy.
+// The caller must hold h.mu.
+func (h *WebSocketHub) closeSlow(client *wsClient) {
+	client.slowOnce.Do(func() {
+		close(client.slow)
+		h.closedSlow.Add(1)
+	})
+}
+
+// enqueue queues a message without blocking, the slow client policy is applied if the queue is full.
+// The caller must hold h.mu.
+func (h *WebSocketHub) enqueue(client *wsClient, msg wsEncoded) {
+	select {
+	case client.queue <- msg:
+		return
+	default:
+	}
+
+	if wsSlowClientPolicy(config.Conf) == WebSocketSlowClientPolicyClose {
+		h.closeSlow(client)
+		return
+	}
+
+	// drop the oldest message, a newer message has the whole table
+	select {
+	case <-client.queue:
+		client.dropped.Add(1)
+		h.dropped.Add(1)
+	default:
+	}
+	select {
+	case client.queue <- msg:
+	default:
+		client.dropped.Add(1)
+		h.dropped.Add(1)
+	}
+}
+
+// serve sends queued messages and pings to the client until the client goes away
+func (h *WebSocketHub) serve(ctx context.Context, client *wsClient) {
+	logger := slog.With("method", "serve", "remote_addr", client.remoteAddr, "producer", client.producer)
+
+	h.register(client)
+	defer h.unregister(client)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		defer cancel()
+		h.readLoop(ctx, client)
+	}()
+
+	var ping <-chan time.Time
+	if config.Conf.WebSocketPingSeconds > 0 {
+		ticker := time.NewTicker(time.Duration(config.Conf.WebSocketPingSeconds) * time.Second)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.slow:
+			logger.WarnContext(ctx, "closing slow WebSocket client", "queue_size", cap(client.queue))
+			client.conn.Close(websocket.StatusPolicyViolation, "slow client")
+			return
+		case msg := <-client.queue:
+			wctx, wcancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := client.conn.Write(wctx, websocket.MessageText, msg.body)
+			wcancel()
+			if err != nil {
+				logger.WarnContext(ctx, "failed to send update to WebSocket", "error", err)
+				return
+			}
+			client.sent.Add(1)
+			h.sent.Add(1)
+		case <-ping:
+			// the pong is received by the read loop
+			pctx, pcancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := client.conn.Ping(pctx)
+			pcancel()
+			if err != nil {
+				logger.WarnContext(ctx, "WebSocket client did not answer ping", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// readLoop reads frames from the client until the connection is closed, control frames (pong, close) are handled while reading.
+// Clients of the events protocol can request to resume or a snapshot, other messages are ignored.
+func (h *WebSocketHub) readLoop(ctx context.Context, client *wsClient) {
+	for {
+		_, b, err := client.conn.Read(ctx)
+		if err != nil {
+			if status := websocket.CloseStatus(err); status != -1 {
+				slog.DebugContext(ctx, "WebSocket closed by client", "remote_addr", client.remoteAddr, "status", status)
+			} else if !errors.Is(err, context.Canceled) {
+				slog.DebugContext(ctx, "failed to read from WebSocket", "remote_addr", client.remoteAddr, "error", err)
+			}
+			return
+		}
+		if !client.events {
+			continue
+		}
+
+		var msg WSClientMessage
+		if err := json.Unmarshal(b, &msg); err != nil {
+			slog.DebugContext(ctx, "invalid message from WebSocket", "remote_addr", client.remoteAddr, "error", err)
+			continue
+		}
+		h.mu.Lock()
+		switch msg.Type {
+		case "resume":
+			h.resume(client, msg.Epoch, msg.Seq)
+		case "snapshot":
+			if frame := h.last[client.producer]; frame != nil {
+				h.enqueueSnapshot(client, frame)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+type AdminWSConnection struct {
+	RemoteAddr string `json:"remote_addr"`
+	Producer   bool   `json:"producer"`
+	// Transport is "websocket" or "sse"
+	Transport   string    `json:"transport"`
+	Events      bool      `json:"events"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Queued      int       `json:"queued"`
+	Sent        int64     `json:"sent"`
+	Dropped     int64     `json:"dropped"`
+}
+
+type GetAdminWSStatsResponse struct {
+	Clients   int `json:"clients"`
+	Producers int `json:"producers"`
+	// counters since the server started
+	Snapshots      int64 `json:"snapshots"`
+	Connections    int64 `json:"connections"`
+	Disconnections int64 `json:"disconnections"`
+	Sent           int64 `json:"sent"`
+	Dropped        int64 `json:"dropped"`
+	ClosedSlow     int64 `json:"closed_slow"`
+	Resumed        int64 `json:"resumed"`
+
+	Active []AdminWSConnection `json:"active"`
+}
+
+// HandleGetAdminWSStats returns connections and counters of /ws, /producer/ws, and /events
+func HandleGetAdminWSStats(c echo.Context) error {
+	resp := GetAdminWSStatsResponse{
+		Snapshots:      wsHub.snapshots.Load(),
+		Connections:    wsHub.connections.Load(),
+		Disconnections: wsHub.disconnections.Load(),
+		Sent:           wsHub.sent.Load(),
+		Dropped:        wsHub.dropped.Load(),
+		ClosedSlow:     wsHub.closedSlow.Load(),
+		Resumed:        wsHub.resumed.Load(),
+		Active:         []AdminWSConnection{},
+	}
+
+	wsHub.mu.Lock()
+	for client := range wsHub.clients {
+		if client.producer {
+			resp.Producers++
+		} else {
+			resp.Clients++
+		}
+		transport := "websocket"
+		if client.sse {
+			transport = "sse"
+		}
+		resp.Active = append(resp.Active, AdminWSConnection{
+			RemoteAddr:  client.remoteAddr,
+			Producer:    client.producer,
+			Transport:   transport,
+			Events:      client.events,
+			ConnectedAt: client.connectedAt,
+			Queued:      len(client.queue),
+			Sent:        client.sent.Load(),
+			Dropped:     client.dropped.Load(),
+		})
+	}
+	wsHub.mu.Unlock()
+
+	sort.SliceStable(resp.Active, func(i, j int) bool {
+		return resp.Active[i].ConnectedAt.Before(resp.Active[j].ConnectedAt)
+	})
+
+	return c.JSON(http.StatusOK, resp)
+}
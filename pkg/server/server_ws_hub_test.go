@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// newTestHub returns a hub with two changes of the public feed: game_started and snapshot (seq 1, 2), and card_added (seq 3)
+func newTestHub(t *testing.T) *WebSocketHub {
+	t.Helper()
+	h := &WebSocketHub{
+		clients:  make(map[*wsClient]struct{}),
+		last:     make(map[bool]*wsFrame),
+		history:  make(map[bool][]wsEncoded),
+		notifyCh: make(chan struct{}, 1),
+	}
+
+	started := &Send{GameID: "g1", Boards: []SendBoard{{Number: 1}}}
+	flop := &Send{GameID: "g1", Boards: []SendBoard{{Number: 1, Cards: []SendCard{{Suit: "hearts", Rank: "A"}}}}}
+	var prev *Send
+	var seq int64
+	for _, cur := range []*Send{started, flop} {
+		frame, err := newWSFrame(prev, cur, seq)
+		if err != nil {
+			t.Fatalf("newWSFrame(): %+v", err)
+		}
+		h.broadcast(frame, false)
+		prev, seq = cur, frame.seq
+	}
+	if seq != 3 {
+		t.Fatalf("seq = %d, want 3", seq)
+	}
+	return h
+}
+
+// queued returns types and sequence numbers of queued messages
+func queued(t *testing.T, client *wsClient) []string {
+	t.Helper()
+	var got []string
+	for len(client.queue) > 0 {
+		msg := <-client.queue
+		var m WSMessage
+		if err := json.Unmarshal(msg.body, &m); err != nil {
+			t.Fatalf("json.Unmarshal(): %+v", err)
+		}
+		if m.Epoch != wsEpoch {
+			t.Errorf("epoch = %q, want %q", m.Epoch, wsEpoch)
+		}
+		got = append(got, fmt.Sprintf("%s:%d", m.Type, m.Seq))
+	}
+	return got
+}
+
+func TestWebSocketHub_Resume(t *testing.T) {
+	tests := []struct {
+		name  string
+		epoch string
+		since int64
+		want  []string
+	}{
+		{name: "messages after seq", epoch: wsEpoch, since: 2, want: []string{"card_added:3"}},
+		{name: "all messages", epoch: wsEpoch, since: 0, want: []string{"game_started:1", "snapshot:2", "card_added:3"}},
+		{name: "up to date", epoch: wsEpoch, since: 3},
+		{name: "another epoch", epoch: "restarted", since: 2, want: []string{"snapshot:3"}},
+		{name: "no epoch", since: 2, want: []string{"snapshot:3"}},
+		{name: "seq ahead of the server", epoch: wsEpoch, since: 5, want: []string{"snapshot:3"}},
+		{name: "new client", epoch: wsEpoch, since: -1, want: []string{"snapshot:3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHub(t)
+			client := &wsClient{
+				events: true,
+				since:  tt.since,
+				epoch:  tt.epoch,
+				queue:  make(chan wsEncoded, 16),
+				slow:   make(chan struct{}),
+			}
+			h.register(client)
+
+			if got := queued(t, client); !slices.Equal(got, tt.want) {
+				t.Errorf("queued = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketHub_ResumeMessage(t *testing.T) {
+	h := newTestHub(t)
+	client := &wsClient{events: true, since: 3, epoch: wsEpoch, queue: make(chan wsEncoded, 16), slow: make(chan struct{})}
+	h.register(client)
+
+	// a resume message of another epoch gets the whole table
+	h.mu.Lock()
+	h.resume(client, "restarted", 1)
+	h.mu.Unlock()
+	if got, want := queued(t, client), []string{"snapshot:3"}; !slices.Equal(got, want) {
+		t.Errorf("queued = %v, want %v", got, want)
+	}
+
+	h.mu.Lock()
+	h.resume(client, wsEpoch, 1)
+	h.mu.Unlock()
+	if got, want := queued(t, client), []string{"snapshot:2", "card_added:3"}; !slices.Equal(got, want) {
+		t.Errorf("queued = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,407 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// Action is an action of a player recorded by the dealer
+type Action string
+
+const (
+	ActionBlind Action = "blind"
+	// ActionAnte is a dead chip, it is not a bet of the street
+	ActionAnte  Action = "ante"
+	ActionBet   Action = "bet"
+	ActionCall  Action = "call"
+	ActionRaise Action = "raise"
+	ActionCheck Action = "check"
+	ActionFold  Action = "fold"
+	// ActionAllIn puts the whole stack into the pot
+	ActionAllIn Action = "allin"
+)
+
+var actions = []Action{
+	ActionBlind,
+	ActionAnte,
+	ActionBet,
+	ActionCall,
+	ActionRaise,
+	ActionCheck,
+	ActionFold,
+	ActionAllIn,
+}
+
+// IsValid returns true if a is a known action
+func (a Action) IsValid() bool {
+	return slices.Contains(actions, a)
+}
+
+var (
+	ErrInvalidAction     = errors.New("invalid action")
+	ErrInsufficientStack = errors.New("insufficient stack")
+	ErrSeatNotFound      = errors.New("seat not found")
+	ErrNoPlayerAction    = errors.New("no action in the game")
+)
+
+// Street returns the betting round of a game state, actions before the flop are preflop
+func Street(state GameState) GameState {
+	switch state {
+	case GameStateFlop, GameStateTurn, GameStateRiver:
+		return state
+	case GameStateShowdown, GameStateFinished:
+		return GameStateRiver
+	default:
+		return GameStatePreflop
+	}
+}
+
+// RecordAction saves the action of the player at the seat in the current game and takes the chips from the stack.
+// amount is the chips put into the pot by the action, a call without amount calls the current bet.
+func RecordAction(ctx context.Context, q *query.Queries, seatNumber int32, action Action, amount int64) (*query.PlayerAction, error) {
+	if !action.IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAction, action)
+	}
+
+	gameID, err := GetOrCreateCurrentGame(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("GetOrCreateCurrentGame(): %w", err)
+	}
+
+	game, err := q.GetGameByID(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetGameByID(): %w", err)
+	}
+	street := Street(GameState(game.State))
+
+	seat, err := q.GetSeatBySeatNumber(ctx, sql.NullInt32{Int32: seatNumber, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSeatNotFound
+		}
+		return nil, fmt.Errorf("q.GetSeatBySeatNumber(): %w", err)
+	}
+
+	if action == ActionCall && amount == 0 {
+		amount, err = toCall(ctx, q, gameID, street, seatNumber)
+		if err != nil {
+			return nil, fmt.Errorf("toCall(): %w", err)
+		}
+		amount = min(amount, seat.Stack)
+	}
+
+	switch action {
+	case ActionCheck, ActionFold:
+		if amount != 0 {
+			return nil, fmt.Errorf("%w: %s with amount", ErrInvalidAction, action)
+		}
+	case ActionAllIn:
+		amount = seat.Stack
+		if amount <= 0 {
+			return nil, ErrInsufficientStack
+		}
+	default:
+		if amount <= 0 {
+			return nil, fmt.Errorf("%w: %s without amount", ErrInvalidAction, action)
+		}
+		if amount > seat.Stack {
+			return nil, ErrInsufficientStack
+		}
+	}
+
+	if err := q.AddPlayerAction(ctx, query.AddPlayerActionParams{
+		GameID:     gameID,
+		SeatNumber: seatNumber,
+		PlayerID:   seat.PlayerID,
+		Street:     string(street),
+		Action:     string(action),
+		Amount:     amount,
+	}); err != nil {
+		return nil, fmt.Errorf("q.AddPlayerAction(): %w", err)
+	}
+	recorded, err := q.GetLastPlayerActionByGameID(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetLastPlayerActionByGameID(): %w", err)
+	}
+
+	// a seat without a player has no stack, so chips are always taken from a player
+	if amount > 0 {
+		if err := q.AddStackToPlayer(ctx, query.AddStackToPlayerParams{
+			Stack: -amount,
+			ID:    seat.PlayerID.Int32,
+		}); err != nil {
+			return nil, fmt.Errorf("q.AddStackToPlayer(): %w", err)
+		}
+	}
+
+	// the equity at the moment of going all-in is kept for the luck statistics
+	if amount > 0 && amount == seat.Stack && seat.PlayerID.Valid {
+		if err := recordAllInEquity(ctx, q, gameID, seat, street); err != nil {
+			return nil, fmt.Errorf("recordAllInEquity(): %w", err)
+		}
+	}
+
+	// the hand of the folded player is out of the game
+	if action == ActionFold {
+		hand, err := q.GetHandBySerial(ctx, seat.Serial)
+		switch {
+		case err == nil:
+			if err := q.MuckHand(ctx, hand.HandID); err != nil {
+				return nil, fmt.Errorf("q.MuckHand(): %w", err)
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			// no hand is read at the seat
+		default:
+			return nil, fmt.Errorf("q.GetHandBySerial(): %w", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "Player action recorded",
+		slog.String("game_id", gameID),
+		slog.String("event", "player_action"),
+		slog.Int("seat_number", int(seatNumber)),
+		slog.String("street", string(street)),
+		slog.String("action", string(action)),
+		slog.Int64("amount", amount))
+
+	return &recorded, nil
+}
+
+// recordAllInEquity saves the current equity of the hand at the seat.
+// If the equity is not calculated yet, it is set by the next CalcEquity.
+func recordAllInEquity(ctx context.Context, q *query.Queries, gameID string, seat query.GetSeatBySeatNumberRow, street GameState) error {
+	var equity sql.NullFloat64
+	hand, err := q.GetHandBySerial(ctx, seat.Serial)
+	switch {
+	case err == nil:
+		equity = hand.Equity
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("q.GetHandBySerial(): %w", err)
+	}
+
+	if err := q.AddAllInEquity(ctx, query.AddAllInEquityParams{
+		GameID:     gameID,
+		PlayerID:   seat.PlayerID.Int32,
+		SeatNumber: seat.SeatNumber.Int32,
+		Street:     string(street),
+		Equity:     equity,
+	}); err != nil {
+		return fmt.Errorf("q.AddAllInEquity(): %w", err)
+	}
+	return nil
+}
+
+// toCall returns the chips to match the biggest bet of the street
+func toCall(ctx context.Context, q *query.Queries, gameID string, street GameState, seatNumber int32) (int64, error) {
+	playerActions, err := q.GetPlayerActionsByGameID(ctx, gameID)
+	if err != nil {
+		return 0, fmt.Errorf("q.GetPlayerActionsByGameID(): %w", err)
+	}
+
+	bets := streetBets(playerActions, street)
+	var highest int64
+	for _, b := range bets {
+		highest = max(highest, b)
+	}
+	return highest - bets[seatNumber], nil
+}
+
+// streetBets returns the chips bet on the street by seats
+func streetBets(playerActions []query.PlayerAction, street GameState) map[int32]int64 {
+	bets := make(map[int32]int64)
+	for _, a := range playerActions {
+		if a.Street == string(street) && Action(a.Action) != ActionAnte {
+			bets[a.SeatNumber] += a.Amount
+		}
+	}
+	return bets
+}
+
+// UndoLastAction deletes the last action of the current game and returns the chips to the stack.
+// The hand mucked by an undone fold is back in the game.
+func UndoLastAction(ctx context.Context, q *query.Queries) (*query.PlayerAction, error) {
+	game, err := q.GetCurrentGame(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoActiveGame
+		}
+		return nil, fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+
+	last, err := q.GetLastPlayerActionByGameID(ctx, game.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoPlayerAction
+		}
+		return nil, fmt.Errorf("q.GetLastPlayerActionByGameID(): %w", err)
+	}
+
+	if err := q.DeletePlayerActionByID(ctx, last.ID); err != nil {
+		return nil, fmt.Errorf("q.DeletePlayerActionByID(): %w", err)
+	}
+	// the player is not all-in after the undo
+	if err := q.DeleteUnsettledAllInEquity(ctx, query.DeleteUnsettledAllInEquityParams{
+		GameID:     game.ID,
+		SeatNumber: last.SeatNumber,
+	}); err != nil {
+		return nil, fmt.Errorf("q.DeleteUnsettledAllInEquity(): %w", err)
+	}
+	if last.Amount > 0 && last.PlayerID.Valid {
+		if err := q.AddStackToPlayer(ctx, query.AddStackToPlayerParams{
+			Stack: last.Amount,
+			ID:    last.PlayerID.Int32,
+		}); err != nil {
+			return nil, fmt.Errorf("q.AddStackToPlayer(): %w", err)
+		}
+	}
+	if Action(last.Action) == ActionFold && last.PlayerID.Valid {
+		if err := q.UnmuckHandByGameIDAndPlayerID(ctx, query.UnmuckHandByGameIDAndPlayerIDParams{
+			GameID:   game.ID,
+			PlayerID: last.PlayerID.Int32,
+		}); err != nil {
+			return nil, fmt.Errorf("q.UnmuckHandByGameIDAndPlayerID(): %w", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "Player action undone",
+		slog.String("game_id", game.ID),
+		slog.String("event", "player_action_undone"),
+		slog.Int("seat_number", int(last.SeatNumber)),
+		slog.String("action", last.Action),
+		slog.Int64("amount", last.Amount))
+	return &last, nil
+}
+
+// TableSeat is chips of a seat in the current game
+type TableSeat struct {
+	SeatNumber int32
+	PlayerID   sql.NullInt32
+	PlayerName string
+	Stack      int64
+	// Contributed is the chips put into the pot in the game
+	Contributed int64
+	// Bet is the chips bet on the current street
+	Bet    int64
+	Folded bool
+	AllIn  bool
+}
+
+// Pot is a main pot or a side pot, Seats are seats eligible to win it
+type Pot struct {
+	Amount int64
+	Seats  []int32
+}
+
+// Table is stacks and pots of the current game
+type Table struct {
+	// GameID is empty if there is no active game
+	GameID   string
+	Street   GameState
+	Seats    []TableSeat
+	Pots     []Pot
+	PotTotal int64
+}
+
+// GetTable returns stacks of seats and pots of the current game
+func GetTable(ctx context.Context, q *query.Queries) (*Table, error) {
+	seats, err := q.GetSeats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetSeats(): %w", err)
+	}
+
+	table := &Table{Street: GameStatePreflop}
+	var playerActions []query.PlayerAction
+	game, err := q.GetCurrentGame(ctx)
+	switch {
+	case err == nil:
+		table.GameID = game.ID
+		table.Street = Street(GameState(game.State))
+		playerActions, err = q.GetPlayerActionsByGameID(ctx, game.ID)
+		if err != nil {
+			return nil, fmt.Errorf("q.GetPlayerActionsByGameID(): %w", err)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+
+	contributed := make(map[int32]int64)
+	folded := make(map[int32]bool)
+	allIn := make(map[int32]bool)
+	for _, a := range playerActions {
+		contributed[a.SeatNumber] += a.Amount
+		table.PotTotal += a.Amount
+		switch Action(a.Action) {
+		case ActionFold:
+			folded[a.SeatNumber] = true
+		case ActionAllIn:
+			allIn[a.SeatNumber] = true
+		}
+	}
+	bets := streetBets(playerActions, table.Street)
+
+	for _, s := range seats {
+		n := s.SeatNumber.Int32
+		table.Seats = append(table.Seats, TableSeat{
+			SeatNumber:  n,
+			PlayerID:    s.PlayerID,
+			PlayerName:  s.PlayerName.String,
+			Stack:       s.Stack,
+			Contributed: contributed[n],
+			Bet:         bets[n],
+			Folded:      folded[n],
+			// a bet of the whole stack is all-in without the allin action
+			AllIn: !folded[n] && (allIn[n] || (contributed[n] > 0 && s.Stack == 0)),
+		})
+	}
+	table.Pots = CalcPots(table.Seats)
+
+	return table, nil
+}
+
+// CalcPots splits chips of seats into the main pot and side pots.
+// A pot is capped by the contribution of an all-in player.
+// Only players who put chips into the pot and have not folded are eligible to it.
+func CalcPots(seats []TableSeat) []Pot {
+	var levels []int64
+	var highest int64
+	for _, s := range seats {
+		highest = max(highest, s.Contributed)
+		if s.AllIn && !s.Folded {
+			levels = append(levels, s.Contributed)
+		}
+	}
+	levels = append(levels, highest)
+	slices.Sort(levels)
+	levels = slices.Compact(levels)
+
+	pots := []Pot{}
+	var prev int64
+	for _, level := range levels {
+		pot := Pot{Seats: []int32{}}
+		for _, s := range seats {
+			pot.Amount += min(s.Contributed, level) - min(s.Contributed, prev)
+			// players still betting can match the level
+			if !s.Folded && s.Contributed > 0 && (s.Contributed >= level || !s.AllIn) {
+				pot.Seats = append(pot.Seats, s.SeatNumber)
+			}
+		}
+		prev = level
+		if pot.Amount == 0 {
+			continue
+		}
+
+		// a level without a new all-in player is a part of the previous pot
+		if n := len(pots); n > 0 && slices.Equal(pots[n-1].Seats, pot.Seats) {
+			pots[n-1].Amount += pot.Amount
+			continue
+		}
+		pots = append(pots, pot)
+	}
+	return pots
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestCalcPots(t *testing.T) {
+	player := sql.NullInt32{Int32: 1, Valid: true}
+
+	tests := []struct {
+		name  string
+		seats []TableSeat
+		want  []Pot
+	}{
+		{
+			name: "main pot",
+			seats: []TableSeat{
+				{SeatNumber: 1, PlayerID: player, Contributed: 100},
+				{SeatNumber: 2, PlayerID: player, Contributed: 100},
+			},
+			want: []Pot{{Amount: 200, Seats: []int32{1, 2}}},
+		},
+		{
+			name: "folded player is not eligible",
+			seats: []TableSeat{
+				{SeatNumber: 1, PlayerID: player, Contributed: 100},
+				{SeatNumber: 2, PlayerID: player, Contributed: 100},
+				{SeatNumber: 3, PlayerID: player, Contributed: 50, Folded: true},
+			},
+			want: []Pot{{Amount: 250, Seats: []int32{1, 2}}},
+		},
+		{
+			name: "seated player without chips in the pot is not eligible",
+			seats: []TableSeat{
+				{SeatNumber: 1, PlayerID: player, Contributed: 100},
+				{SeatNumber: 2, PlayerID: player, Contributed: 100},
+				{SeatNumber: 3, PlayerID: player},
+			},
+			want: []Pot{{Amount: 200, Seats: []int32{1, 2}}},
+		},
+		{
+			name: "side pot",
+			seats: []TableSeat{
+				{SeatNumber: 1, PlayerID: player, Contributed: 50, AllIn: true},
+				{SeatNumber: 2, PlayerID: player, Contributed: 100},
+				{SeatNumber: 3, PlayerID: player, Contributed: 100},
+			},
+			want: []Pot{
+				{Amount: 150, Seats: []int32{1, 2, 3}},
+				{Amount: 100, Seats: []int32{2, 3}},
+			},
+		},
+		{
+			name: "side pot with a fold",
+			seats: []TableSeat{
+				{SeatNumber: 1, PlayerID: player, Contributed: 50, AllIn: true},
+				{SeatNumber: 2, PlayerID: player, Contributed: 200},
+				{SeatNumber: 3, PlayerID: player, Contributed: 100, Folded: true},
+			},
+			want: []Pot{
+				{Amount: 150, Seats: []int32{1, 2}},
+				{Amount: 200, Seats: []int32{2}},
+			},
+		},
+		{
+			name: "players still betting can match the all-in",
+			seats: []TableSeat{
+				{SeatNumber: 1, PlayerID: player, Contributed: 100, AllIn: true},
+				{SeatNumber: 2, PlayerID: player, Contributed: 100},
+				{SeatNumber: 3, PlayerID: player, Contributed: 20},
+			},
+			want: []Pot{{Amount: 220, Seats: []int32{1, 2, 3}}},
+		},
+		{
+			name:  "no chips",
+			seats: []TableSeat{{SeatNumber: 1, PlayerID: player}},
+			want:  []Pot{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalcPots(tt.seats); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CalcPots() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
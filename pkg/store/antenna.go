@@ -11,8 +11,7 @@ import (
 	"github.com/whywaita/rfid-poker/pkg/query"
 )
 
-func GetAntennaBySerial(ctx context.Context, conn *sql.DB, deviceID string, antennaID int) (*query.GetAntennaBySerialRow, error) {
-	q := query.New(conn)
+func GetAntennaBySerial(ctx context.Context, q *query.Queries, deviceID string, antennaID int) (*query.GetAntennaBySerialRow, error) {
 	antenna, err := q.GetAntennaBySerial(ctx, ToSerial(deviceID, antennaID))
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("GetAntennaBySerial(): %w", err)
@@ -24,16 +23,15 @@ func GetAntennaBySerial(ctx context.Context, conn *sql.DB, deviceID string, ante
 	return &antenna, nil
 }
 
-// GetBoardAntennaByDeviceID gets a board antenna by device ID prefix
+// GetBoardAntennaByDeviceID gets the board antenna of the device, a device has one board antenna at most
 // This is used to treat all pair_ids from the same board device as one board
-func GetBoardAntennaByDeviceID(ctx context.Context, conn *sql.DB, deviceID string) (*query.GetBoardAntennaByDeviceIDPrefixRow, error) {
-	q := query.New(conn)
-	antenna, err := q.GetBoardAntennaByDeviceIDPrefix(ctx, deviceID)
+func GetBoardAntennaByDeviceID(ctx context.Context, q *query.Queries, deviceID string) (*query.GetBoardAntennaByDeviceIDRow, error) {
+	antenna, err := q.GetBoardAntennaByDeviceID(ctx, deviceID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, sql.ErrNoRows
 		}
-		return nil, fmt.Errorf("GetBoardAntennaByDeviceIDPrefix(): %w", err)
+		return nil, fmt.Errorf("q.GetBoardAntennaByDeviceID(): %w", err)
 	}
 
 	return &antenna, nil
@@ -42,14 +40,13 @@ func GetBoardAntennaByDeviceID(ctx context.Context, conn *sql.DB, deviceID strin
 // RegisterNewDevice register new device to database
 // serial is device serial number
 // We become unknown as new antenna, that will be registered as new player, muck, board, etc.
-func RegisterNewDevice(ctx context.Context, conn *sql.DB, deviceID string, pairID int) error {
+func RegisterNewDevice(ctx context.Context, q *query.Queries, deviceID string, pairID int) error {
 	s := ToSerial(deviceID, pairID)
-	unknownId, err := GetUnknownAntennaTypeID(ctx, conn)
+	unknownId, err := GetUnknownAntennaTypeID(ctx, q)
 	if err != nil {
 		return fmt.Errorf("GetUnknownAntennaID(): %w", err)
 	}
 
-	q := query.New(conn)
 	if err := q.AddNewAntenna(ctx, query.AddNewAntennaParams{
 		Serial:        s,
 		AntennaTypeID: unknownId,
@@ -105,6 +102,10 @@ const (
 	AntennaTypePlayer
 	AntennaTypeMuck
 	AntennaTypeBoard
+	// AntennaTypeBurn records burn cards
+	AntennaTypeBurn
+	// AntennaTypeRabbit records cards revealed after the hand ends (rabbit hunting)
+	AntennaTypeRabbit
 )
 
 // GetAntennaType get antenna type
@@ -116,6 +117,10 @@ func GetAntennaType(antennaTypeName string) AntennaType {
 		return AntennaTypeMuck
 	case "board":
 		return AntennaTypeBoard
+	case "burn":
+		return AntennaTypeBurn
+	case "rabbit":
+		return AntennaTypeRabbit
 	default:
 		return AntennaTypeUnknown
 	}
@@ -130,14 +135,17 @@ func (at AntennaType) String() string {
 		return "muck"
 	case AntennaTypeBoard:
 		return "board"
+	case AntennaTypeBurn:
+		return "burn"
+	case AntennaTypeRabbit:
+		return "rabbit"
 	default:
 		return "unknown"
 	}
 }
 
 // GetUnknownAntennaTypeID get unknown antenna type id
-func GetUnknownAntennaTypeID(ctx context.Context, conn *sql.DB) (int32, error) {
-	q := query.New(conn)
+func GetUnknownAntennaTypeID(ctx context.Context, q *query.Queries) (int32, error) {
 	antennaTypeID, err := q.GetAntennaTypeIdIsUnknown(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("GetAntennaByAntennaTypeName(): %w", err)
@@ -0,0 +1,434 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/whywaita/poker-go"
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// Award is chips won by a seat from pots
+type Award struct {
+	SeatNumber int32
+	PlayerID   sql.NullInt32
+	Amount     int64
+	// Eligible is chips of awarded pots the seat could win
+	Eligible int64
+}
+
+// UnawardedReason is why a pot is not awarded when the game finishes
+type UnawardedReason string
+
+const (
+	// UnawardedNoHand is a pot without a hand of eligible players, e.g. hands are not read
+	UnawardedNoHand UnawardedReason = "no_hand"
+	// UnawardedMissingHand is a pot some eligible players have no hand of, e.g. a card is not read
+	UnawardedMissingHand UnawardedReason = "missing_hand"
+	// UnawardedIncompleteBoard is a pot to showdown before all boards are complete
+	UnawardedIncompleteBoard UnawardedReason = "incomplete_board"
+	// UnawardedNoPlayer is chips won by a seat without a player
+	UnawardedNoPlayer UnawardedReason = "no_player"
+)
+
+var (
+	ErrPotNotFound = errors.New("pot not found")
+	ErrPotAwarded  = errors.New("pot is already awarded")
+	ErrNotEligible = errors.New("player is not eligible to the pot")
+)
+
+// settledPots is chips of pots won by seats, and pots that can't be awarded
+type settledPots struct {
+	won       map[int32]int64 // key: seat_number
+	eligible  map[int32]int64 // key: seat_number
+	unawarded []unawardedPot
+}
+
+type unawardedPot struct {
+	Pot
+	reason UnawardedReason
+}
+
+// settlePots decides winners of pots by hands not mucked of seats.
+// A pot with one player left is paid without showdown, otherwise all eligible seats must have a hand and all boards must be complete.
+func settlePots(pots []Pot, hands map[int32][]poker.Card, boards []Board, button sql.NullInt32) settledPots {
+	settled := settledPots{
+		won:      make(map[int32]int64),
+		eligible: make(map[int32]int64),
+	}
+	for _, pot := range pots {
+		contenders := make([]int32, 0, len(pot.Seats))
+		for _, seat := range pot.Seats {
+			if _, ok := hands[seat]; ok {
+				contenders = append(contenders, seat)
+			}
+		}
+		switch {
+		case len(pot.Seats) == 1:
+			// an uncalled bet goes back to the player
+			settled.won[pot.Seats[0]] += pot.Amount
+			settled.eligible[pot.Seats[0]] += pot.Amount
+			continue
+		case len(contenders) == 0:
+			settled.unawarded = append(settled.unawarded, unawardedPot{Pot: pot, reason: UnawardedNoHand})
+			continue
+		case len(contenders) < len(pot.Seats):
+			// the seat without a hand may win the pot
+			settled.unawarded = append(settled.unawarded, unawardedPot{Pot: pot, reason: UnawardedMissingHand})
+			continue
+		}
+
+		if slices.ContainsFunc(boards, func(b Board) bool { return len(b.Cards) != 5 }) {
+			settled.unawarded = append(settled.unawarded, unawardedPot{Pot: pot, reason: UnawardedIncompleteBoard})
+			continue
+		}
+
+		for _, seat := range contenders {
+			settled.eligible[seat] += pot.Amount
+		}
+		// each board wins an equal share of the pot, the odd chip goes to the first board
+		for j, b := range boards {
+			share := pot.Amount / int64(len(boards))
+			if int64(j) < pot.Amount%int64(len(boards)) {
+				share++
+			}
+			winners := showdownWinners(contenders, hands, b.Cards)
+			for seat, amount := range SplitPot(share, winners, button) {
+				settled.won[seat] += amount
+			}
+		}
+	}
+	return settled
+}
+
+// awardPots pays the main pot and side pots of the current game to winners and adds the chips to their stacks.
+// Pots that can't be awarded are saved to be awarded by the dealer with AwardUnawardedPot.
+func awardPots(ctx context.Context, q *query.Queries, game query.Game) ([]Award, error) {
+	logger := slog.With("method", "awardPots")
+
+	table, err := GetTable(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("GetTable(): %w", err)
+	}
+	if table.GameID != game.ID || table.PotTotal == 0 {
+		return nil, nil
+	}
+
+	stored, err := GetStored(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("GetStored(): %w", err)
+	}
+	boards, err := GetBoards(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("GetBoards(): %w", err)
+	}
+
+	// hands not mucked by seat
+	hands := make(map[int32][]poker.Card)
+	playerIDs := make(map[int32]sql.NullInt32)
+	for _, s := range table.Seats {
+		playerIDs[s.SeatNumber] = s.PlayerID
+		for _, st := range stored {
+			if s.PlayerID.Valid && s.PlayerID.Int32 == st.PlayerID {
+				hands[s.SeatNumber] = st.Hand
+			}
+		}
+	}
+
+	settled := settlePots(table.Pots, hands, boards, game.ButtonPosition)
+	for _, pot := range settled.unawarded {
+		eligible := make([]int32, 0, len(pot.Seats))
+		for _, seat := range pot.Seats {
+			if id := playerIDs[seat]; id.Valid {
+				eligible = append(eligible, id.Int32)
+			}
+		}
+		if err := addUnawardedPot(ctx, q, game.ID, pot.Amount, eligible, pot.reason); err != nil {
+			return nil, fmt.Errorf("addUnawardedPot(): %w", err)
+		}
+		logger.WarnContext(ctx, "pot is not awarded", "game_id", game.ID, "reason", pot.reason, "amount", pot.Amount)
+	}
+
+	// losers of pots are awarded nothing
+	awards := make([]Award, 0, len(settled.eligible))
+	for _, s := range table.Seats {
+		if _, ok := settled.eligible[s.SeatNumber]; !ok {
+			continue
+		}
+		amount := settled.won[s.SeatNumber]
+		awards = append(awards, Award{
+			SeatNumber: s.SeatNumber,
+			PlayerID:   playerIDs[s.SeatNumber],
+			Amount:     amount,
+			Eligible:   settled.eligible[s.SeatNumber],
+		})
+		if amount == 0 {
+			continue
+		}
+
+		if !s.PlayerID.Valid {
+			// the player left the seat, the dealer gives the chips back by hand
+			if err := addUnawardedPot(ctx, q, game.ID, amount, nil, UnawardedNoPlayer); err != nil {
+				return nil, fmt.Errorf("addUnawardedPot(): %w", err)
+			}
+			logger.WarnContext(ctx, "pot is not awarded", "game_id", game.ID, "reason", UnawardedNoPlayer, "seat_number", s.SeatNumber, "amount", amount)
+			continue
+		}
+		if err := q.AddStackToPlayer(ctx, query.AddStackToPlayerParams{
+			Stack: amount,
+			ID:    s.PlayerID.Int32,
+		}); err != nil {
+			return nil, fmt.Errorf("q.AddStackToPlayer(): %w", err)
+		}
+
+		slog.InfoContext(ctx, "Pot awarded",
+			slog.String("game_id", game.ID),
+			slog.String("event", "pot_awarded"),
+			slog.Int("seat_number", int(s.SeatNumber)),
+			slog.Int64("amount", amount))
+	}
+	return awards, nil
+}
+
+func addUnawardedPot(ctx context.Context, q *query.Queries, gameID string, amount int64, playerIDs []int32, reason UnawardedReason) error {
+	if playerIDs == nil {
+		playerIDs = []int32{}
+	}
+	b, err := json.Marshal(playerIDs)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(): %w", err)
+	}
+	if err := q.AddUnawardedPot(ctx, query.AddUnawardedPotParams{
+		GameID:    gameID,
+		Amount:    amount,
+		PlayerIds: b,
+		Reason:    string(reason),
+	}); err != nil {
+		return fmt.Errorf("q.AddUnawardedPot(): %w", err)
+	}
+	return nil
+}
+
+// UnawardedPot is a pot left to the dealer when the game finished
+type UnawardedPot struct {
+	ID     int32
+	GameID string
+	Amount int64
+	// PlayerIDs is players eligible to win the pot, any player can win it if empty
+	PlayerIDs []int32
+	Reason    UnawardedReason
+	CreatedAt time.Time
+}
+
+func toUnawardedPot(p query.UnawardedPot) (UnawardedPot, error) {
+	var playerIDs []int32
+	if err := json.Unmarshal(p.PlayerIds, &playerIDs); err != nil {
+		return UnawardedPot{}, fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+	return UnawardedPot{
+		ID:        p.ID,
+		GameID:    p.GameID,
+		Amount:    p.Amount,
+		PlayerIDs: playerIDs,
+		Reason:    UnawardedReason(p.Reason),
+		CreatedAt: p.CreatedAt,
+	}, nil
+}
+
+// GetUnawardedPots returns pots not awarded yet in order
+func GetUnawardedPots(ctx context.Context, q *query.Queries) ([]UnawardedPot, error) {
+	rows, err := q.GetUnawardedPots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetUnawardedPots(): %w", err)
+	}
+
+	pots := make([]UnawardedPot, 0, len(rows))
+	for _, row := range rows {
+		pot, err := toUnawardedPot(row)
+		if err != nil {
+			return nil, fmt.Errorf("toUnawardedPot(): %w", err)
+		}
+		pots = append(pots, pot)
+	}
+	return pots, nil
+}
+
+// AwardUnawardedPot pays the pot to winners chosen by the dealer and adds the chips to their stacks.
+// Winners split the pot equally, odd chips go one by one from the lowest player ID. Returns chips won by players.
+func AwardUnawardedPot(ctx context.Context, conn *sql.DB, id int32, winners []int32) (map[int32]int64, error) {
+	winners = slices.Compact(slices.Sorted(slices.Values(winners)))
+	if len(winners) == 0 {
+		return nil, fmt.Errorf("%w: no winner", ErrNotEligible)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("conn.BeginTx(): %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil || err != nil {
+			tx.Rollback()
+		}
+	}()
+	q := query.New(tx)
+
+	row, err := q.GetUnawardedPotByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrPotNotFound
+			return nil, err
+		}
+		return nil, fmt.Errorf("q.GetUnawardedPotByID(): %w", err)
+	}
+	if row.AwardedAt.Valid {
+		err = ErrPotAwarded
+		return nil, err
+	}
+	pot, err := toUnawardedPot(row)
+	if err != nil {
+		return nil, fmt.Errorf("toUnawardedPot(): %w", err)
+	}
+
+	for _, playerID := range winners {
+		if len(pot.PlayerIDs) > 0 && !slices.Contains(pot.PlayerIDs, playerID) {
+			err = fmt.Errorf("%w: %d", ErrNotEligible, playerID)
+			return nil, err
+		}
+		if _, err = q.GetPlayer(ctx, playerID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = fmt.Errorf("%w: %d", ErrNotEligible, playerID)
+				return nil, err
+			}
+			return nil, fmt.Errorf("q.GetPlayer(): %w", err)
+		}
+	}
+
+	won := SplitPot(pot.Amount, winners, sql.NullInt32{})
+	for _, playerID := range winners {
+		if err = q.AddStackToPlayer(ctx, query.AddStackToPlayerParams{
+			Stack: won[playerID],
+			ID:    playerID,
+		}); err != nil {
+			return nil, fmt.Errorf("q.AddStackToPlayer(): %w", err)
+		}
+		// the history of the game is kept if the game is finished, not if it is deleted
+		if err = q.AddWonToHandHistory(ctx, query.AddWonToHandHistoryParams{
+			Won:      won[playerID],
+			GameID:   pot.GameID,
+			PlayerID: playerID,
+		}); err != nil {
+			return nil, fmt.Errorf("q.AddWonToHandHistory(): %w", err)
+		}
+	}
+	if err = q.SetUnawardedPotAwarded(ctx, id); err != nil {
+		return nil, fmt.Errorf("q.SetUnawardedPotAwarded(): %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("tx.Commit(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Pot awarded by hand",
+		slog.String("game_id", pot.GameID),
+		slog.String("event", "pot_awarded"),
+		slog.Int("pot_id", int(id)),
+		slog.Int64("amount", pot.Amount))
+	return won, nil
+}
+
+// Showdown is the result of a hand at showdown
+type Showdown struct {
+	PlayerID int32
+	// Won is true if the hand is the best on any board
+	Won bool
+	// HandCategory is the made hand on the first board (e.g. "Two Pair")
+	HandCategory string
+}
+
+// getShowdowns returns hands at showdown of the current game, a showdown is two or more hands not mucked with the complete board
+func getShowdowns(ctx context.Context, q *query.Queries) ([]Showdown, error) {
+	stored, err := GetStored(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("GetStored(): %w", err)
+	}
+	boards, err := GetBoards(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("GetBoards(): %w", err)
+	}
+	if len(stored) < 2 || slices.ContainsFunc(boards, func(b Board) bool { return len(b.Cards) != 5 }) {
+		return nil, nil
+	}
+
+	players := make([]int32, 0, len(stored))
+	hands := make(map[int32][]poker.Card, len(stored))
+	for _, s := range stored {
+		players = append(players, s.PlayerID)
+		hands[s.PlayerID] = s.Hand
+	}
+
+	won := make(map[int32]bool)
+	for _, b := range boards {
+		for _, id := range showdownWinners(players, hands, b.Cards) {
+			won[id] = true
+		}
+	}
+
+	showdowns := make([]Showdown, 0, len(stored))
+	for _, s := range stored {
+		showdowns = append(showdowns, Showdown{
+			PlayerID:     s.PlayerID,
+			Won:          won[s.PlayerID],
+			HandCategory: poker.NewBestMadeHand(append(slices.Clone(s.Hand), boards[0].Cards...)).Type().String(),
+		})
+	}
+	return showdowns, nil
+}
+
+// showdownWinners returns keys (seats or players) of the best hands on the board
+func showdownWinners(keys []int32, hands map[int32][]poker.Card, board []poker.Card) []int32 {
+	var winners []int32
+	best := -1
+	for _, key := range keys {
+		power := poker.NewBestMadeHand(append(slices.Clone(hands[key]), board...)).Power()
+		switch {
+		case power > best:
+			best = power
+			winners = []int32{key}
+		case power == best:
+			winners = append(winners, key)
+		}
+	}
+	return winners
+}
+
+// SplitPot splits chips equally between winners.
+// Odd chips go one by one to winners in order from the left of the button, or from the lowest seat without the button.
+func SplitPot(amount int64, winners []int32, button sql.NullInt32) map[int32]int64 {
+	split := make(map[int32]int64, len(winners))
+	if len(winners) == 0 {
+		return split
+	}
+
+	ordered := slices.Clone(winners)
+	slices.Sort(ordered)
+	if button.Valid {
+		// seats after the button come first
+		i, _ := slices.BinarySearch(ordered, button.Int32+1)
+		ordered = slices.Concat(ordered[i:], ordered[:i])
+	}
+
+	n := int64(len(ordered))
+	for i, seat := range ordered {
+		split[seat] = amount / n
+		if int64(i) < amount%n {
+			split[seat]++
+		}
+	}
+	return split
+}
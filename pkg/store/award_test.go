@@ -0,0 +1,150 @@
+package store
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/whywaita/poker-go"
+)
+
+func TestSettlePots(t *testing.T) {
+	board := []poker.Card{
+		{Rank: poker.RankDeuce, Suit: poker.Spades},
+		{Rank: poker.RankSeven, Suit: poker.Clubs},
+		{Rank: poker.RankNine, Suit: poker.Diamonds},
+		{Rank: poker.RankJack, Suit: poker.Spades},
+		{Rank: poker.RankThree, Suit: poker.Hearts},
+	}
+	aces := []poker.Card{{Rank: poker.RankAce, Suit: poker.Hearts}, {Rank: poker.RankAce, Suit: poker.Spades}}
+	kings := []poker.Card{{Rank: poker.RankKing, Suit: poker.Hearts}, {Rank: poker.RankKing, Suit: poker.Spades}}
+	queens := []poker.Card{{Rank: poker.RankQueen, Suit: poker.Hearts}, {Rank: poker.RankQueen, Suit: poker.Spades}}
+	akHearts := []poker.Card{{Rank: poker.RankAce, Suit: poker.Hearts}, {Rank: poker.RankKing, Suit: poker.Hearts}}
+	akClubs := []poker.Card{{Rank: poker.RankAce, Suit: poker.Clubs}, {Rank: poker.RankKing, Suit: poker.Clubs}}
+	button := sql.NullInt32{Int32: 1, Valid: true}
+
+	tests := []struct {
+		name         string
+		pots         []Pot
+		hands        map[int32][]poker.Card
+		boards       []Board
+		wantWon      map[int32]int64
+		wantEligible map[int32]int64
+		wantReasons  []UnawardedReason
+	}{
+		{
+			name: "side pot",
+			pots: []Pot{
+				{Amount: 150, Seats: []int32{1, 2, 3}},
+				{Amount: 100, Seats: []int32{2, 3}},
+			},
+			hands:        map[int32][]poker.Card{1: aces, 2: kings, 3: queens},
+			boards:       []Board{{Number: 1, Cards: board}},
+			wantWon:      map[int32]int64{1: 150, 2: 100},
+			wantEligible: map[int32]int64{1: 150, 2: 250, 3: 250},
+		},
+		{
+			name:         "one player left",
+			pots:         []Pot{{Amount: 200, Seats: []int32{2}}},
+			hands:        map[int32][]poker.Card{2: kings},
+			boards:       []Board{{Number: 1, Cards: board[:3]}},
+			wantWon:      map[int32]int64{2: 200},
+			wantEligible: map[int32]int64{2: 200},
+		},
+		{
+			name:         "uncalled bet",
+			pots:         []Pot{{Amount: 300, Seats: []int32{2}}},
+			boards:       []Board{{Number: 1}},
+			wantWon:      map[int32]int64{2: 300},
+			wantEligible: map[int32]int64{2: 300},
+		},
+		{
+			name:         "tie with the odd chip",
+			pots:         []Pot{{Amount: 101, Seats: []int32{1, 2}}},
+			hands:        map[int32][]poker.Card{1: akHearts, 2: akClubs},
+			boards:       []Board{{Number: 1, Cards: board}},
+			wantWon:      map[int32]int64{1: 50, 2: 51},
+			wantEligible: map[int32]int64{1: 101, 2: 101},
+		},
+		{
+			name:  "run it twice",
+			pots:  []Pot{{Amount: 101, Seats: []int32{1, 2}}},
+			hands: map[int32][]poker.Card{1: queens, 2: kings},
+			boards: []Board{
+				{Number: 1, Cards: append(board[:4:4], poker.Card{Rank: poker.RankQueen, Suit: poker.Clubs})},
+				{Number: 2, SharedCards: 4, Cards: append(board[:4:4], poker.Card{Rank: poker.RankFour, Suit: poker.Clubs})},
+			},
+			wantWon:      map[int32]int64{1: 51, 2: 50},
+			wantEligible: map[int32]int64{1: 101, 2: 101},
+		},
+		{
+			name:         "incomplete board",
+			pots:         []Pot{{Amount: 200, Seats: []int32{1, 2}}},
+			hands:        map[int32][]poker.Card{1: aces, 2: kings},
+			boards:       []Board{{Number: 1, Cards: board[:3]}},
+			wantWon:      map[int32]int64{},
+			wantEligible: map[int32]int64{},
+			wantReasons:  []UnawardedReason{UnawardedIncompleteBoard},
+		},
+		{
+			name:         "missing hand",
+			pots:         []Pot{{Amount: 200, Seats: []int32{1, 2}}},
+			hands:        map[int32][]poker.Card{2: kings},
+			boards:       []Board{{Number: 1, Cards: board}},
+			wantWon:      map[int32]int64{},
+			wantEligible: map[int32]int64{},
+			wantReasons:  []UnawardedReason{UnawardedMissingHand},
+		},
+		{
+			name:         "no hand",
+			pots:         []Pot{{Amount: 200, Seats: []int32{1, 2}}},
+			boards:       []Board{{Number: 1, Cards: board}},
+			wantWon:      map[int32]int64{},
+			wantEligible: map[int32]int64{},
+			wantReasons:  []UnawardedReason{UnawardedNoHand},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := settlePots(tt.pots, tt.hands, tt.boards, button)
+			if !reflect.DeepEqual(got.won, tt.wantWon) {
+				t.Errorf("won = %v, want %v", got.won, tt.wantWon)
+			}
+			if !reflect.DeepEqual(got.eligible, tt.wantEligible) {
+				t.Errorf("eligible = %v, want %v", got.eligible, tt.wantEligible)
+			}
+			var reasons []UnawardedReason
+			for _, p := range got.unawarded {
+				reasons = append(reasons, p.reason)
+			}
+			if !reflect.DeepEqual(reasons, tt.wantReasons) {
+				t.Errorf("unawarded = %v, want %v", reasons, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestSplitPot(t *testing.T) {
+	tests := []struct {
+		name   string
+		button sql.NullInt32
+		want   map[int32]int64
+	}{
+		{
+			name:   "odd chips from the left of the button",
+			button: sql.NullInt32{Int32: 3, Valid: true},
+			want:   map[int32]int64{5: 4, 1: 3, 3: 3},
+		},
+		{
+			name: "odd chips from the lowest seat without the button",
+			want: map[int32]int64{1: 4, 3: 3, 5: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitPot(10, []int32{5, 1, 3}, tt.button); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitPot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
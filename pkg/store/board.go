@@ -9,46 +9,59 @@ import (
 	"slices"
 	"sort"
 
+	"entgo.io/ent/dialect/sql/sqlgraph"
 	"github.com/whywaita/poker-go"
 	"github.com/whywaita/rfid-poker/pkg/query"
 )
 
 var (
 	ErrBoardCardLimitExceeded = errors.New("board card limit exceeded (max 5 cards)")
+	ErrHandInProgress         = errors.New("hand has not ended")
+	ErrInvalidSharedCards     = errors.New("shared cards must be within cards of the first board")
 )
 
-func AddBoard(ctx context.Context, conn *sql.DB, cards []poker.Card, serial string) (bool, error) {
+// Board is a board of the current game. A game has more than one board on run it twice or double-board games.
+type Board struct {
+	Number int32
+	// SharedCards is the number of cards shared with the first board
+	SharedCards int
+	// Cards includes shared cards
+	Cards []poker.Card
+}
+
+// AddBoard stores cards to the board read by the antenna, and returns true if new cards are stored.
+// q must be of a transaction.
+func AddBoard(ctx context.Context, q *query.Queries, cards []poker.Card, serial string) (bool, error) {
 	// Get or create current game
-	gameID, err := GetOrCreateCurrentGame(ctx, conn)
+	gameID, err := GetOrCreateCurrentGame(ctx, q)
 	if err != nil {
 		return false, fmt.Errorf("GetOrCreateCurrentGame(): %w", err)
 	}
 
-	tx, err := conn.BeginTx(ctx, nil)
+	boardNumber, err := boardNumberBySerial(ctx, q, gameID, serial)
 	if err != nil {
-		return false, fmt.Errorf("conn.BeginTx(): %w", err)
+		return false, fmt.Errorf("boardNumberBySerial(): %w", err)
 	}
-	defer func() {
-		if r := recover(); r != nil || err != nil {
-			tx.Rollback()
-		}
-	}()
-	qWithTx := query.New(tx)
 
-	nowBoard, err := GetBoardAll(ctx, qWithTx)
+	boards, err := GetBoards(ctx, q)
 	if err != nil {
-		tx.Rollback()
-		return false, fmt.Errorf("GetBoard(): %w", err)
+		return false, fmt.Errorf("GetBoards(): %w", err)
+	}
+	var nowBoard []poker.Card
+	for _, b := range boards {
+		if b.Number == boardNumber {
+			nowBoard = b.Cards
+		}
 	}
 
 	board, needInsert, isUpdated := concatCards(nowBoard, cards)
 
 	// Check if adding new cards would exceed the limit (max 5 board cards)
 	if len(board) > 5 {
-		tx.Rollback()
 		slog.WarnContext(ctx, "Board card limit exceeded, rejecting request",
 			slog.String("game_id", gameID),
 			slog.String("event", "board_card_limit_exceeded"),
+			slog.Int("board_number", int(boardNumber)),
 			slog.Int("current_board_count", len(nowBoard)),
 			slog.Int("attempted_total", len(board)))
 		return false, ErrBoardCardLimitExceeded
@@ -56,14 +69,17 @@ func AddBoard(ctx context.Context, conn *sql.DB, cards []poker.Card, serial stri
 
 	if len(needInsert) > 0 {
 		for _, c := range needInsert {
-			err := qWithTx.AddCardToBoard(ctx, query.AddCardToBoardParams{
-				CardSuit: c.Suit.String(),
-				CardRank: c.Rank.String(),
-				Serial:   serial,
-				GameID:   gameID,
+			err := q.AddCardToBoard(ctx, query.AddCardToBoardParams{
+				CardSuit:    c.Suit.String(),
+				CardRank:    c.Rank.String(),
+				Serial:      serial,
+				GameID:      gameID,
+				BoardNumber: boardNumber,
 			})
 			if err != nil {
-				tx.Rollback()
+				if sqlgraph.IsUniqueConstraintError(err) {
+					return false, ErrCardInPlay
+				}
 				return false, fmt.Errorf("query.AddCardToBoard(): %w", err)
 			}
 			slog.InfoContext(ctx, "Added board card",
@@ -73,35 +89,166 @@ func AddBoard(ctx context.Context, conn *sql.DB, cards []poker.Card, serial stri
 				slog.String("card_suit", c.Suit.String()),
 				slog.Bool("is_board", true),
 				slog.String("serial", serial),
+				slog.Int("board_number", int(boardNumber)),
 				slog.Int("board_card_count", len(board)+1))
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return false, fmt.Errorf("tx.Commit(): %w", err)
+	return isUpdated, nil
+}
+
+// boardNumberBySerial returns the board number of cards read by the board antenna.
+// If there are two or more board antennas, each antenna is a board in order of antenna ID (double-board).
+// Otherwise, cards go to the last board of the game (run it twice by AddNextBoard).
+func boardNumberBySerial(ctx context.Context, q *query.Queries, gameID, serial string) (int32, error) {
+	antennas, err := q.GetAntenna(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("q.GetAntenna(): %w", err)
+	}
+	var boardAntennas []query.GetAntennaRow
+	for _, a := range antennas {
+		if GetAntennaType(a.AntennaTypeName) == AntennaTypeBoard {
+			boardAntennas = append(boardAntennas, a)
+		}
+	}
+
+	if len(boardAntennas) >= 2 {
+		sort.SliceStable(boardAntennas, func(i, j int) bool {
+			return boardAntennas[i].ID < boardAntennas[j].ID
+		})
+		for i, a := range boardAntennas {
+			if a.Serial != serial {
+				continue
+			}
+			number := int32(i + 1)
+			if number > 1 {
+				if err := q.AddGameBoard(ctx, query.AddGameBoardParams{
+					GameID:      gameID,
+					BoardNumber: number,
+				}); err != nil {
+					return 0, fmt.Errorf("q.AddGameBoard(): %w", err)
+				}
+			}
+			return number, nil
+		}
 	}
 
-	return isUpdated, nil
+	gameBoards, err := q.GetGameBoards(ctx, gameID)
+	if err != nil {
+		return 0, fmt.Errorf("q.GetGameBoards(): %w", err)
+	}
+	if len(gameBoards) == 0 {
+		return 1, nil
+	}
+	return gameBoards[len(gameBoards)-1].BoardNumber, nil
+}
+
+// AddNextBoard adds a board to the current game, the next board cards read by the board antenna go to the new board.
+// sharedCards is the number of cards of the first board shared with the new board.
+func AddNextBoard(ctx context.Context, conn *sql.DB, sharedCards int) (int32, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("conn.BeginTx(): %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil || err != nil {
+			tx.Rollback()
+		}
+	}()
+	q := query.New(tx)
+
+	gameID, err := GetOrCreateCurrentGame(ctx, q)
+	if err != nil {
+		return 0, fmt.Errorf("GetOrCreateCurrentGame(): %w", err)
+	}
+
+	boards, err := GetBoards(ctx, q)
+	if err != nil {
+		return 0, fmt.Errorf("GetBoards(): %w", err)
+	}
+	if sharedCards < 0 || sharedCards > len(boards[0].Cards) {
+		err = fmt.Errorf("%w (shared_cards: %d, first board: %d cards)", ErrInvalidSharedCards, sharedCards, len(boards[0].Cards))
+		return 0, err
+	}
+
+	number := boards[len(boards)-1].Number + 1
+	if err = q.AddGameBoard(ctx, query.AddGameBoardParams{
+		GameID:      gameID,
+		BoardNumber: number,
+		SharedCards: int32(sharedCards),
+	}); err != nil {
+		return 0, fmt.Errorf("q.AddGameBoard(): %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("tx.Commit(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Added next board",
+		slog.String("game_id", gameID),
+		slog.String("event", "board_added"),
+		slog.Int("board_number", int(number)),
+		slog.Int("shared_cards", sharedCards))
+	return number, nil
 }
 
-func GetBoardAll(ctx context.Context, q *query.Queries) ([]poker.Card, error) {
+// GetBoards returns all boards of the current game, the first board always exists
+func GetBoards(ctx context.Context, q *query.Queries) ([]Board, error) {
 	cards, err := q.GetBoard(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("db.GetBoard(): %w", err)
 	}
-
-	var board []poker.Card
+	// order by oldest
+	sort.SliceStable(cards, func(i, j int) bool {
+		return cards[i].ID < cards[j].ID
+	})
+	own := make(map[int32][]poker.Card)
 	for _, c := range cards {
 		card, err := query.Card{CardSuit: c.CardSuit, CardRank: c.CardRank}.ToPokerGo()
 		if err != nil {
 			return nil, fmt.Errorf("card.ToPokerGo(): %w", err)
 		}
-		board = append(board, *card)
+		own[c.BoardNumber] = append(own[c.BoardNumber], *card)
 	}
 
-	return board, nil
+	boards := []Board{{Number: 1}}
+	game, err := q.GetCurrentGame(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+	if err == nil {
+		gameBoards, err := q.GetGameBoards(ctx, game.ID)
+		if err != nil {
+			return nil, fmt.Errorf("q.GetGameBoards(): %w", err)
+		}
+		for _, gb := range gameBoards {
+			boards = append(boards, Board{Number: gb.BoardNumber, SharedCards: int(gb.SharedCards)})
+		}
+	}
+
+	first := own[1]
+	if len(first) > 5 {
+		first = first[:5]
+	}
+	for i := range boards {
+		shared := min(boards[i].SharedCards, len(first))
+		b := make([]poker.Card, 0, 5)
+		b = append(b, first[:shared]...)
+		if boards[i].Number == 1 {
+			b = append(b, first...)
+		} else {
+			b = append(b, own[boards[i].Number]...)
+		}
+		if len(b) > 5 {
+			b = b[:5]
+		}
+		boards[i].Cards = b
+	}
+
+	return boards, nil
 }
 
+// GetBoard returns cards of the first board
 func GetBoard(ctx context.Context, q *query.Queries) ([]poker.Card, error) {
 	cards, err := q.GetBoard(ctx)
 	if err != nil {
@@ -112,12 +259,14 @@ func GetBoard(ctx context.Context, q *query.Queries) ([]poker.Card, error) {
 	sort.SliceStable(cards, func(i, j int) bool {
 		return cards[i].ID < cards[j].ID
 	})
-	if len(cards) > 5 {
-		cards = cards[:5]
-	}
-
 	var board []poker.Card
 	for _, c := range cards {
+		if c.BoardNumber != 1 {
+			continue
+		}
+		if len(board) == 5 {
+			break
+		}
 		card, err := query.Card{CardSuit: c.CardSuit, CardRank: c.CardRank}.ToPokerGo()
 		if err != nil {
 			return nil, fmt.Errorf("card.ToPokerGo(): %w", err)
@@ -148,3 +297,52 @@ func concatCards(already, newCards []poker.Card) ([]poker.Card, []poker.Card, bo
 
 	return concat, needInsert, isUpdated
 }
+
+// AddRabbit stores a card revealed after the hand ends, rabbit cards complete the board up to 5 cards.
+// It returns ErrHandInProgress until all but one player have mucked. q must be of a transaction.
+func AddRabbit(ctx context.Context, q *query.Queries, card poker.Card, serial string) error {
+	progress, err := GetGameProgress(ctx, q)
+	if err != nil {
+		return fmt.Errorf("GetGameProgress(): %w", err)
+	}
+	if progress == nil || !progress.OnePlayerLeft() {
+		return ErrHandInProgress
+	}
+
+	board, err := GetBoard(ctx, q)
+	if err != nil {
+		return fmt.Errorf("GetBoard(): %w", err)
+	}
+	rabbit, err := GetRabbit(ctx, q)
+	if err != nil {
+		return fmt.Errorf("GetRabbit(): %w", err)
+	}
+	if len(board)+len(rabbit) >= 5 {
+		return ErrBoardCardLimitExceeded
+	}
+
+	if err := AddCard(ctx, q, card, serial); err != nil {
+		return fmt.Errorf("AddCard(): %w", err)
+	}
+	return nil
+}
+
+// GetRabbit returns cards revealed after the hand ends, order by read.
+// Rabbit cards are not a part of the board, so they are excluded from equity and history.
+func GetRabbit(ctx context.Context, q *query.Queries) ([]poker.Card, error) {
+	cards, err := q.GetCardsByAntennaTypeName(ctx, AntennaTypeRabbit.String())
+	if err != nil {
+		return nil, fmt.Errorf("q.GetCardsByAntennaTypeName(): %w", err)
+	}
+
+	var rabbit []poker.Card
+	for _, c := range cards {
+		card, err := query.Card{CardSuit: c.CardSuit, CardRank: c.CardRank}.ToPokerGo()
+		if err != nil {
+			return nil, fmt.Errorf("card.ToPokerGo(): %w", err)
+		}
+		rabbit = append(rabbit, *card)
+	}
+
+	return rabbit, nil
+}
@@ -3,15 +3,21 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 
+	"entgo.io/ent/dialect/sql/sqlgraph"
 	"github.com/whywaita/poker-go"
 	"github.com/whywaita/rfid-poker/pkg/query"
 )
 
-func GetCardBySerial(ctx context.Context, conn *sql.DB, serial string) ([]poker.Card, error) {
-	q := query.New(conn)
+var (
+	// ErrCardInPlay is returned when the card is already stored at another antenna
+	ErrCardInPlay = errors.New("card is already in play")
+)
+
+func GetCardBySerial(ctx context.Context, q *query.Queries, serial string) ([]poker.Card, error) {
 	cards, err := q.GetCardBySerial(ctx, serial)
 	if err != nil {
 		return nil, fmt.Errorf("q.GetCardBySerial(): %w", err)
@@ -29,14 +35,14 @@ func GetCardBySerial(ctx context.Context, conn *sql.DB, serial string) ([]poker.
 	return result, nil
 }
 
-func AddCard(ctx context.Context, conn *sql.DB, card poker.Card, serial string) error {
+// AddCard stores the card read by the antenna to the current game, q must be of a transaction
+func AddCard(ctx context.Context, q *query.Queries, card poker.Card, serial string) error {
 	// Get or create current game
-	gameID, err := GetOrCreateCurrentGame(ctx, conn)
+	gameID, err := GetOrCreateCurrentGame(ctx, q)
 	if err != nil {
 		return fmt.Errorf("GetOrCreateCurrentGame(): %w", err)
 	}
 
-	q := query.New(conn)
 	_, err = q.AddCard(ctx, query.AddCardParams{
 		Serial:   serial,
 		CardSuit: card.Suit.String(),
@@ -45,6 +51,9 @@ func AddCard(ctx context.Context, conn *sql.DB, card poker.Card, serial string)
 		IsBoard:  false,
 	})
 	if err != nil {
+		if sqlgraph.IsUniqueConstraintError(err) {
+			return ErrCardInPlay
+		}
 		return fmt.Errorf("q.AddCard(): %w", err)
 	}
 
@@ -56,3 +65,41 @@ func AddCard(ctx context.Context, conn *sql.DB, card poker.Card, serial string)
 		slog.String("serial", serial))
 	return nil
 }
+
+// GetCardLocation returns where the card is stored in the current game, or nil if the card is not stored
+func GetCardLocation(ctx context.Context, q *query.Queries, card poker.Card) (*query.GetCardLocationByRankSuitRow, error) {
+	location, err := q.GetCardLocationByRankSuit(ctx, query.GetCardLocationByRankSuitParams{
+		CardRank: card.Rank.String(),
+		CardSuit: card.Suit.String(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("q.GetCardLocationByRankSuit(): %w", err)
+	}
+
+	return &location, nil
+}
+
+// RemoveCardFromPlay deletes the stored card, the hand including the card is also deleted and the other card of the hand is kept.
+// q must be of a transaction.
+func RemoveCardFromPlay(ctx context.Context, q *query.Queries, cardID int32, handID sql.NullInt32) error {
+	if handID.Valid {
+		if err := q.UnsetCardHandByHandID(ctx, handID); err != nil {
+			return fmt.Errorf("q.UnsetCardHandByHandID(): %w", err)
+		}
+		if err := q.DeleteHandByID(ctx, handID.Int32); err != nil {
+			return fmt.Errorf("q.DeleteHandByID(): %w", err)
+		}
+	}
+	if err := q.DeleteCardByID(ctx, cardID); err != nil {
+		return fmt.Errorf("q.DeleteCardByID(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Removed card from play",
+		slog.String("event", "card_removed_from_play"),
+		slog.Int("card_id", int(cardID)),
+		slog.Int("hand_id", int(handID.Int32)))
+	return nil
+}
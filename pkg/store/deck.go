@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/whywaita/poker-go"
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// Locations of a card in the current game
+const (
+	CardLocationPlayer = "player"
+	CardLocationBoard  = "board"
+	CardLocationMucked = "mucked"
+	CardLocationBurned = "burned"
+	CardLocationRabbit = "rabbit"
+	CardLocationUnseen = "unseen"
+	// CardLocationUnknown is a card stored at an antenna whose type is changed after the read
+	CardLocationUnknown = "unknown"
+)
+
+// DeckCard is a card of the deck with its location in the current game
+type DeckCard struct {
+	Card       poker.Card
+	Location   string
+	Serial     string
+	PlayerID   int32
+	PlayerName string
+	ReadAt     *time.Time
+}
+
+// GetDeckLocations returns all 52 cards of the deck in deck order, with where they are in the current game
+func GetDeckLocations(ctx context.Context, q *query.Queries) ([]DeckCard, error) {
+	rows, err := q.GetCardsWithLocation(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetCardsWithLocation(): %w", err)
+	}
+
+	stored := make(map[poker.Card]query.GetCardsWithLocationRow, len(rows))
+	for _, r := range rows {
+		card, err := query.Card{CardSuit: r.CardSuit, CardRank: r.CardRank}.ToPokerGo()
+		if err != nil {
+			return nil, fmt.Errorf("card.ToPokerGo(): %w", err)
+		}
+		stored[*card] = r
+	}
+
+	deck := poker.NewDeck()
+	result := make([]DeckCard, 0, len(deck.Cards))
+	for _, card := range deck.Cards {
+		r, ok := stored[card]
+		if !ok {
+			result = append(result, DeckCard{Card: card, Location: CardLocationUnseen})
+			continue
+		}
+
+		readAt := r.ReadAt
+		dc := DeckCard{
+			Card:     card,
+			Location: cardLocation(r),
+			Serial:   r.Serial,
+			ReadAt:   &readAt,
+		}
+		if dc.Location == CardLocationPlayer {
+			dc.PlayerID = r.PlayerID.Int32
+			dc.PlayerName = r.PlayerName.String
+		}
+		result = append(result, dc)
+	}
+
+	return result, nil
+}
+
+func cardLocation(r query.GetCardsWithLocationRow) string {
+	switch GetAntennaType(r.AntennaTypeName) {
+	case AntennaTypePlayer:
+		if r.IsMuck.Bool {
+			return CardLocationMucked
+		}
+		return CardLocationPlayer
+	case AntennaTypeBoard:
+		return CardLocationBoard
+	case AntennaTypeMuck:
+		return CardLocationMucked
+	case AntennaTypeBurn:
+		return CardLocationBurned
+	case AntennaTypeRabbit:
+		return CardLocationRabbit
+	default:
+		return CardLocationUnknown
+	}
+}
@@ -60,6 +60,9 @@ func CalcEquity(ctx context.Context, q *query.Queries) error {
 		if err := q.ResetEquity(ctx); err != nil {
 			return fmt.Errorf("db.ResetEquity(): %w", err)
 		}
+		if err := q.DeleteHandEquityAll(ctx); err != nil {
+			return fmt.Errorf("db.DeleteHandEquityAll(): %w", err)
+		}
 	}
 
 	if len(players) <= 1 {
@@ -67,35 +70,72 @@ func CalcEquity(ctx context.Context, q *query.Queries) error {
 		if err := q.ResetEquity(ctx); err != nil {
 			return fmt.Errorf("db.ResetEquity(): %w", err)
 		}
+		if err := q.DeleteHandEquityAll(ctx); err != nil {
+			return fmt.Errorf("db.DeleteHandEquityAll(): %w", err)
+		}
 		return nil
 	}
 
-	board, err := GetBoard(ctx, q)
+	boards, err := GetBoards(ctx, q)
 	if err != nil {
-		return fmt.Errorf("GetBoard(): %w", err)
+		return fmt.Errorf("GetBoards(): %w", err)
 	}
 
-	// Skip equity calculation if board has 1 or 2 cards (incomplete state)
+	// Skip equity calculation if a board has 1 or 2 cards (incomplete state)
 	// Equity can be calculated for 0 (preflop), 3 (flop), 4 (turn), or 5 (river) cards
-	if len(board) == 1 || len(board) == 2 {
-		logger.InfoContext(ctx, "Board is incomplete, skipping equity calculation", "board_count", len(board))
-		return nil
+	for _, board := range boards {
+		if len(board.Cards) == 1 || len(board.Cards) == 2 {
+			logger.InfoContext(ctx, "Board is incomplete, skipping equity calculation", "board_number", board.Number, "board_count", len(board.Cards))
+			return nil
+		}
 	}
 
-	logger.InfoContext(ctx, "Start EvaluateEquityByMadeHandWithCommunity", "players", players, "board", board)
-	equities, err := poker.EvaluateEquityByMadeHandWithCommunity(players, board)
-	if err != nil {
-		return fmt.Errorf("poker.EvaluateEquityByMadeHandWithCommunity: %w", err)
+	// boards with the same cards (e.g. before the second run is dealt) have the same equities
+	cache := make(map[string][]float64)
+	boardEquities := make([][]float64, 0, len(boards))
+	for _, board := range boards {
+		key := fmt.Sprint(board.Cards)
+		equities, ok := cache[key]
+		if !ok {
+			logger.InfoContext(ctx, "Start EvaluateEquityByMadeHandWithCommunity", "players", players, "board", board.Cards, "board_number", board.Number)
+			equities, err = poker.EvaluateEquityByMadeHandWithCommunity(players, board.Cards)
+			if err != nil {
+				return fmt.Errorf("poker.EvaluateEquityByMadeHandWithCommunity: %w", err)
+			}
+			logger.InfoContext(ctx, "End EvaluateEquityByMadeHandWithCommunity", "equities", equities, "board_number", board.Number)
+			cache[key] = equities
+		}
+		boardEquities = append(boardEquities, equities)
 	}
-	logger.InfoContext(ctx, "End EvaluateEquityByMadeHandWithCommunity", "equities", equities)
 
 	for i, p := range playersRow {
+		// each board wins an equal share of the pot
+		var combined float64
+		for j, board := range boards {
+			combined += boardEquities[j][i] / float64(len(boards))
+			if err := q.UpsertHandEquity(ctx, query.UpsertHandEquityParams{
+				HandID:      p.HandID,
+				BoardNumber: board.Number,
+				Equity:      boardEquities[j][i],
+			}); err != nil {
+				return fmt.Errorf("db.UpsertHandEquity(hand_id: %v, board_number: %v): %w", p.HandID, board.Number, err)
+			}
+		}
+
 		if err := q.UpdateEquity(ctx, query.UpdateEquityParams{
-			Equity: sql.NullFloat64{Float64: equities[i], Valid: true},
+			Equity: sql.NullFloat64{Float64: combined, Valid: true},
 			ID:     p.HandID,
 		}); err != nil {
 			return fmt.Errorf("db.UpdatePlayerEquity(hand_id: %v): %w", p.HandID, err)
 		}
+		// all-in before the equity is calculated
+		if err := q.SetAllInEquityIfUnset(ctx, query.SetAllInEquityIfUnsetParams{
+			Equity:   sql.NullFloat64{Float64: combined, Valid: true},
+			GameID:   p.GameID,
+			PlayerID: p.ID,
+		}); err != nil {
+			return fmt.Errorf("db.SetAllInEquityIfUnset(player_id: %v): %w", p.ID, err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"entgo.io/ent/dialect/sql/sqlgraph"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// Rollout status of a device
+const (
+	FirmwareRolloutNoTarget   = "no_target"
+	FirmwareRolloutUpToDate   = "up_to_date"
+	FirmwareRolloutPending    = "pending"
+	FirmwareRolloutDownloaded = "downloaded"
+	FirmwareRolloutFailed     = "failed"
+)
+
+// ErrFirmwareExists is returned when the version of the client type is already uploaded
+var ErrFirmwareExists = errors.New("firmware version already exists")
+
+// AddFirmware stores a firmware image, and marks it as desired version of the client type if desired is true
+func AddFirmware(ctx context.Context, conn *sql.DB, clientType, version string, data []byte, desired bool) (int32, error) {
+	sum := sha256.Sum256(data)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("conn.BeginTx(): %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil || err != nil {
+			tx.Rollback()
+		}
+	}()
+	qWithTx := query.New(tx)
+
+	result, err := qWithTx.AddFirmware(ctx, query.AddFirmwareParams{
+		ClientType: clientType,
+		Version:    version,
+		Sha256:     hex.EncodeToString(sum[:]),
+		Size:       int32(len(data)),
+		Data:       data,
+	})
+	if err != nil {
+		if sqlgraph.IsUniqueConstraintError(err) {
+			return 0, ErrFirmwareExists
+		}
+		return 0, fmt.Errorf("q.AddFirmware(): %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("result.LastInsertId(): %w", err)
+	}
+
+	if desired {
+		if err = setDesiredFirmware(ctx, qWithTx, clientType, int32(id)); err != nil {
+			return 0, fmt.Errorf("setDesiredFirmware(): %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("tx.Commit(): %w", err)
+	}
+
+	return int32(id), nil
+}
+
+// SetDesiredFirmware marks the firmware as desired version of its client type
+func SetDesiredFirmware(ctx context.Context, conn *sql.DB, firmwareID int32) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("conn.BeginTx(): %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil || err != nil {
+			tx.Rollback()
+		}
+	}()
+	qWithTx := query.New(tx)
+
+	firmware, err := qWithTx.GetFirmwareByID(ctx, firmwareID)
+	if err != nil {
+		return fmt.Errorf("q.GetFirmwareByID(): %w", err)
+	}
+	if err = setDesiredFirmware(ctx, qWithTx, firmware.ClientType, firmwareID); err != nil {
+		return fmt.Errorf("setDesiredFirmware(): %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit(): %w", err)
+	}
+
+	return nil
+}
+
+func setDesiredFirmware(ctx context.Context, q *query.Queries, clientType string, firmwareID int32) error {
+	if err := q.UnsetDesiredFirmwareByClientType(ctx, clientType); err != nil {
+		return fmt.Errorf("q.UnsetDesiredFirmwareByClientType(): %w", err)
+	}
+	if err := q.SetDesiredFirmware(ctx, firmwareID); err != nil {
+		return fmt.Errorf("q.SetDesiredFirmware(): %w", err)
+	}
+	return nil
+}
+
+// CheckFirmwareUpdate records the firmware running on the device, and returns the firmware the device should update to.
+// It returns nil if the device is up to date or no firmware is desired for the client type.
+func CheckFirmwareUpdate(ctx context.Context, conn *sql.DB, deviceID, clientType, firmwareVersion string) (*query.GetDesiredFirmwareByClientTypeRow, error) {
+	q := query.New(conn)
+	if err := q.UpsertDevice(ctx, query.UpsertDeviceParams{
+		DeviceID:        deviceID,
+		ClientType:      clientType,
+		FirmwareVersion: firmwareVersion,
+	}); err != nil {
+		return nil, fmt.Errorf("q.UpsertDevice(): %w", err)
+	}
+
+	desired, err := q.GetDesiredFirmwareByClientType(ctx, clientType)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("q.GetDesiredFirmwareByClientType(): %w", err)
+	}
+	if desired.Version == firmwareVersion {
+		return nil, nil
+	}
+
+	if err := q.SetDeviceOfferedVersion(ctx, query.SetDeviceOfferedVersionParams{
+		OfferedVersion: sql.NullString{String: desired.Version, Valid: true},
+		DeviceID:       deviceID,
+	}); err != nil {
+		return nil, fmt.Errorf("q.SetDeviceOfferedVersion(): %w", err)
+	}
+
+	return &desired, nil
+}
+
+// FirmwareRolloutStatus returns the rollout status of the device against the desired version
+func FirmwareRolloutStatus(device query.Device, desiredVersion string) string {
+	switch {
+	case desiredVersion == "":
+		return FirmwareRolloutNoTarget
+	case device.FirmwareVersion == desiredVersion:
+		return FirmwareRolloutUpToDate
+	case device.DownloadedVersion.Valid && device.DownloadedVersion.String == desiredVersion:
+		if device.DownloadedAt.Valid && device.LastBootAt.After(device.DownloadedAt.Time) {
+			// rebooted after download, but still running the old version
+			return FirmwareRolloutFailed
+		}
+		return FirmwareRolloutDownloaded
+	default:
+		return FirmwareRolloutPending
+	}
+}
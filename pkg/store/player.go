@@ -13,44 +13,32 @@ import (
 	"github.com/whywaita/rfid-poker/pkg/query"
 )
 
-func AddHand(ctx context.Context, conn *sql.DB, input []poker.Card, serial string) error {
+// AddHand stores the hand of the player at the antenna, q must be of a transaction
+func AddHand(ctx context.Context, q *query.Queries, input []poker.Card, serial string) error {
 	if len(input) != 2 {
 		return fmt.Errorf("invalid input length (not 2): %v", input)
 	}
 
 	// Get or create current game
-	gameID, err := GetOrCreateCurrentGame(ctx, conn)
+	gameID, err := GetOrCreateCurrentGame(ctx, q)
 	if err != nil {
 		return fmt.Errorf("GetOrCreateCurrentGame(): %w", err)
 	}
 
-	tx, err := conn.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("conn.BeginTx(): %w", err)
-	}
-	defer func() {
-		if r := recover(); r != nil || err != nil {
-			tx.Rollback()
-		}
-	}()
-	qWithTx := query.New(tx)
-
 	sort.SliceStable(input, func(i, j int) bool {
 		return input[i].Rank < input[j].Rank
 	})
 
-	player, err := qWithTx.GetPlayerBySerial(ctx, serial)
+	player, err := q.GetPlayerBySerial(ctx, serial)
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("q.GetPlayerBySerial(): %w", err)
 	}
 
-	hand, err := qWithTx.AddHand(ctx, query.AddHandParams{
+	hand, err := q.AddHand(ctx, query.AddHandParams{
 		PlayerID: player.ID,
 		GameID:   gameID,
 	})
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("db.AddHand(): %w", err)
 	}
 
@@ -62,25 +50,35 @@ func AddHand(ctx context.Context, conn *sql.DB, input []poker.Card, serial strin
 		slog.String("serial", serial))
 	handResult, err := hand.LastInsertId()
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("hand.LastInsertId(): %w", err)
 	}
 	if handResult > math.MaxInt32 {
-		tx.Rollback()
 		return fmt.Errorf("hand ID %d exceeds maximum int32 value", handResult)
 	}
 
 	for _, c := range input {
-		_, err = qWithTx.AddCard(ctx, query.AddCardParams{
+		_, err = q.AddCard(ctx, query.AddCardParams{
 			CardSuit: c.Suit.String(),
 			CardRank: c.Rank.String(),
 			Serial:   serial,
 			IsBoard:  false,
 			GameID:   gameID,
 		})
-		if err != nil && !sqlgraph.IsUniqueConstraintError(err) {
-			tx.Rollback()
-			return fmt.Errorf("q.AddCard(): %w", err)
+		if err != nil {
+			if !sqlgraph.IsUniqueConstraintError(err) {
+				return fmt.Errorf("q.AddCard(): %w", err)
+			}
+			// the card is already stored, it must be the first card read by this antenna
+			location, err := q.GetCardLocationByRankSuit(ctx, query.GetCardLocationByRankSuitParams{
+				CardRank: c.Rank.String(),
+				CardSuit: c.Suit.String(),
+			})
+			if err != nil {
+				return fmt.Errorf("q.GetCardLocationByRankSuit(): %w", err)
+			}
+			if location.Serial != serial {
+				return ErrCardInPlay
+			}
 		}
 		slog.InfoContext(ctx, "Added card to player hand",
 			slog.String("game_id", gameID),
@@ -90,33 +88,28 @@ func AddHand(ctx context.Context, conn *sql.DB, input []poker.Card, serial strin
 			slog.Bool("is_board", false),
 			slog.String("serial", serial))
 
-		dbCard, err := qWithTx.GetCardByRankSuit(ctx, query.GetCardByRankSuitParams{
+		dbCard, err := q.GetCardByRankSuit(ctx, query.GetCardByRankSuitParams{
 			CardRank: c.Rank.String(),
 			CardSuit: c.Suit.String(),
 		})
 		if err != nil {
-			tx.Rollback()
 			return fmt.Errorf("q.GetCardByRankSuit(): %w", err)
 		}
-		if _, err := qWithTx.SetCardHandByCardID(ctx, query.SetCardHandByCardIDParams{
+		if _, err := q.SetCardHandByCardID(ctx, query.SetCardHandByCardIDParams{
 			HandID: sql.NullInt32{Int32: int32(handResult), Valid: true},
 			ID:     dbCard.ID,
 		}); err != nil {
-			tx.Rollback()
 			return fmt.Errorf("q.SetCardHand(): %w", err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("tx.Commit(): %w", err)
-	}
-
 	return nil
 }
 
-func MuckPlayer(ctx context.Context, conn *sql.DB, cards []poker.Card) error {
+// MuckPlayer mucks the hand including the card, q must be of a transaction
+func MuckPlayer(ctx context.Context, q *query.Queries, cards []poker.Card) error {
 	// Get current game ID for logging
-	gameID, err := GetOrCreateCurrentGame(ctx, conn)
+	gameID, err := GetOrCreateCurrentGame(ctx, q)
 	if err != nil {
 		return fmt.Errorf("GetOrCreateCurrentGame(): %w", err)
 	}
@@ -125,40 +118,23 @@ func MuckPlayer(ctx context.Context, conn *sql.DB, cards []poker.Card) error {
 		slog.String("game_id", gameID),
 		slog.String("event", "muck_initiated"),
 		slog.Int("card_count", len(cards)))
-	tx, err := conn.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("conn.BeginTx(): %w", err)
-	}
-	defer func() {
-		if r := recover(); r != nil || err != nil {
-			tx.Rollback()
-		}
-	}()
-	qWithTx := query.New(tx)
 
-	card, err := qWithTx.GetCardByRankSuit(ctx, query.GetCardByRankSuitParams{
+	card, err := q.GetCardByRankSuit(ctx, query.GetCardByRankSuitParams{
 		CardRank: cards[0].Rank.String(),
 		CardSuit: cards[0].Suit.String(),
 	})
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("q.GetCardByRankSuit(): %w", err)
 	}
-	hand, err := qWithTx.GetHand(ctx, card.HandID.Int32)
+	hand, err := q.GetHand(ctx, card.HandID.Int32)
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("q.GetHandByCardId(): %w", err)
 	}
 
-	if err := qWithTx.MuckHand(ctx, hand.ID); err != nil {
-		tx.Rollback()
+	if err := q.MuckHand(ctx, hand.ID); err != nil {
 		return fmt.Errorf("q.MuckHand(): %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("tx.Commit(): %w", err)
-	}
-
 	slog.InfoContext(ctx, "Player hand mucked",
 		slog.String("game_id", gameID),
 		slog.String("event", "hand_mucked"),
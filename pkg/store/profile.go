@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+var (
+	ErrPlayerNotFound = errors.New("player not found")
+	ErrNotMember      = errors.New("membership uid is not registered")
+	ErrSeatInPlay     = errors.New("seat has cards in play")
+)
+
+// SeatPlayer seats the player at the antenna, the player leaves the previous seat.
+// The player sitting at the antenna before is kept with the history.
+func SeatPlayer(ctx context.Context, conn *sql.DB, playerID int32, serial string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("conn.BeginTx(): %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil || err != nil {
+			tx.Rollback()
+		}
+	}()
+	q := query.New(tx)
+
+	if err = seatPlayer(ctx, q, playerID, serial); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit(): %w", err)
+	}
+	return nil
+}
+
+func seatPlayer(ctx context.Context, q *query.Queries, playerID int32, serial string) error {
+	player, err := q.GetPlayer(ctx, playerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlayerNotFound
+		}
+		return fmt.Errorf("q.GetPlayer(): %w", err)
+	}
+
+	// the hand at the seat belongs to the player sitting now, and the player can't leave the hand
+	serials := []string{serial}
+	previous, err := q.GetPlayerWithDevice(ctx, player.ID)
+	switch {
+	case err == nil:
+		serials = append(serials, previous.Serial)
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("q.GetPlayerWithDevice(): %w", err)
+	}
+	for _, s := range serials {
+		cards, err := q.GetCardBySerial(ctx, s)
+		if err != nil {
+			return fmt.Errorf("q.GetCardBySerial(): %w", err)
+		}
+		if len(cards) > 0 {
+			return ErrSeatInPlay
+		}
+	}
+
+	if err := q.UnsetPlayerIDToAntennaByPlayerID(ctx, sql.NullInt32{Int32: player.ID, Valid: true}); err != nil {
+		return fmt.Errorf("q.UnsetPlayerIDToAntennaByPlayerID(): %w", err)
+	}
+	if err := q.SetPlayerIDToAntennaBySerial(ctx, query.SetPlayerIDToAntennaBySerialParams{
+		PlayerID: sql.NullInt32{Int32: player.ID, Valid: true},
+		Serial:   serial,
+	}); err != nil {
+		return fmt.Errorf("q.SetPlayerIDToAntennaBySerial(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Player seated",
+		slog.String("event", "player_seated"),
+		slog.Int("player_id", int(player.ID)),
+		slog.String("player_name", player.Name),
+		slog.String("serial", serial))
+	return nil
+}
+
+// CheckIn seats the player of the membership card read by the antenna, q must be of a transaction
+func CheckIn(ctx context.Context, q *query.Queries, membershipUID string, serial string) (*query.Player, error) {
+	player, err := q.GetPlayerByMembershipUid(ctx, sql.NullString{String: membershipUID, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotMember
+		}
+		return nil, fmt.Errorf("q.GetPlayerByMembershipUid(): %w", err)
+	}
+
+	if err := seatPlayer(ctx, q, player.ID, serial); err != nil {
+		return nil, fmt.Errorf("seatPlayer(): %w", err)
+	}
+	return &player, nil
+}
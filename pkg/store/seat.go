@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// Position names of seats from the dealer button
+const (
+	PositionButton     = "BTN"
+	PositionSmallBlind = "SB"
+	PositionBigBlind   = "BB"
+)
+
+// GetOccupiedSeats returns seat numbers of player antennas with a player in ascending order
+func GetOccupiedSeats(ctx context.Context, q *query.Queries) ([]int32, error) {
+	players, err := q.GetPlayersWithDevice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetPlayersWithDevice(): %w", err)
+	}
+
+	seats := make([]int32, 0, len(players))
+	for _, p := range players {
+		if p.SeatNumber.Valid {
+			seats = append(seats, p.SeatNumber.Int32)
+		}
+	}
+	slices.Sort(seats)
+	return seats, nil
+}
+
+// NextButton returns the first occupied seat after the button, clockwise in order of seat number
+func NextButton(seats []int32, button sql.NullInt32) sql.NullInt32 {
+	if len(seats) == 0 {
+		return sql.NullInt32{}
+	}
+	if !button.Valid {
+		return sql.NullInt32{Int32: seats[0], Valid: true}
+	}
+	for _, s := range seats {
+		if s > button.Int32 {
+			return sql.NullInt32{Int32: s, Valid: true}
+		}
+	}
+	return sql.NullInt32{Int32: seats[0], Valid: true}
+}
+
+// nextButtonPosition returns the button of a new game, moved from the button of the last game
+func nextButtonPosition(ctx context.Context, q *query.Queries) (sql.NullInt32, error) {
+	seats, err := GetOccupiedSeats(ctx, q)
+	if err != nil {
+		return sql.NullInt32{}, fmt.Errorf("GetOccupiedSeats(): %w", err)
+	}
+
+	last, err := q.GetLastButtonPosition(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return sql.NullInt32{}, fmt.Errorf("q.GetLastButtonPosition(): %w", err)
+	}
+
+	return NextButton(seats, last), nil
+}
+
+// Positions returns position names (BTN, SB, BB, UTG, ...) of occupied seats, key is the seat number.
+// If the button seat is empty, the first occupied seat before it has the button.
+func Positions(seats []int32, button int32) map[int32]string {
+	positions := make(map[int32]string, len(seats))
+	if len(seats) == 0 {
+		return positions
+	}
+
+	sorted := slices.Clone(seats)
+	slices.Sort(sorted)
+
+	start := len(sorted) - 1
+	for i, s := range sorted {
+		if s <= button {
+			start = i
+		}
+	}
+
+	names := positionNames(len(sorted))
+	for i := range sorted {
+		positions[sorted[(start+i)%len(sorted)]] = names[i]
+	}
+	return positions
+}
+
+// positionNames returns position names in order from the button for the number of players
+func positionNames(n int) []string {
+	switch n {
+	case 1:
+		return []string{PositionButton}
+	case 2:
+		// the button is the small blind in heads-up
+		return []string{PositionButton, PositionBigBlind}
+	}
+
+	names := []string{PositionButton, PositionSmallBlind, PositionBigBlind}
+	rest := n - len(names)
+	if rest == 0 {
+		return names
+	}
+
+	late := []string{"LJ", "HJ", "CO"}
+	lateCount := min(rest-1, len(late))
+
+	names = append(names, "UTG")
+	for i := 1; i < rest-lateCount; i++ {
+		names = append(names, fmt.Sprintf("UTG+%d", i))
+	}
+	return append(names, late[len(late)-lateCount:]...)
+}
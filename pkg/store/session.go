@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+var (
+	ErrSessionOpen     = errors.New("session is already open")
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionClosed   = errors.New("session is already closed")
+)
+
+// OpenSession opens a new session, games started while it is open are attached to it.
+// Only one session is open at a time.
+func OpenSession(ctx context.Context, q *query.Queries, name string, venue sql.NullString, tables []string) (int32, error) {
+	if _, err := q.GetOpenSession(ctx); err == nil {
+		return 0, ErrSessionOpen
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("q.GetOpenSession(): %w", err)
+	}
+
+	if tables == nil {
+		tables = []string{}
+	}
+	b, err := json.Marshal(tables)
+	if err != nil {
+		return 0, fmt.Errorf("json.Marshal(): %w", err)
+	}
+
+	result, err := q.CreateSession(ctx, query.CreateSessionParams{
+		Name:   name,
+		Venue:  venue,
+		Tables: b,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("q.CreateSession(): %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("result.LastInsertId(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Session opened",
+		slog.String("event", "session_opened"),
+		slog.Int64("session_id", id),
+		slog.String("name", name))
+	return int32(id), nil
+}
+
+// CloseSession closes the session, the game in progress stays in the session
+func CloseSession(ctx context.Context, q *query.Queries, id int32) error {
+	session, err := q.GetSession(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("q.GetSession(): %w", err)
+	}
+	if session.EndedAt.Valid {
+		return ErrSessionClosed
+	}
+
+	if err := q.CloseSession(ctx, id); err != nil {
+		return fmt.Errorf("q.CloseSession(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Session closed",
+		slog.String("event", "session_closed"),
+		slog.Int("session_id", int(id)),
+		slog.String("name", session.Name))
+	return nil
+}
+
+// ParseSessionTables returns names of tables of a session
+func ParseSessionTables(b json.RawMessage) ([]string, error) {
+	tables := []string{}
+	if len(b) == 0 {
+		return tables, nil
+	}
+	if err := json.Unmarshal(b, &tables); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+	return tables, nil
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// GameState is a state of a game
+type GameState string
+
+const (
+	// GameStateWaiting is a game created without cards
+	GameStateWaiting GameState = "waiting"
+	// GameStateDealing is a game dealing hole cards
+	GameStateDealing GameState = "dealing"
+	GameStatePreflop GameState = "preflop"
+	GameStateFlop    GameState = "flop"
+	GameStateTurn    GameState = "turn"
+	GameStateRiver   GameState = "river"
+	// GameStateShowdown is a game with the river complete, waiting to be finished
+	GameStateShowdown GameState = "showdown"
+	GameStateFinished GameState = "finished"
+)
+
+// gameStateOrder is states in order of a game, a game moves only forward
+var gameStateOrder = []GameState{
+	GameStateWaiting,
+	GameStateDealing,
+	GameStatePreflop,
+	GameStateFlop,
+	GameStateTurn,
+	GameStateRiver,
+	GameStateShowdown,
+	GameStateFinished,
+}
+
+// ErrInvalidStateTransition is returned when the transition is not allowed
+var ErrInvalidStateTransition = errors.New("invalid game state transition")
+
+// CanTransition returns true if the game can move from one state to another.
+// A game moves to the next state, or finishes from any state.
+func CanTransition(from, to GameState) bool {
+	if from == GameStateFinished {
+		return false
+	}
+	if to == GameStateFinished {
+		return true
+	}
+	i := slices.Index(gameStateOrder, from)
+	return i >= 0 && i+1 < len(gameStateOrder) && gameStateOrder[i+1] == to
+}
+
+// Before returns true if s comes before other in a game
+func (s GameState) Before(other GameState) bool {
+	return slices.Index(gameStateOrder, s) < slices.Index(gameStateOrder, other)
+}
+
+// Next returns the state after s
+func (s GameState) Next() GameState {
+	i := slices.Index(gameStateOrder, s)
+	if i < 0 || i+1 >= len(gameStateOrder) {
+		return GameStateFinished
+	}
+	return gameStateOrder[i+1]
+}
+
+// TransitionGameState moves the game to the state
+func TransitionGameState(ctx context.Context, q *query.Queries, game query.Game, to GameState) error {
+	from := GameState(game.State)
+	if !CanTransition(from, to) {
+		return fmt.Errorf("%w (from: %s, to: %s)", ErrInvalidStateTransition, from, to)
+	}
+
+	if err := q.UpdateGameState(ctx, query.UpdateGameStateParams{
+		State: string(to),
+		ID:    game.ID,
+	}); err != nil {
+		return fmt.Errorf("q.UpdateGameState(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Game state changed",
+		slog.String("game_id", game.ID),
+		slog.String("event", "game_state_changed"),
+		slog.String("from", string(from)),
+		slog.String("to", string(to)))
+	return nil
+}
+
+// GameProgress is the progress of the current game read from cards
+type GameProgress struct {
+	Game        query.Game
+	CardCount   int
+	HandCount   int // including mucked hands
+	ActiveHands int
+	BoardCount  int
+}
+
+// GetGameProgress returns the progress of the current game, or nil if there is no active game
+func GetGameProgress(ctx context.Context, q *query.Queries) (*GameProgress, error) {
+	game, err := q.GetCurrentGame(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+
+	cardCount, err := q.CountCardsByGameID(ctx, game.ID)
+	if err != nil {
+		return nil, fmt.Errorf("q.CountCardsByGameID(): %w", err)
+	}
+	handCount, err := q.CountHandsByGameID(ctx, game.ID)
+	if err != nil {
+		return nil, fmt.Errorf("q.CountHandsByGameID(): %w", err)
+	}
+	active, err := q.GetPlayersWithHand(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetPlayersWithHand(): %w", err)
+	}
+	board, err := GetBoard(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("GetBoard(): %w", err)
+	}
+
+	return &GameProgress{
+		Game:        game,
+		CardCount:   int(cardCount),
+		HandCount:   int(handCount),
+		ActiveHands: len(active),
+		BoardCount:  len(board),
+	}, nil
+}
+
+// DealtState returns the state the cards of the game have reached
+func (p GameProgress) DealtState() GameState {
+	switch {
+	case p.CardCount == 0:
+		return GameStateWaiting
+	case p.BoardCount >= 5:
+		return GameStateRiver
+	case p.BoardCount == 4:
+		return GameStateTurn
+	case p.BoardCount == 3:
+		return GameStateFlop
+	case p.HandCount >= 2:
+		return GameStatePreflop
+	default:
+		return GameStateDealing
+	}
+}
+
+// OnePlayerLeft returns true if all but one player have mucked
+func (p GameProgress) OnePlayerLeft() bool {
+	return p.HandCount >= 2 && p.ActiveHands <= 1
+}
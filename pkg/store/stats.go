@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// StatsFilter is the range of history for statistics
+type StatsFilter struct {
+	From time.Time
+	To   time.Time
+	// SessionID limits history to games of the session if set
+	SessionID sql.NullInt32
+	// PlayerID limits statistics to the player if set
+	PlayerID sql.NullInt32
+}
+
+// AllInStat is the expected and actual chips of a player's all-ins
+type AllInStat struct {
+	PlayerID   int32
+	PlayerName string
+	Count      int64
+	// AverageEquity is the average equity at the moment of going all-in
+	AverageEquity float64
+	// Expected is the sum of equity multiplied by the pot the player could win
+	Expected float64
+	// Actual is the sum of chips won from the pots
+	Actual int64
+}
+
+// Luck returns the actual chips minus the expected chips, positive if the player ran good
+func (s AllInStat) Luck() float64 {
+	return float64(s.Actual) - s.Expected
+}
+
+// GetAllInStats returns all-in statistics of players in finished games
+func GetAllInStats(ctx context.Context, q *query.Queries, filter StatsFilter) ([]AllInStat, error) {
+	rows, err := q.GetAllInStats(ctx, query.GetAllInStatsParams{
+		CreatedAt:   filter.From,
+		CreatedAt_2: filter.To,
+		SessionID:   filter.SessionID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("q.GetAllInStats(): %w", err)
+	}
+
+	stats := make([]AllInStat, 0, len(rows))
+	for _, r := range rows {
+		stats = append(stats, AllInStat{
+			PlayerID:      r.PlayerID,
+			PlayerName:    r.Name,
+			Count:         r.AllinCount,
+			AverageEquity: r.EquitySum / float64(r.AllinCount),
+			Expected:      r.Expected,
+			Actual:        r.Actual,
+		})
+	}
+	return stats, nil
+}
+
+// PlayerStat is statistics of a player from the history of hands
+type PlayerStat struct {
+	PlayerID     int32
+	PlayerName   string
+	HandsDealt   int64
+	HandsMucked  int64
+	Showdowns    int64
+	ShowdownsWon int64
+	// ShowdownEquity is the average equity at the all-in of hands at showdown, hands without an all-in are not counted
+	ShowdownEquity float64
+	// BiggestPot is the most chips won in a game
+	BiggestPot  int64
+	Won         int64
+	Contributed int64
+	// HandCategories is the number of made hands at showdown by category (e.g. "Two Pair")
+	HandCategories map[string]int64
+}
+
+// Net returns chips won minus chips put into pots
+func (s PlayerStat) Net() int64 {
+	return s.Won - s.Contributed
+}
+
+// ShowdownWinRate returns the rate of showdowns won
+func (s PlayerStat) ShowdownWinRate() float64 {
+	if s.Showdowns == 0 {
+		return 0
+	}
+	return float64(s.ShowdownsWon) / float64(s.Showdowns)
+}
+
+// GetPlayerStats returns statistics of players
+func GetPlayerStats(ctx context.Context, q *query.Queries, filter StatsFilter) ([]PlayerStat, error) {
+	rows, err := q.GetPlayerStats(ctx, query.GetPlayerStatsParams{
+		CreatedAt:   filter.From,
+		CreatedAt_2: filter.To,
+		SessionID:   filter.SessionID,
+		PlayerID:    filter.PlayerID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("q.GetPlayerStats(): %w", err)
+	}
+	categories, err := q.GetHandCategoryCounts(ctx, query.GetHandCategoryCountsParams{
+		CreatedAt:   filter.From,
+		CreatedAt_2: filter.To,
+		SessionID:   filter.SessionID,
+		PlayerID:    filter.PlayerID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("q.GetHandCategoryCounts(): %w", err)
+	}
+	byPlayer := make(map[int32]map[string]int64)
+	for _, c := range categories {
+		if _, ok := byPlayer[c.PlayerID]; !ok {
+			byPlayer[c.PlayerID] = make(map[string]int64)
+		}
+		byPlayer[c.PlayerID][c.HandCategory.String] = c.Count
+	}
+
+	stats := make([]PlayerStat, 0, len(rows))
+	for _, r := range rows {
+		hc := byPlayer[r.PlayerID]
+		if hc == nil {
+			hc = make(map[string]int64)
+		}
+		stats = append(stats, PlayerStat{
+			PlayerID:       r.PlayerID,
+			PlayerName:     r.Name,
+			HandsDealt:     r.HandsDealt,
+			HandsMucked:    r.HandsMucked,
+			Showdowns:      r.Showdowns,
+			ShowdownsWon:   r.ShowdownsWon,
+			ShowdownEquity: r.ShowdownEquity.Float64,
+			BiggestPot:     r.BiggestPot,
+			Won:            r.Won,
+			Contributed:    r.Contributed,
+			HandCategories: hc,
+		})
+	}
+	return stats, nil
+}
+
+// ErrUnknownMetric is returned when the leaderboard metric is not supported
+var ErrUnknownMetric = errors.New("unknown leaderboard metric")
+
+// LeaderboardMetrics is metrics to rank players, a larger value is a higher rank
+var LeaderboardMetrics = map[string]func(PlayerStat) float64{
+	"net":               func(s PlayerStat) float64 { return float64(s.Net()) },
+	"won":               func(s PlayerStat) float64 { return float64(s.Won) },
+	"hands_dealt":       func(s PlayerStat) float64 { return float64(s.HandsDealt) },
+	"showdowns":         func(s PlayerStat) float64 { return float64(s.Showdowns) },
+	"showdowns_won":     func(s PlayerStat) float64 { return float64(s.ShowdownsWon) },
+	"showdown_win_rate": func(s PlayerStat) float64 { return s.ShowdownWinRate() },
+	"biggest_pot":       func(s PlayerStat) float64 { return float64(s.BiggestPot) },
+	"showdown_equity":   func(s PlayerStat) float64 { return s.ShowdownEquity },
+}
+
+// RankPlayers returns players with minHands or more hands in order of the metric
+func RankPlayers(stats []PlayerStat, metric string, minHands int64) ([]PlayerStat, error) {
+	value, ok := LeaderboardMetrics[metric]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMetric, metric)
+	}
+
+	ranked := make([]PlayerStat, 0, len(stats))
+	for _, s := range stats {
+		if s.HandsDealt >= minHands {
+			ranked = append(ranked, s)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return value(ranked[i]) > value(ranked[j])
+	})
+	return ranked, nil
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -13,6 +14,8 @@ import (
 )
 
 type Stored struct {
+	PlayerID   int32
+	HandID     int32
 	PlayerName string
 	Hand       []poker.Card
 	Equity     float64
@@ -37,9 +40,46 @@ func CreateNewGame(ctx context.Context, conn *sql.DB) (string, error) {
 	return gameID, nil
 }
 
-// GetOrCreateCurrentGame returns the current active game ID, or creates a new one if none exists
-// This function uses a transaction to ensure atomicity of the check-and-create operation
-func GetOrCreateCurrentGame(ctx context.Context, conn *sql.DB) (string, error) {
+// GetOrCreateCurrentGame returns the current active game ID, or creates a new one if none exists.
+// q must be of a transaction, so the check-and-create is atomic.
+func GetOrCreateCurrentGame(ctx context.Context, q *query.Queries) (string, error) {
+	game, err := q.GetCurrentGame(ctx)
+	if err == nil {
+		return game.ID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+
+	// No active game, create a new one within the same transaction
+	// The dealer button moves to the next seat
+	button, err := nextButtonPosition(ctx, q)
+	if err != nil {
+		return "", fmt.Errorf("nextButtonPosition(): %w", err)
+	}
+
+	gameID := uuid.New().String()
+	if err := q.StartGame(ctx, query.StartGameParams{
+		ID:             gameID,
+		ButtonPosition: button,
+	}); err != nil {
+		return "", fmt.Errorf("q.StartGame(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "New game started",
+		slog.String("game_id", gameID),
+		slog.String("event", "game_started"),
+		slog.Any("button_position", button))
+
+	return gameID, nil
+}
+
+// ErrGameInProgress is returned when a new game is started before the current game is finished
+var ErrGameInProgress = errors.New("game is in progress")
+
+// StartNewGame starts a new game before the first card is read, with the dealer button position and a label.
+// If buttonPosition is not set, the button moves to the next seat from the last game.
+func StartNewGame(ctx context.Context, conn *sql.DB, buttonPosition sql.NullInt32, label sql.NullString) (string, error) {
 	tx, err := conn.BeginTx(ctx, nil)
 	if err != nil {
 		return "", fmt.Errorf("conn.BeginTx(): %w", err)
@@ -52,53 +92,57 @@ func GetOrCreateCurrentGame(ctx context.Context, conn *sql.DB) (string, error) {
 
 	db := query.New(tx)
 
-	game, err := db.GetCurrentGame(ctx)
-	if err == nil {
-		// Game exists, commit and return
-		if err := tx.Commit(); err != nil {
-			return "", fmt.Errorf("tx.Commit(): %w", err)
-		}
-		return game.ID, nil
+	if _, err = db.GetCurrentGame(ctx); err == nil {
+		err = ErrGameInProgress
+		return "", err
 	}
-
-	if err != sql.ErrNoRows {
-		tx.Rollback()
+	if !errors.Is(err, sql.ErrNoRows) {
 		return "", fmt.Errorf("db.GetCurrentGame(): %w", err)
 	}
+	err = nil
+
+	if !buttonPosition.Valid {
+		if buttonPosition, err = nextButtonPosition(ctx, db); err != nil {
+			return "", fmt.Errorf("nextButtonPosition(): %w", err)
+		}
+	}
 
-	// No active game, create a new one within the same transaction
 	gameID := uuid.New().String()
-	if err := db.CreateGame(ctx, gameID); err != nil {
-		tx.Rollback()
-		return "", fmt.Errorf("db.CreateGame(): %w", err)
+	if err = db.StartGame(ctx, query.StartGameParams{
+		ID:             gameID,
+		ButtonPosition: buttonPosition,
+		Label:          label,
+	}); err != nil {
+		return "", fmt.Errorf("db.StartGame(): %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err = tx.Commit(); err != nil {
 		return "", fmt.Errorf("tx.Commit(): %w", err)
 	}
 
 	slog.InfoContext(ctx, "New game started",
 		slog.String("game_id", gameID),
-		slog.String("event", "game_started"))
+		slog.String("event", "game_started"),
+		slog.String("label", label.String))
 
 	return gameID, nil
 }
 
-// FinishCurrentGame finishes the current active game
-func FinishCurrentGame(ctx context.Context, conn *sql.DB) error {
-	db := query.New(conn)
-
-	game, err := db.GetCurrentGame(ctx)
-	if err == sql.ErrNoRows {
+// FinishCurrentGame finishes the current active game.
+// Hands are archived and cards are cleared for the next game, but the game is kept.
+// q must be of a transaction, so a failure doesn't leave the game half finished (e.g. pots paid twice on retry).
+func FinishCurrentGame(ctx context.Context, q *query.Queries) error {
+	game, err := q.GetCurrentGame(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
 		// No active game to finish
 		return nil
 	}
 	if err != nil {
-		return fmt.Errorf("db.GetCurrentGame(): %w", err)
+		return fmt.Errorf("q.GetCurrentGame(): %w", err)
 	}
 
-	if err := db.FinishGame(ctx, game.ID); err != nil {
-		return fmt.Errorf("db.FinishGame(): %w", err)
+	if err := finishGame(ctx, q, game); err != nil {
+		return fmt.Errorf("finishGame(): %w", err)
 	}
 
 	slog.InfoContext(ctx, "Game finished",
@@ -108,25 +152,86 @@ func FinishCurrentGame(ctx context.Context, conn *sql.DB) error {
 	return nil
 }
 
-func ClearGame(ctx context.Context, conn *sql.DB) error {
-	db := query.New(conn)
-
+// ClearGame finishes the current active game and deletes it. q must be of a transaction as FinishCurrentGame.
+func ClearGame(ctx context.Context, q *query.Queries) error {
 	// Get current game before finishing
-	game, err := db.GetCurrentGame(ctx)
-	if err == sql.ErrNoRows {
+	game, err := q.GetCurrentGame(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
 		// No active game to clear
 		return nil
 	}
 	if err != nil {
-		return fmt.Errorf("db.GetCurrentGame(): %w", err)
+		return fmt.Errorf("q.GetCurrentGame(): %w", err)
 	}
 
 	gameID := game.ID
 
+	if err := finishGame(ctx, q, game); err != nil {
+		return fmt.Errorf("finishGame(): %w", err)
+	}
+
+	// Delete the game itself
+	if err := q.DeleteGameByID(ctx, gameID); err != nil {
+		return fmt.Errorf("q.DeleteGameByID(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Game cleared and archived",
+		slog.String("game_id", gameID),
+		slog.String("event", "game_cleared"),
+		slog.Time("started_at", game.StartedAt))
+
+	return nil
+}
+
+// finishGame awards pots of the game, archives hands of the game to hand_history, and deletes cards and hands of the game
+func finishGame(ctx context.Context, db *query.Queries, game query.Game) error {
+	gameID := game.ID
+
+	// Award pots before cards are cleared
+	awards, err := awardPots(ctx, db, game)
+	if err != nil {
+		return fmt.Errorf("awardPots(): %w", err)
+	}
+
+	showdowns, err := getShowdowns(ctx, db)
+	if err != nil {
+		return fmt.Errorf("getShowdowns(): %w", err)
+	}
+
 	// Archive hands to hand_history before clearing
 	if err := db.CopyHandsToHistory(ctx, gameID); err != nil {
 		return fmt.Errorf("db.CopyHandsToHistory(): %w", err)
 	}
+	for _, sd := range showdowns {
+		if err := db.SetShowdownToHandHistory(ctx, query.SetShowdownToHandHistoryParams{
+			ShowdownWon:  sd.Won,
+			HandCategory: sql.NullString{String: sd.HandCategory, Valid: true},
+			GameID:       gameID,
+			PlayerID:     sd.PlayerID,
+		}); err != nil {
+			return fmt.Errorf("db.SetShowdownToHandHistory(): %w", err)
+		}
+	}
+	for _, a := range awards {
+		if !a.PlayerID.Valid {
+			continue
+		}
+		if err := db.AddWonToHandHistory(ctx, query.AddWonToHandHistoryParams{
+			Won:      a.Amount,
+			GameID:   gameID,
+			PlayerID: a.PlayerID.Int32,
+		}); err != nil {
+			return fmt.Errorf("db.AddWonToHandHistory(): %w", err)
+		}
+		if err := db.SettleAllInEquity(ctx, query.SettleAllInEquityParams{
+			Pot:      a.Eligible,
+			Won:      a.Amount,
+			GameID:   gameID,
+			PlayerID: a.PlayerID.Int32,
+		}); err != nil {
+			return fmt.Errorf("db.SettleAllInEquity(): %w", err)
+		}
+	}
 
 	// Finish current game
 	if err := db.FinishGame(ctx, gameID); err != nil {
@@ -142,15 +247,13 @@ func ClearGame(ctx context.Context, conn *sql.DB) error {
 		return fmt.Errorf("db.DeleteHandByGameID(): %w", err)
 	}
 
-	// Delete the game itself
-	if err := db.DeleteGameByID(ctx, gameID); err != nil {
-		return fmt.Errorf("db.DeleteGameByID(): %w", err)
+	if err := db.DeleteGameBoardByGameID(ctx, gameID); err != nil {
+		return fmt.Errorf("db.DeleteGameBoardByGameID(): %w", err)
 	}
 
-	slog.InfoContext(ctx, "Game cleared and archived",
-		slog.String("game_id", gameID),
-		slog.String("event", "game_cleared"),
-		slog.Time("started_at", game.StartedAt))
+	if err := db.DeleteGameActivityByGameID(ctx, gameID); err != nil {
+		return fmt.Errorf("db.DeleteGameActivityByGameID(): %w", err)
+	}
 
 	return nil
 }
@@ -173,6 +276,8 @@ func GetStored(ctx context.Context, q *query.Queries) ([]Stored, error) {
 		}
 
 		stored = append(stored, Stored{
+			PlayerID:   p.ID,
+			HandID:     p.HandID,
 			PlayerName: p.Name,
 			Hand: []poker.Card{
 				cardA,
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+// AntennaTypeActivity is the last card read by antennas of a type in the game
+type AntennaTypeActivity struct {
+	AntennaTypeName string
+	LastReadAt      time.Time
+	TimeoutSeconds  int
+	ExpiresAt       time.Time
+}
+
+// GameTimeout is the timer to clear the game after all antennas stop reading cards
+type GameTimeout struct {
+	GameID     string
+	Activities []AntennaTypeActivity
+
+	// Started is true when both player and board antennas have read cards, the timer does not run before it
+	Started bool
+	// ExpiresAt is when all antenna types have timed out
+	ExpiresAt time.Time
+	// PausedAt is set while the timer is paused by the dealer
+	PausedAt sql.NullTime
+}
+
+// Remaining returns the time until the game is cleared, it is not decreased while the timer is paused
+func (t GameTimeout) Remaining(now time.Time) time.Duration {
+	if t.PausedAt.Valid {
+		now = t.PausedAt.Time
+	}
+	if !t.Started || !now.Before(t.ExpiresAt) {
+		return 0
+	}
+	return t.ExpiresAt.Sub(now)
+}
+
+// Expired returns true if the game should be cleared
+func (t GameTimeout) Expired(now time.Time) bool {
+	return t.Started && !t.PausedAt.Valid && !now.Before(t.ExpiresAt)
+}
+
+// ErrNoActiveGame is returned when the current game is required
+var ErrNoActiveGame = errors.New("no active game")
+
+// PauseGame stops the timer to clear the current game, e.g. while a player is thinking long
+func PauseGame(ctx context.Context, q *query.Queries) error {
+	game, err := q.GetCurrentGame(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoActiveGame
+		}
+		return fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+
+	if err := q.PauseGame(ctx, game.ID); err != nil {
+		return fmt.Errorf("q.PauseGame(): %w", err)
+	}
+	return nil
+}
+
+// ResumeGame restarts the timer of the current game with the time remaining when it was paused
+func ResumeGame(ctx context.Context, conn *sql.DB) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("conn.BeginTx(): %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil || err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	q := query.New(tx)
+	game, err := q.GetCurrentGame(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNoActiveGame
+			return err
+		}
+		return fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+
+	// move activities forward by the paused time
+	if err = q.DelayGameActivityByPause(ctx, game.ID); err != nil {
+		return fmt.Errorf("q.DelayGameActivityByPause(): %w", err)
+	}
+	if err = q.ResumeGame(ctx, game.ID); err != nil {
+		return fmt.Errorf("q.ResumeGame(): %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit(): %w", err)
+	}
+	return nil
+}
+
+// RecordGameActivity saves the time of a card read by an antenna type in the game
+func RecordGameActivity(ctx context.Context, q *query.Queries, gameID, antennaTypeName string, readAt time.Time) error {
+	if err := q.UpsertGameActivity(ctx, query.UpsertGameActivityParams{
+		GameID:          gameID,
+		AntennaTypeName: antennaTypeName,
+		LastReadAt:      readAt,
+	}); err != nil {
+		return fmt.Errorf("q.UpsertGameActivity(): %w", err)
+	}
+	return nil
+}
+
+// GetGameTimeout returns the timeout of the current game, or nil if no game is active.
+// timeoutSecondsByAntennaType overrides timeoutSeconds for the antenna type.
+func GetGameTimeout(ctx context.Context, q *query.Queries, timeoutSeconds int, timeoutSecondsByAntennaType map[string]int) (*GameTimeout, error) {
+	game, err := q.GetCurrentGame(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("q.GetCurrentGame(): %w", err)
+	}
+
+	activities, err := q.GetGameActivitiesByGameID(ctx, game.ID)
+	if err != nil {
+		return nil, fmt.Errorf("q.GetGameActivitiesByGameID(): %w", err)
+	}
+
+	timeout := &GameTimeout{
+		GameID:     game.ID,
+		Activities: make([]AntennaTypeActivity, 0, len(activities)),
+		PausedAt:   game.PausedAt,
+	}
+	hasPlayer, hasBoard := false, false
+	for _, a := range activities {
+		seconds := timeoutSeconds
+		if s, ok := timeoutSecondsByAntennaType[a.AntennaTypeName]; ok {
+			seconds = s
+		}
+		activity := AntennaTypeActivity{
+			AntennaTypeName: a.AntennaTypeName,
+			LastReadAt:      a.LastReadAt,
+			TimeoutSeconds:  seconds,
+			ExpiresAt:       a.LastReadAt.Add(time.Duration(seconds) * time.Second),
+		}
+		timeout.Activities = append(timeout.Activities, activity)
+
+		if activity.ExpiresAt.After(timeout.ExpiresAt) {
+			timeout.ExpiresAt = activity.ExpiresAt
+		}
+		switch GetAntennaType(a.AntennaTypeName) {
+		case AntennaTypePlayer:
+			hasPlayer = true
+		case AntennaTypeBoard:
+			hasBoard = true
+		}
+	}
+	timeout.Started = hasPlayer && hasBoard
+
+	return timeout, nil
+}
@@ -0,0 +1,299 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/whywaita/rfid-poker/pkg/query"
+)
+
+var (
+	ErrTournamentRunning = errors.New("tournament is already running")
+	ErrNoTournament      = errors.New("no running tournament")
+	ErrInvalidBlindLevel = errors.New("invalid blind level")
+	ErrLastBlindLevel    = errors.New("tournament is on the last blind level")
+)
+
+// BlindLevel is a level of the blind structure, it is saved as JSON with the tournament
+type BlindLevel struct {
+	SmallBlind int64 `json:"small_blind"`
+	BigBlind   int64 `json:"big_blind"`
+	Ante       int64 `json:"ante"`
+	Minutes    int   `json:"minutes"`
+}
+
+// Tournament is a running tournament with the blind structure
+type Tournament struct {
+	query.Tournament
+	BlindLevels []BlindLevel
+}
+
+// TournamentClock is the blind level of a tournament at a time
+type TournamentClock struct {
+	// Level is the index of the current level in BlindLevels
+	Level  int
+	Blinds BlindLevel
+	// Next is blinds of the next level, nil on the last level
+	Next *BlindLevel
+	// EndsAt is when the current level ends, zero on the last level as it does not end
+	EndsAt time.Time
+	// Remaining is not decreased while the clock is paused
+	Remaining time.Duration
+	Paused    bool
+}
+
+// Clock returns the blind level at now.
+// Levels go up while the clock is running without saving, so the clock continues from before a restart.
+func (t Tournament) Clock(now time.Time) TournamentClock {
+	if t.PausedAt.Valid {
+		now = t.PausedAt.Time
+	}
+
+	last := len(t.BlindLevels) - 1
+	level := min(int(t.Level), last)
+	startedAt := t.LevelStartedAt
+	for level < last {
+		endsAt := startedAt.Add(time.Duration(t.BlindLevels[level].Minutes) * time.Minute)
+		if now.Before(endsAt) {
+			break
+		}
+		level++
+		startedAt = endsAt
+	}
+
+	clock := TournamentClock{
+		Level:  level,
+		Blinds: t.BlindLevels[level],
+		Paused: t.PausedAt.Valid,
+	}
+	if level < last {
+		next := t.BlindLevels[level+1]
+		clock.Next = &next
+		clock.EndsAt = startedAt.Add(time.Duration(t.BlindLevels[level].Minutes) * time.Minute)
+		clock.Remaining = clock.EndsAt.Sub(now)
+	}
+	return clock
+}
+
+// TournamentStandings is players and chips left in a tournament
+type TournamentStandings struct {
+	Entries    int
+	Remaining  int
+	Eliminated int
+	// ChipsInPlay is stacks of remaining players and chips in the pot
+	ChipsInPlay  int64
+	AverageStack int64
+}
+
+// CalcTournamentStandings returns standings from seats of the table.
+// A seated player with chips or in the pot remains, other entries are eliminated.
+func CalcTournamentStandings(t Tournament, table *Table) TournamentStandings {
+	standings := TournamentStandings{
+		Entries:     int(t.Entries),
+		ChipsInPlay: table.PotTotal,
+	}
+	for _, s := range table.Seats {
+		if !s.PlayerID.Valid || (s.Stack == 0 && s.Contributed == 0) {
+			continue
+		}
+		standings.Remaining++
+		standings.ChipsInPlay += s.Stack
+	}
+	standings.Eliminated = max(standings.Entries-standings.Remaining, 0)
+	if standings.Remaining > 0 {
+		standings.AverageStack = standings.ChipsInPlay / int64(standings.Remaining)
+	}
+	return standings
+}
+
+// ValidateBlindLevels returns an error if the blind structure can't be used
+func ValidateBlindLevels(levels []BlindLevel) error {
+	if len(levels) == 0 {
+		return fmt.Errorf("%w: no blind levels", ErrInvalidBlindLevel)
+	}
+	for i, l := range levels {
+		if l.BigBlind <= 0 || l.SmallBlind < 0 || l.Ante < 0 || l.Minutes <= 0 {
+			return fmt.Errorf("%w: level %d must have big_blind and minutes", ErrInvalidBlindLevel, i+1)
+		}
+	}
+	return nil
+}
+
+// GetRunningTournament returns the running tournament, ErrNoTournament if no tournament is running
+func GetRunningTournament(ctx context.Context, q *query.Queries) (*Tournament, error) {
+	t, err := q.GetRunningTournament(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoTournament
+		}
+		return nil, fmt.Errorf("q.GetRunningTournament(): %w", err)
+	}
+
+	var levels []BlindLevel
+	if err := json.Unmarshal(t.Levels, &levels); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+	if err := ValidateBlindLevels(levels); err != nil {
+		return nil, fmt.Errorf("ValidateBlindLevels(): %w", err)
+	}
+	return &Tournament{Tournament: t, BlindLevels: levels}, nil
+}
+
+// StartTournament starts a tournament and the clock on the first level.
+// startingStack is set to stacks of seated players if not 0, and seated players are the entries if entries is 0.
+func StartTournament(ctx context.Context, conn *sql.DB, name string, levels []BlindLevel, startingStack int64, entries int32) (id int32, err error) {
+	if err := ValidateBlindLevels(levels); err != nil {
+		return 0, err
+	}
+	b, err := json.Marshal(levels)
+	if err != nil {
+		return 0, fmt.Errorf("json.Marshal(): %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("conn.BeginTx(): %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil || err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	q := query.New(tx)
+	if _, err = q.GetRunningTournament(ctx); err == nil {
+		err = ErrTournamentRunning
+		return 0, err
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("q.GetRunningTournament(): %w", err)
+	}
+
+	if startingStack > 0 {
+		if err = q.SetStackToSeatedPlayers(ctx, startingStack); err != nil {
+			return 0, fmt.Errorf("q.SetStackToSeatedPlayers(): %w", err)
+		}
+	}
+	if entries == 0 {
+		seats, err := q.GetSeats(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("q.GetSeats(): %w", err)
+		}
+		for _, s := range seats {
+			if s.PlayerID.Valid {
+				entries++
+			}
+		}
+	}
+
+	result, err := q.CreateTournament(ctx, query.CreateTournamentParams{
+		Name:          name,
+		Levels:        b,
+		StartingStack: startingStack,
+		Entries:       entries,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("q.CreateTournament(): %w", err)
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("result.LastInsertId(): %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("tx.Commit(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Tournament started",
+		slog.String("event", "tournament_started"),
+		slog.Int64("tournament_id", lastID),
+		slog.String("name", name),
+		slog.Int("entries", int(entries)))
+	return int32(lastID), nil
+}
+
+// PauseTournament stops the clock of the running tournament
+func PauseTournament(ctx context.Context, q *query.Queries) error {
+	t, err := GetRunningTournament(ctx, q)
+	if err != nil {
+		return fmt.Errorf("GetRunningTournament(): %w", err)
+	}
+
+	if err := q.PauseTournament(ctx, t.ID); err != nil {
+		return fmt.Errorf("q.PauseTournament(): %w", err)
+	}
+	return nil
+}
+
+// ResumeTournament restarts the clock of the running tournament with the time remaining when it was paused
+func ResumeTournament(ctx context.Context, q *query.Queries) error {
+	t, err := GetRunningTournament(ctx, q)
+	if err != nil {
+		return fmt.Errorf("GetRunningTournament(): %w", err)
+	}
+
+	if err := q.ResumeTournament(ctx, t.ID); err != nil {
+		return fmt.Errorf("q.ResumeTournament(): %w", err)
+	}
+	return nil
+}
+
+// SetTournamentLevel moves the running tournament to the level (index of the blind structure) with the full time of the level
+func SetTournamentLevel(ctx context.Context, q *query.Queries, level int) error {
+	t, err := GetRunningTournament(ctx, q)
+	if err != nil {
+		return fmt.Errorf("GetRunningTournament(): %w", err)
+	}
+	if level < 0 || level >= len(t.BlindLevels) {
+		return fmt.Errorf("%w: level %d is not in %d levels", ErrInvalidBlindLevel, level+1, len(t.BlindLevels))
+	}
+
+	if err := q.SetTournamentLevel(ctx, query.SetTournamentLevelParams{
+		Level: int32(level),
+		ID:    t.ID,
+	}); err != nil {
+		return fmt.Errorf("q.SetTournamentLevel(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Tournament level set",
+		slog.String("event", "tournament_level_set"),
+		slog.Int("tournament_id", int(t.ID)),
+		slog.Int("level", level+1))
+	return nil
+}
+
+// SkipTournamentLevel moves the running tournament to the next level before the clock runs out
+func SkipTournamentLevel(ctx context.Context, q *query.Queries, now time.Time) error {
+	t, err := GetRunningTournament(ctx, q)
+	if err != nil {
+		return fmt.Errorf("GetRunningTournament(): %w", err)
+	}
+	clock := t.Clock(now)
+	if clock.Next == nil {
+		return ErrLastBlindLevel
+	}
+
+	return SetTournamentLevel(ctx, q, clock.Level+1)
+}
+
+// FinishTournament ends the running tournament, stacks are kept on seats
+func FinishTournament(ctx context.Context, q *query.Queries) error {
+	t, err := GetRunningTournament(ctx, q)
+	if err != nil {
+		return fmt.Errorf("GetRunningTournament(): %w", err)
+	}
+
+	if err := q.FinishTournament(ctx, t.ID); err != nil {
+		return fmt.Errorf("q.FinishTournament(): %w", err)
+	}
+
+	slog.InfoContext(ctx, "Tournament finished",
+		slog.String("event", "tournament_finished"),
+		slog.Int("tournament_id", int(t.ID)),
+		slog.String("name", t.Name))
+	return nil
+}